@@ -3,11 +3,13 @@ package account
 import (
 	"context"
 	"kc-bank/app/services/account/command"
+	"kc-bank/pkg/handler"
 )
 
 type CreateAccountRequest struct {
+	handler.IdempotencyHeader
 	Currency string `json:"currency" validate:"required"`
-	UserId   string `json:"userId" validate:"required"`
+	UserId   string `json:"userId"`
 }
 
 func (req *CreateAccountRequest) ToCommand() command.Command {
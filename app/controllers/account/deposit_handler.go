@@ -0,0 +1,59 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"kc-bank/app/controllers/account/response"
+	"kc-bank/app/services/account/command"
+	"kc-bank/domain"
+	"kc-bank/pkg/handler"
+)
+
+// DepositRequest pays cash into the account at a branch or ATM. Branch operations are recorded against the
+// teller making the request.
+type DepositRequest struct {
+	handler.IdempotencyHeader
+	Id        string      `json:"-" param:"id"`
+	Amount    json.Number `json:"amount" validate:"required"`
+	Channel   string      `json:"channel" validate:"required,oneof=BRANCH ATM"`
+	BranchId  string      `json:"branchId" validate:"required_if=Channel BRANCH,max=64"`
+	AtmId     string      `json:"atmId" validate:"required_if=Channel ATM,max=64"`
+	Reference string      `json:"reference" validate:"max=140"`
+}
+
+func (req *DepositRequest) ToCommand() command.CashCommand {
+	return command.CashCommand{
+		AccountId: req.Id,
+		Amount:    req.Amount.String(),
+		Channel: domain.CashChannel{
+			Type:     domain.CashChannelType(req.Channel),
+			BranchId: req.BranchId,
+			AtmId:    req.AtmId,
+		},
+		Reference: req.Reference,
+	}
+}
+
+type DepositResponse struct {
+	Transaction response.TransactionResponse `json:"transaction"`
+}
+
+type DepositHandler struct {
+	command command.ICommandHandler
+}
+
+func NewDepositHandler(command command.ICommandHandler) *DepositHandler {
+	return &DepositHandler{
+		command: command,
+	}
+}
+
+func (h *DepositHandler) Handle(ctx context.Context, req *DepositRequest) (*DepositResponse, error) {
+	transfer, err := h.command.Deposit(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DepositResponse{Transaction: response.ToTransactionResponse(req.Id, transfer)}, nil
+}
@@ -25,7 +25,6 @@ func NewGetAccountHandler(queryService query.IAccountQueryService) *GetAccountHa
 }
 
 func (h *GetAccountHandler) Handle(ctx context.Context, req *GetAccountRequest) (*GetAccountResponse, error) {
-	// TODO: user just should be able to see his/her accounts
 	account, err := h.queryService.GetAccount(ctx, req.Id)
 
 	if err != nil {
@@ -0,0 +1,46 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"kc-bank/app/controllers/account/response"
+	"kc-bank/app/services/account/query"
+)
+
+type GetAccountLedgerRequest struct {
+	Id string `json:"id" param:"id"`
+}
+
+type GetAccountLedgerResponse struct {
+	AccountId     string                          `json:"accountId"`
+	Balance       json.Number                     `json:"balance"`
+	LedgerBalance json.Number                     `json:"ledgerBalance"`
+	Reconciled    bool                            `json:"reconciled"`
+	Entries       []response.JournalEntryResponse `json:"entries"`
+}
+
+type GetAccountLedgerHandler struct {
+	queryService query.IAccountQueryService
+}
+
+func NewGetAccountLedgerHandler(queryService query.IAccountQueryService) *GetAccountLedgerHandler {
+	return &GetAccountLedgerHandler{
+		queryService: queryService,
+	}
+}
+
+func (h *GetAccountLedgerHandler) Handle(ctx context.Context, req *GetAccountLedgerRequest) (*GetAccountLedgerResponse, error) {
+	ledger, err := h.queryService.GetAccountLedger(ctx, req.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetAccountLedgerResponse{
+		AccountId:     ledger.Account.Id,
+		Balance:       json.Number(ledger.Account.Balance.String()),
+		LedgerBalance: json.Number(ledger.LedgerBalance.String()),
+		Reconciled:    ledger.Account.Balance == ledger.LedgerBalance,
+		Entries:       response.ToJournalEntryResponseList(ledger.Entries),
+	}, nil
+}
@@ -0,0 +1,91 @@
+package account
+
+import (
+	"context"
+	"kc-bank/app/controllers/account/response"
+	"kc-bank/app/services/account/query"
+	"kc-bank/domain"
+	errorresponse "kc-bank/pkg/error_response"
+	"time"
+)
+
+type GetAccountTransactionsRequest struct {
+	Id        string `json:"id" param:"id"`
+	From      string `query:"from"`
+	To        string `query:"to"`
+	MinAmount string `query:"minAmount" validate:"omitempty,numeric"`
+	MaxAmount string `query:"maxAmount" validate:"omitempty,numeric"`
+	Direction string `query:"direction" validate:"omitempty,oneof=IN OUT"`
+	Cursor    string `query:"cursor"`
+	Limit     int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (req *GetAccountTransactionsRequest) ToQuery() (query.TransactionQuery, error) {
+	from, err := parseOptionalTime(req.From)
+	if err != nil {
+		return query.TransactionQuery{}, err
+	}
+
+	to, err := parseOptionalTime(req.To)
+	if err != nil {
+		return query.TransactionQuery{}, err
+	}
+
+	return query.TransactionQuery{
+		AccountId: req.Id,
+		From:      from,
+		To:        to,
+		MinAmount: req.MinAmount,
+		MaxAmount: req.MaxAmount,
+		Direction: domain.TransferDirection(req.Direction),
+		Cursor:    req.Cursor,
+		Limit:     req.Limit,
+	}, nil
+}
+
+type GetAccountTransactionsResponse struct {
+	Transactions []response.TransactionResponse `json:"transactions"`
+	NextCursor   string                         `json:"nextCursor,omitempty"`
+}
+
+type GetAccountTransactionsHandler struct {
+	queryService query.IAccountQueryService
+}
+
+func NewGetAccountTransactionsHandler(queryService query.IAccountQueryService) *GetAccountTransactionsHandler {
+	return &GetAccountTransactionsHandler{
+		queryService: queryService,
+	}
+}
+
+func (h *GetAccountTransactionsHandler) Handle(ctx context.Context, req *GetAccountTransactionsRequest) (*GetAccountTransactionsResponse, error) {
+	transactionQuery, err := req.ToQuery()
+
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := h.queryService.GetAccountTransactions(ctx, transactionQuery)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetAccountTransactionsResponse{
+		Transactions: response.ToTransactionResponseList(page.Account.Id, page.Transfers),
+		NextCursor:   page.NextCursor,
+	}, nil
+}
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errorresponse.NewBadRequestError("invalid time " + value + ", expected RFC 3339")
+	}
+
+	return &parsed, nil
+}
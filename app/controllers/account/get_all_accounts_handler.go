@@ -23,7 +23,6 @@ func NewGetAccountAllHandler(queryService query.IAccountQueryService) *GetAccoun
 }
 
 func (h *GetAccountAllHandler) Handle(ctx context.Context, req *GetAccountAllRequest) (*GetAccountAllResponse, error) {
-	// TODO: user just should be able to see his/her accounts
 	accounts, err := h.queryService.GetAllAccounts(ctx)
 
 	if err != nil {
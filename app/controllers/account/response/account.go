@@ -1,30 +1,54 @@
 package response
 
 import (
+	"encoding/json"
 	"kc-bank/domain"
 	"time"
 )
 
 type AccountResponse struct {
-	Id        string    `json:"id"`
-	Currency  string    `json:"currency"`
-	Iban      string    `json:"iban"`
-	Balance   float64   `json:"balance"`
-	UserId    string    `json:"userId"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	Id                  string      `json:"id"`
+	Currency            string      `json:"currency"`
+	Iban                string      `json:"iban"`
+	Balance             json.Number `json:"balance"`
+	AvailableBalance    json.Number `json:"availableBalance"`
+	HeldAmount          json.Number `json:"heldAmount"`
+	OverdraftLimit      json.Number `json:"overdraftLimit,omitempty"`
+	OverdraftBreachedAt *time.Time  `json:"overdraftBreachedAt,omitempty"`
+	UserId              string      `json:"userId"`
+	Status              string      `json:"status"`
+	StatusReason        string      `json:"statusReason,omitempty"`
+	CreatedAt           time.Time   `json:"createdAt"`
+	UpdatedAt           time.Time   `json:"updatedAt"`
 }
 
 func ToAccountResponse(account *domain.Account) AccountResponse {
-	return AccountResponse{
-		Id:        account.Id,
-		Currency:  account.Currency,
-		Iban:      account.Iban,
-		Balance:   account.Balance,
-		UserId:    account.UserId,
-		CreatedAt: account.CreatedAt,
-		UpdatedAt: account.UpdatedAt,
+	response := AccountResponse{
+		Id:                  account.Id,
+		Currency:            account.Currency,
+		Iban:                account.Iban,
+		Balance:             json.Number(account.Balance.String()),
+		OverdraftBreachedAt: account.OverdraftBreachedAt,
+		UserId:              account.UserId,
+		Status:              string(account.EffectiveStatus()),
+		StatusReason:        account.StatusReason,
+		CreatedAt:           account.CreatedAt,
+		UpdatedAt:           account.UpdatedAt,
 	}
+
+	if available, err := account.AvailableBalance(); err == nil {
+		response.AvailableBalance = json.Number(available.String())
+	}
+
+	if account.HeldAmount != nil {
+		response.HeldAmount = json.Number(account.HeldAmount.String())
+	}
+
+	if account.OverdraftLimit != nil {
+		response.OverdraftLimit = json.Number(account.OverdraftLimit.String())
+	}
+
+	return response
 }
 
 func ToAccountResponseList(accounts []*domain.Account) []AccountResponse {
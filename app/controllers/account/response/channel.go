@@ -0,0 +1,23 @@
+package response
+
+import "kc-bank/domain"
+
+type CashChannelResponse struct {
+	Type     string `json:"type"`
+	BranchId string `json:"branchId,omitempty"`
+	AtmId    string `json:"atmId,omitempty"`
+	TellerId string `json:"tellerId,omitempty"`
+}
+
+func ToCashChannelResponse(channel *domain.CashChannel) *CashChannelResponse {
+	if channel == nil {
+		return nil
+	}
+
+	return &CashChannelResponse{
+		Type:     string(channel.Type),
+		BranchId: channel.BranchId,
+		AtmId:    channel.AtmId,
+		TellerId: channel.TellerId,
+	}
+}
@@ -0,0 +1,53 @@
+package response
+
+import (
+	"encoding/json"
+	"kc-bank/domain"
+	"time"
+)
+
+type PostingResponse struct {
+	AccountId string      `json:"accountId"`
+	Direction string      `json:"direction"`
+	Amount    json.Number `json:"amount"`
+	Currency  string      `json:"currency"`
+}
+
+type JournalEntryResponse struct {
+	Id        string            `json:"id"`
+	Type      string            `json:"type"`
+	Reference string            `json:"reference"`
+	Postings  []PostingResponse `json:"postings"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+func ToJournalEntryResponse(entry *domain.JournalEntry) JournalEntryResponse {
+	var postings = make([]PostingResponse, 0, len(entry.Postings))
+
+	for _, posting := range entry.Postings {
+		postings = append(postings, PostingResponse{
+			AccountId: posting.AccountId,
+			Direction: string(posting.Direction),
+			Amount:    json.Number(posting.Amount.String()),
+			Currency:  posting.Amount.Currency,
+		})
+	}
+
+	return JournalEntryResponse{
+		Id:        entry.Id,
+		Type:      string(entry.Type),
+		Reference: entry.Reference,
+		Postings:  postings,
+		CreatedAt: entry.CreatedAt,
+	}
+}
+
+func ToJournalEntryResponseList(entries []*domain.JournalEntry) []JournalEntryResponse {
+	var response = make([]JournalEntryResponse, 0)
+
+	for _, entry := range entries {
+		response = append(response, ToJournalEntryResponse(entry))
+	}
+
+	return response
+}
@@ -0,0 +1,72 @@
+package response
+
+import (
+	"encoding/json"
+	"kc-bank/domain"
+	"time"
+)
+
+type TransactionResponse struct {
+	Id              string               `json:"id"`
+	Type            string               `json:"type"`
+	Direction       string               `json:"direction"`
+	CounterpartIban string               `json:"counterpartIban,omitempty"`
+	Amount          json.Number          `json:"amount"`
+	Currency        string               `json:"currency"`
+	BalanceAfter    json.Number          `json:"balanceAfter,omitempty"`
+	Channel         *CashChannelResponse `json:"channel,omitempty"`
+	Reference       string               `json:"reference,omitempty"`
+	Status          string               `json:"status"`
+	FailureReason   string               `json:"failureReason,omitempty"`
+	CreatedAt       time.Time            `json:"createdAt"`
+	UpdatedAt       time.Time            `json:"updatedAt"`
+	CompletedAt     *time.Time           `json:"completedAt,omitempty"`
+}
+
+// ToTransactionResponse describes transfer from the point of view of the
+// account identified by accountId.
+func ToTransactionResponse(accountId string, transfer *domain.Transfer) TransactionResponse {
+	direction := transfer.DirectionFor(accountId)
+
+	counterpartIban := transfer.FromIban
+	balanceAfter := transfer.ToBalanceAfter
+	amount := transfer.CreditAmount()
+
+	if direction == domain.TransferDirectionOut {
+		counterpartIban = transfer.ToIban
+		balanceAfter = transfer.FromBalanceAfter
+		amount = transfer.Amount
+	}
+
+	response := TransactionResponse{
+		Id:              transfer.Id,
+		Type:            string(transfer.EffectiveType()),
+		Direction:       string(direction),
+		CounterpartIban: counterpartIban,
+		Amount:          json.Number(amount.String()),
+		Currency:        amount.Currency,
+		Channel:         ToCashChannelResponse(transfer.Channel),
+		Reference:       transfer.Reference,
+		Status:          string(transfer.Status),
+		FailureReason:   transfer.FailureReason,
+		CreatedAt:       transfer.CreatedAt,
+		UpdatedAt:       transfer.UpdatedAt,
+		CompletedAt:     transfer.CompletedAt,
+	}
+
+	if balanceAfter != nil {
+		response.BalanceAfter = json.Number(balanceAfter.String())
+	}
+
+	return response
+}
+
+func ToTransactionResponseList(accountId string, transfers []*domain.Transfer) []TransactionResponse {
+	var response = make([]TransactionResponse, 0)
+
+	for _, transfer := range transfers {
+		response = append(response, ToTransactionResponse(accountId, transfer))
+	}
+
+	return response
+}
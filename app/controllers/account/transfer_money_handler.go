@@ -2,20 +2,28 @@ package account
 
 import (
 	"context"
+	"encoding/json"
 	"kc-bank/app/services/account/command"
+	"kc-bank/pkg/handler"
+	"kc-bank/pkg/services"
 )
 
 type TransferMoneyRequest struct {
-	Amount   float64 `json:"amount" validate:"required"`
-	FromIBAN string  `json:"fromIBAN" validate:"required"`
-	ToIBAN   string  `json:"toIBAN" validate:"required"`
+	handler.IdempotencyHeader
+	Amount                  json.Number `json:"amount" validate:"required"`
+	FromIBAN                string      `json:"fromIBAN" validate:"required"`
+	ToIBAN                  string      `json:"toIBAN" validate:"required"`
+	AllowCurrencyConversion bool        `json:"allowCurrencyConversion"`
+	QuoteId                 string      `json:"quoteId"`
 }
 
 func (req *TransferMoneyRequest) ToCommand() command.TransferMoneyCommand {
 	return command.TransferMoneyCommand{
-		Amount:   req.Amount,
-		FromIBAN: req.FromIBAN,
-		ToIBAN:   req.ToIBAN,
+		Amount:                  req.Amount.String(),
+		FromIBAN:                services.NormalizeIBAN(req.FromIBAN),
+		ToIBAN:                  services.NormalizeIBAN(req.ToIBAN),
+		AllowCurrencyConversion: req.AllowCurrencyConversion,
+		QuoteId:                 req.QuoteId,
 	}
 }
 
@@ -2,25 +2,41 @@ package account
 
 import (
 	"context"
+	"encoding/json"
 	"kc-bank/app/services/account/command"
+	"kc-bank/pkg/handler"
+	"kc-bank/pkg/services"
+	"net/http"
 )
 
 type TransferMoneyWithRabbitMQRequest struct {
-	Amount   float64 `json:"amount" validate:"required"`
-	FromIBAN string  `json:"fromIBAN" validate:"required"`
-	ToIBAN   string  `json:"toIBAN" validate:"required"`
+	handler.IdempotencyHeader
+	Amount                  json.Number `json:"amount" validate:"required"`
+	FromIBAN                string      `json:"fromIBAN" validate:"required"`
+	ToIBAN                  string      `json:"toIBAN" validate:"required"`
+	AllowCurrencyConversion bool        `json:"allowCurrencyConversion"`
+	QuoteId                 string      `json:"quoteId"`
 }
 
 func (req *TransferMoneyWithRabbitMQRequest) ToCommand() command.TransferMoneyCommand {
 	return command.TransferMoneyCommand{
-		Amount:   req.Amount,
-		FromIBAN: req.FromIBAN,
-		ToIBAN:   req.ToIBAN,
+		Amount:                  req.Amount.String(),
+		FromIBAN:                services.NormalizeIBAN(req.FromIBAN),
+		ToIBAN:                  services.NormalizeIBAN(req.ToIBAN),
+		AllowCurrencyConversion: req.AllowCurrencyConversion,
+		QuoteId:                 req.QuoteId,
 	}
 }
 
 type TransferMoneyWithRabbitMQResponse struct {
-	Message string `json:"message"`
+	Message    string `json:"message"`
+	TransferId string `json:"transferId"`
+	Status     string `json:"status"`
+}
+
+// StatusCode reports 202: the transfer is only queued at this point.
+func (res *TransferMoneyWithRabbitMQResponse) StatusCode() int {
+	return http.StatusAccepted
 }
 
 type TransferMoneyWithRabbitMQHandler struct {
@@ -34,13 +50,15 @@ func NewTransferMoneyWithRabbitMQHandler(command command.ICommandHandler) *Trans
 }
 
 func (h *TransferMoneyWithRabbitMQHandler) Handle(ctx context.Context, req *TransferMoneyWithRabbitMQRequest) (*TransferMoneyWithRabbitMQResponse, error) {
-	err := h.command.TransferMoneyWithRabbitMQPublisher(ctx, req.ToCommand())
+	transfer, err := h.command.TransferMoneyWithRabbitMQPublisher(ctx, req.ToCommand())
 
 	if err != nil {
 		return nil, err
 	}
 
 	return &TransferMoneyWithRabbitMQResponse{
-		Message: "Amount successfully transferred!",
+		Message:    "Transfer accepted for processing",
+		TransferId: transfer.Id,
+		Status:     string(transfer.Status),
 	}, nil
 }
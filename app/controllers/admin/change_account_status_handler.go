@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"context"
+	"kc-bank/app/controllers/account/response"
+	"kc-bank/app/services/account/command"
+	"kc-bank/pkg/services"
+)
+
+type ChangeAccountStatusRequest struct {
+	Id          string `json:"-" param:"id"`
+	Status      string `json:"status" validate:"required,oneof=ACTIVE FROZEN_DEBIT FROZEN_ALL DORMANT CLOSED"`
+	Reason      string `json:"reason" validate:"required,max=500"`
+	SweepToIban string `json:"sweepToIban"`
+}
+
+func (req *ChangeAccountStatusRequest) ToCommand() command.ChangeAccountStatusCommand {
+	return command.ChangeAccountStatusCommand{
+		AccountId:   req.Id,
+		Status:      req.Status,
+		Reason:      req.Reason,
+		SweepToIban: services.NormalizeIBAN(req.SweepToIban),
+	}
+}
+
+type ChangeAccountStatusResponse struct {
+	Account response.AccountResponse `json:"account"`
+}
+
+type ChangeAccountStatusHandler struct {
+	command command.ICommandHandler
+}
+
+func NewChangeAccountStatusHandler(command command.ICommandHandler) *ChangeAccountStatusHandler {
+	return &ChangeAccountStatusHandler{
+		command: command,
+	}
+}
+
+func (h *ChangeAccountStatusHandler) Handle(ctx context.Context, req *ChangeAccountStatusRequest) (*ChangeAccountStatusResponse, error) {
+	account, err := h.command.ChangeAccountStatus(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangeAccountStatusResponse{Account: response.ToAccountResponse(account)}, nil
+}
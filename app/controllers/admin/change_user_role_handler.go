@@ -0,0 +1,44 @@
+package admin
+
+import (
+	"context"
+	"kc-bank/app/services/user/command"
+)
+
+type ChangeUserRoleRequest struct {
+	Id   string `json:"-" param:"id"`
+	Role string `json:"role" validate:"required,oneof=CUSTOMER TELLER ADMIN"`
+}
+
+func (req *ChangeUserRoleRequest) ToCommand() command.ChangeRoleCommand {
+	return command.ChangeRoleCommand{
+		UserId: req.Id,
+		Role:   req.Role,
+	}
+}
+
+type ChangeUserRoleResponse struct {
+	Message string `json:"message"`
+}
+
+type ChangeUserRoleHandler struct {
+	command command.ICommandHandler
+}
+
+func NewChangeUserRoleHandler(command command.ICommandHandler) *ChangeUserRoleHandler {
+	return &ChangeUserRoleHandler{
+		command: command,
+	}
+}
+
+func (h *ChangeUserRoleHandler) Handle(ctx context.Context, req *ChangeUserRoleRequest) (*ChangeUserRoleResponse, error) {
+	err := h.command.ChangeRole(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangeUserRoleResponse{
+		Message: "User role changed successfully",
+	}, nil
+}
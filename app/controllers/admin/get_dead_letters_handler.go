@@ -0,0 +1,40 @@
+package admin
+
+import (
+	"context"
+	"kc-bank/app/controllers/admin/response"
+	"kc-bank/app/services/account/command"
+)
+
+type GetDeadLettersRequest struct {
+	Limit int `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type GetDeadLettersResponse struct {
+	DeadLetters []response.DeadLetterResponse `json:"deadLetters"`
+}
+
+type GetDeadLettersHandler struct {
+	command command.ICommandHandler
+}
+
+func NewGetDeadLettersHandler(command command.ICommandHandler) *GetDeadLettersHandler {
+	return &GetDeadLettersHandler{
+		command: command,
+	}
+}
+
+func (h *GetDeadLettersHandler) Handle(ctx context.Context, req *GetDeadLettersRequest) (*GetDeadLettersResponse, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = 20
+	}
+
+	deadLetters, err := h.command.GetDeadLetterTransfers(ctx, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetDeadLettersResponse{DeadLetters: response.ToDeadLetterResponseList(deadLetters)}, nil
+}
@@ -0,0 +1,33 @@
+package admin
+
+import (
+	"context"
+	"kc-bank/app/controllers/account/response"
+	"kc-bank/app/services/account/query"
+)
+
+type GetOverdraftBreachesRequest struct{}
+
+type GetOverdraftBreachesResponse struct {
+	Accounts []response.AccountResponse `json:"accounts"`
+}
+
+type GetOverdraftBreachesHandler struct {
+	queryService query.IAccountQueryService
+}
+
+func NewGetOverdraftBreachesHandler(queryService query.IAccountQueryService) *GetOverdraftBreachesHandler {
+	return &GetOverdraftBreachesHandler{
+		queryService: queryService,
+	}
+}
+
+func (h *GetOverdraftBreachesHandler) Handle(ctx context.Context, req *GetOverdraftBreachesRequest) (*GetOverdraftBreachesResponse, error) {
+	accounts, err := h.queryService.GetOverdraftBreaches(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetOverdraftBreachesResponse{Accounts: response.ToAccountResponseList(accounts)}, nil
+}
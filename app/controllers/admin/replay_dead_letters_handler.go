@@ -0,0 +1,34 @@
+package admin
+
+import (
+	"context"
+	"kc-bank/app/services/account/command"
+)
+
+type ReplayDeadLettersRequest struct {
+	Limit int `json:"limit" validate:"required,min=1,max=1000"`
+}
+
+type ReplayDeadLettersResponse struct {
+	Replayed int `json:"replayed"`
+}
+
+type ReplayDeadLettersHandler struct {
+	command command.ICommandHandler
+}
+
+func NewReplayDeadLettersHandler(command command.ICommandHandler) *ReplayDeadLettersHandler {
+	return &ReplayDeadLettersHandler{
+		command: command,
+	}
+}
+
+func (h *ReplayDeadLettersHandler) Handle(ctx context.Context, req *ReplayDeadLettersRequest) (*ReplayDeadLettersResponse, error) {
+	replayed, err := h.command.ReplayDeadLetterTransfers(ctx, req.Limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReplayDeadLettersResponse{Replayed: replayed}, nil
+}
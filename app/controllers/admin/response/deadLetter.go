@@ -0,0 +1,43 @@
+package response
+
+import (
+	"encoding/json"
+	"kc-bank/infra/rabbitmq"
+	"time"
+)
+
+type DeadLetterResponse struct {
+	MessageId  string          `json:"messageId"`
+	RetryCount int             `json:"retryCount"`
+	LastError  string          `json:"lastError"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func ToDeadLetterResponse(deadLetter rabbitmq.DeadLetter) DeadLetterResponse {
+	body := json.RawMessage(deadLetter.Body)
+
+	// Keep the response valid JSON even for the unparseable messages that
+	// were dead-lettered for exactly that reason.
+	if !json.Valid(body) {
+		body, _ = json.Marshal(string(deadLetter.Body))
+	}
+
+	return DeadLetterResponse{
+		MessageId:  deadLetter.MessageId,
+		RetryCount: deadLetter.RetryCount,
+		LastError:  deadLetter.LastError,
+		Timestamp:  deadLetter.Timestamp,
+		Body:       body,
+	}
+}
+
+func ToDeadLetterResponseList(deadLetters []rabbitmq.DeadLetter) []DeadLetterResponse {
+	var response = make([]DeadLetterResponse, 0)
+
+	for _, deadLetter := range deadLetters {
+		response = append(response, ToDeadLetterResponse(deadLetter))
+	}
+
+	return response
+}
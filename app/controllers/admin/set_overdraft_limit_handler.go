@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"kc-bank/app/controllers/account/response"
+	"kc-bank/app/services/account/command"
+)
+
+type SetOverdraftLimitRequest struct {
+	Id     string      `json:"-" param:"id"`
+	Limit  json.Number `json:"limit" validate:"required"`
+	Reason string      `json:"reason" validate:"required,max=500"`
+}
+
+func (req *SetOverdraftLimitRequest) ToCommand() command.SetOverdraftLimitCommand {
+	return command.SetOverdraftLimitCommand{
+		AccountId: req.Id,
+		Limit:     req.Limit.String(),
+		Reason:    req.Reason,
+	}
+}
+
+type SetOverdraftLimitResponse struct {
+	Account response.AccountResponse `json:"account"`
+}
+
+type SetOverdraftLimitHandler struct {
+	command command.ICommandHandler
+}
+
+func NewSetOverdraftLimitHandler(command command.ICommandHandler) *SetOverdraftLimitHandler {
+	return &SetOverdraftLimitHandler{
+		command: command,
+	}
+}
+
+func (h *SetOverdraftLimitHandler) Handle(ctx context.Context, req *SetOverdraftLimitRequest) (*SetOverdraftLimitResponse, error) {
+	account, err := h.command.SetOverdraftLimit(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetOverdraftLimitResponse{Account: response.ToAccountResponse(account)}, nil
+}
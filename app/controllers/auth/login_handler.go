@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"kc-bank/app/services/auth/command"
+)
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+func (req *LoginRequest) ToCommand() command.LoginCommand {
+	return command.LoginCommand{
+		Email:    req.Email,
+		Password: req.Password,
+	}
+}
+
+type LoginResponse = TokenResponse
+
+type LoginHandler struct {
+	command command.ICommandHandler
+}
+
+func NewLoginHandler(command command.ICommandHandler) *LoginHandler {
+	return &LoginHandler{
+		command: command,
+	}
+}
+
+func (h *LoginHandler) Handle(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+	tokens, err := h.command.Login(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toTokenResponse(tokens), nil
+}
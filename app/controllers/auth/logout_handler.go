@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"kc-bank/app/services/auth/command"
+)
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+func (req *LogoutRequest) ToCommand() command.LogoutCommand {
+	return command.LogoutCommand{
+		RefreshToken: req.RefreshToken,
+	}
+}
+
+type LogoutResponse struct {
+	Message string `json:"message"`
+}
+
+type LogoutHandler struct {
+	command command.ICommandHandler
+}
+
+func NewLogoutHandler(command command.ICommandHandler) *LogoutHandler {
+	return &LogoutHandler{
+		command: command,
+	}
+}
+
+func (h *LogoutHandler) Handle(ctx context.Context, req *LogoutRequest) (*LogoutResponse, error) {
+	err := h.command.Logout(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogoutResponse{
+		Message: "Logged out successfully",
+	}, nil
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+	"kc-bank/app/services/auth/command"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+func (req *RefreshTokenRequest) ToCommand() command.RefreshCommand {
+	return command.RefreshCommand{
+		RefreshToken: req.RefreshToken,
+	}
+}
+
+type RefreshTokenResponse = TokenResponse
+
+type RefreshTokenHandler struct {
+	command command.ICommandHandler
+}
+
+func NewRefreshTokenHandler(command command.ICommandHandler) *RefreshTokenHandler {
+	return &RefreshTokenHandler{
+		command: command,
+	}
+}
+
+func (h *RefreshTokenHandler) Handle(ctx context.Context, req *RefreshTokenRequest) (*RefreshTokenResponse, error) {
+	tokens, err := h.command.Refresh(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toTokenResponse(tokens), nil
+}
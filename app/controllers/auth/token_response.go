@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"kc-bank/app/services/auth/command"
+	"time"
+)
+
+type TokenResponse struct {
+	AccessToken           string    `json:"accessToken"`
+	TokenType             string    `json:"tokenType"`
+	AccessTokenExpiresAt  time.Time `json:"accessTokenExpiresAt"`
+	RefreshToken          string    `json:"refreshToken"`
+	RefreshTokenExpiresAt time.Time `json:"refreshTokenExpiresAt"`
+}
+
+func toTokenResponse(tokens *command.TokenPair) *TokenResponse {
+	return &TokenResponse{
+		AccessToken:           tokens.AccessToken,
+		TokenType:             "Bearer",
+		AccessTokenExpiresAt:  tokens.AccessTokenExpiresAt,
+		RefreshToken:          tokens.RefreshToken,
+		RefreshTokenExpiresAt: tokens.RefreshTokenExpiresAt,
+	}
+}
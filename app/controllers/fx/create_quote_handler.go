@@ -0,0 +1,74 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"kc-bank/app/services/fx/command"
+	"net/http"
+	"time"
+)
+
+type CreateQuoteRequest struct {
+	FromCurrency string      `json:"fromCurrency" validate:"required,len=3,uppercase"`
+	ToCurrency   string      `json:"toCurrency" validate:"required,len=3,uppercase"`
+	Amount       json.Number `json:"amount"`
+}
+
+func (req *CreateQuoteRequest) ToCommand() command.CreateQuoteCommand {
+	return command.CreateQuoteCommand{
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		Amount:       req.Amount.String(),
+	}
+}
+
+type CreateQuoteResponse struct {
+	QuoteId         string      `json:"quoteId"`
+	FromCurrency    string      `json:"fromCurrency"`
+	ToCurrency      string      `json:"toCurrency"`
+	Rate            string      `json:"rate"`
+	Amount          json.Number `json:"amount,omitempty"`
+	ConvertedAmount json.Number `json:"convertedAmount,omitempty"`
+	ExpiresAt       time.Time   `json:"expiresAt"`
+}
+
+// StatusCode reports 201: the quote is stored and can be used by its id.
+func (res *CreateQuoteResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+type CreateQuoteHandler struct {
+	command command.ICommandHandler
+}
+
+func NewCreateQuoteHandler(command command.ICommandHandler) *CreateQuoteHandler {
+	return &CreateQuoteHandler{
+		command: command,
+	}
+}
+
+func (h *CreateQuoteHandler) Handle(ctx context.Context, req *CreateQuoteRequest) (*CreateQuoteResponse, error) {
+	result, err := h.command.CreateQuote(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CreateQuoteResponse{
+		QuoteId:      result.Quote.Id,
+		FromCurrency: result.Quote.From,
+		ToCurrency:   result.Quote.To,
+		Rate:         result.Quote.Rate.Rate,
+		ExpiresAt:    result.Quote.ExpiresAt,
+	}
+
+	if result.Amount != nil {
+		response.Amount = json.Number(result.Amount.String())
+	}
+
+	if result.Converted != nil {
+		response.ConvertedAmount = json.Number(result.Converted.String())
+	}
+
+	return response, nil
+}
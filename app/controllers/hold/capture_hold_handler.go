@@ -0,0 +1,54 @@
+package hold
+
+import (
+	"context"
+	"encoding/json"
+	"kc-bank/app/controllers/hold/response"
+	transferResponse "kc-bank/app/controllers/transfer/response"
+	"kc-bank/app/services/hold/command"
+	"kc-bank/pkg/handler"
+	"kc-bank/pkg/services"
+)
+
+type CaptureHoldRequest struct {
+	handler.IdempotencyHeader
+	Id     string      `json:"-" param:"id"`
+	Amount json.Number `json:"amount"`
+	ToIBAN string      `json:"toIBAN" validate:"required"`
+}
+
+func (req *CaptureHoldRequest) ToCommand() command.CaptureHoldCommand {
+	return command.CaptureHoldCommand{
+		HoldId: req.Id,
+		Amount: req.Amount.String(),
+		ToIBAN: services.NormalizeIBAN(req.ToIBAN),
+	}
+}
+
+type CaptureHoldResponse struct {
+	Hold     response.HoldResponse             `json:"hold"`
+	Transfer transferResponse.TransferResponse `json:"transfer"`
+}
+
+type CaptureHoldHandler struct {
+	command command.ICommandHandler
+}
+
+func NewCaptureHoldHandler(command command.ICommandHandler) *CaptureHoldHandler {
+	return &CaptureHoldHandler{
+		command: command,
+	}
+}
+
+func (h *CaptureHoldHandler) Handle(ctx context.Context, req *CaptureHoldRequest) (*CaptureHoldResponse, error) {
+	hold, transfer, err := h.command.CaptureHold(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &CaptureHoldResponse{
+		Hold:     response.ToHoldResponse(hold),
+		Transfer: transferResponse.ToTransferResponse(transfer),
+	}, nil
+}
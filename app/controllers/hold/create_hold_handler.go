@@ -0,0 +1,58 @@
+package hold
+
+import (
+	"context"
+	"encoding/json"
+	"kc-bank/app/controllers/hold/response"
+	"kc-bank/app/services/hold/command"
+	"kc-bank/pkg/handler"
+	"net/http"
+	"time"
+)
+
+type CreateHoldRequest struct {
+	handler.IdempotencyHeader
+	AccountId string      `json:"-" param:"id"`
+	Amount    json.Number `json:"amount" validate:"required"`
+	Reference string      `json:"reference" validate:"max=140"`
+	ExpiresAt *time.Time  `json:"expiresAt"`
+}
+
+func (req *CreateHoldRequest) ToCommand() command.CreateHoldCommand {
+	return command.CreateHoldCommand{
+		AccountId: req.AccountId,
+		Amount:    req.Amount.String(),
+		Reference: req.Reference,
+		ExpiresAt: req.ExpiresAt,
+	}
+}
+
+type CreateHoldResponse struct {
+	Hold response.HoldResponse `json:"hold"`
+}
+
+// StatusCode reports 201: the hold is stored and can be captured or released
+// by its id.
+func (res *CreateHoldResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+type CreateHoldHandler struct {
+	command command.ICommandHandler
+}
+
+func NewCreateHoldHandler(command command.ICommandHandler) *CreateHoldHandler {
+	return &CreateHoldHandler{
+		command: command,
+	}
+}
+
+func (h *CreateHoldHandler) Handle(ctx context.Context, req *CreateHoldRequest) (*CreateHoldResponse, error) {
+	hold, err := h.command.CreateHold(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateHoldResponse{Hold: response.ToHoldResponse(hold)}, nil
+}
@@ -0,0 +1,35 @@
+package hold
+
+import (
+	"context"
+	"kc-bank/app/controllers/hold/response"
+	"kc-bank/app/services/hold/query"
+)
+
+type GetAccountHoldsRequest struct {
+	AccountId string `json:"accountId" param:"id"`
+}
+
+type GetAccountHoldsResponse struct {
+	Holds []response.HoldResponse `json:"holds"`
+}
+
+type GetAccountHoldsHandler struct {
+	queryService query.IHoldQueryService
+}
+
+func NewGetAccountHoldsHandler(queryService query.IHoldQueryService) *GetAccountHoldsHandler {
+	return &GetAccountHoldsHandler{
+		queryService: queryService,
+	}
+}
+
+func (h *GetAccountHoldsHandler) Handle(ctx context.Context, req *GetAccountHoldsRequest) (*GetAccountHoldsResponse, error) {
+	holds, err := h.queryService.GetAccountHolds(ctx, req.AccountId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetAccountHoldsResponse{Holds: response.ToHoldResponseList(holds)}, nil
+}
@@ -0,0 +1,35 @@
+package hold
+
+import (
+	"context"
+	"kc-bank/app/controllers/hold/response"
+	"kc-bank/app/services/hold/query"
+)
+
+type GetHoldRequest struct {
+	Id string `json:"id" param:"id"`
+}
+
+type GetHoldResponse struct {
+	Hold response.HoldResponse `json:"hold"`
+}
+
+type GetHoldHandler struct {
+	queryService query.IHoldQueryService
+}
+
+func NewGetHoldHandler(queryService query.IHoldQueryService) *GetHoldHandler {
+	return &GetHoldHandler{
+		queryService: queryService,
+	}
+}
+
+func (h *GetHoldHandler) Handle(ctx context.Context, req *GetHoldRequest) (*GetHoldResponse, error) {
+	hold, err := h.queryService.GetHold(ctx, req.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetHoldResponse{Hold: response.ToHoldResponse(hold)}, nil
+}
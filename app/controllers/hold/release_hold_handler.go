@@ -0,0 +1,35 @@
+package hold
+
+import (
+	"context"
+	"kc-bank/app/controllers/hold/response"
+	"kc-bank/app/services/hold/command"
+)
+
+type ReleaseHoldRequest struct {
+	Id string `json:"-" param:"id"`
+}
+
+type ReleaseHoldResponse struct {
+	Hold response.HoldResponse `json:"hold"`
+}
+
+type ReleaseHoldHandler struct {
+	command command.ICommandHandler
+}
+
+func NewReleaseHoldHandler(command command.ICommandHandler) *ReleaseHoldHandler {
+	return &ReleaseHoldHandler{
+		command: command,
+	}
+}
+
+func (h *ReleaseHoldHandler) Handle(ctx context.Context, req *ReleaseHoldRequest) (*ReleaseHoldResponse, error) {
+	hold, err := h.command.ReleaseHold(ctx, req.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReleaseHoldResponse{Hold: response.ToHoldResponse(hold)}, nil
+}
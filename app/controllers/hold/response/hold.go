@@ -0,0 +1,54 @@
+package response
+
+import (
+	"encoding/json"
+	"kc-bank/domain"
+	"time"
+)
+
+type HoldResponse struct {
+	Id             string      `json:"id"`
+	AccountId      string      `json:"accountId"`
+	Amount         json.Number `json:"amount"`
+	Currency       string      `json:"currency"`
+	CapturedAmount json.Number `json:"capturedAmount,omitempty"`
+	TransferId     string      `json:"transferId,omitempty"`
+	Status         string      `json:"status"`
+	Reference      string      `json:"reference,omitempty"`
+	ExpiresAt      time.Time   `json:"expiresAt"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+	EndedAt        *time.Time  `json:"endedAt,omitempty"`
+}
+
+func ToHoldResponse(hold *domain.Hold) HoldResponse {
+	response := HoldResponse{
+		Id:         hold.Id,
+		AccountId:  hold.AccountId,
+		Amount:     json.Number(hold.Amount.String()),
+		Currency:   hold.Amount.Currency,
+		TransferId: hold.TransferId,
+		Status:     string(hold.Status),
+		Reference:  hold.Reference,
+		ExpiresAt:  hold.ExpiresAt,
+		CreatedAt:  hold.CreatedAt,
+		UpdatedAt:  hold.UpdatedAt,
+		EndedAt:    hold.EndedAt,
+	}
+
+	if hold.CapturedAmount != nil {
+		response.CapturedAmount = json.Number(hold.CapturedAmount.String())
+	}
+
+	return response
+}
+
+func ToHoldResponseList(holds []*domain.Hold) []HoldResponse {
+	var response = make([]HoldResponse, 0)
+
+	for _, hold := range holds {
+		response = append(response, ToHoldResponse(hold))
+	}
+
+	return response
+}
@@ -0,0 +1,35 @@
+package transfer
+
+import (
+	"context"
+	"kc-bank/app/controllers/transfer/response"
+	"kc-bank/app/services/scheduled/command"
+)
+
+type CancelScheduledTransferRequest struct {
+	Id string `json:"-" param:"id"`
+}
+
+type CancelScheduledTransferResponse struct {
+	ScheduledTransfer response.ScheduledTransferResponse `json:"scheduledTransfer"`
+}
+
+type CancelScheduledTransferHandler struct {
+	command command.ICommandHandler
+}
+
+func NewCancelScheduledTransferHandler(command command.ICommandHandler) *CancelScheduledTransferHandler {
+	return &CancelScheduledTransferHandler{
+		command: command,
+	}
+}
+
+func (h *CancelScheduledTransferHandler) Handle(ctx context.Context, req *CancelScheduledTransferRequest) (*CancelScheduledTransferResponse, error) {
+	scheduled, err := h.command.CancelScheduledTransfer(ctx, req.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &CancelScheduledTransferResponse{ScheduledTransfer: response.ToScheduledTransferResponse(scheduled)}, nil
+}
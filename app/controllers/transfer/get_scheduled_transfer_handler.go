@@ -0,0 +1,35 @@
+package transfer
+
+import (
+	"context"
+	"kc-bank/app/controllers/transfer/response"
+	"kc-bank/app/services/scheduled/query"
+)
+
+type GetScheduledTransferRequest struct {
+	Id string `json:"id" param:"id"`
+}
+
+type GetScheduledTransferResponse struct {
+	ScheduledTransfer response.ScheduledTransferResponse `json:"scheduledTransfer"`
+}
+
+type GetScheduledTransferHandler struct {
+	queryService query.IScheduledTransferQueryService
+}
+
+func NewGetScheduledTransferHandler(queryService query.IScheduledTransferQueryService) *GetScheduledTransferHandler {
+	return &GetScheduledTransferHandler{
+		queryService: queryService,
+	}
+}
+
+func (h *GetScheduledTransferHandler) Handle(ctx context.Context, req *GetScheduledTransferRequest) (*GetScheduledTransferResponse, error) {
+	scheduled, err := h.queryService.GetScheduledTransfer(ctx, req.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetScheduledTransferResponse{ScheduledTransfer: response.ToScheduledTransferResponse(scheduled)}, nil
+}
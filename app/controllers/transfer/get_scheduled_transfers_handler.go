@@ -0,0 +1,34 @@
+package transfer
+
+import (
+	"context"
+	"kc-bank/app/controllers/transfer/response"
+	"kc-bank/app/services/scheduled/query"
+)
+
+type GetScheduledTransfersRequest struct {
+}
+
+type GetScheduledTransfersResponse struct {
+	ScheduledTransfers []response.ScheduledTransferResponse `json:"scheduledTransfers"`
+}
+
+type GetScheduledTransfersHandler struct {
+	queryService query.IScheduledTransferQueryService
+}
+
+func NewGetScheduledTransfersHandler(queryService query.IScheduledTransferQueryService) *GetScheduledTransfersHandler {
+	return &GetScheduledTransfersHandler{
+		queryService: queryService,
+	}
+}
+
+func (h *GetScheduledTransfersHandler) Handle(ctx context.Context, req *GetScheduledTransfersRequest) (*GetScheduledTransfersResponse, error) {
+	scheduled, err := h.queryService.GetScheduledTransfers(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetScheduledTransfersResponse{ScheduledTransfers: response.ToScheduledTransferResponseList(scheduled)}, nil
+}
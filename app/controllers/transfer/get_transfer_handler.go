@@ -0,0 +1,35 @@
+package transfer
+
+import (
+	"context"
+	"kc-bank/app/controllers/transfer/response"
+	"kc-bank/app/services/account/query"
+)
+
+type GetTransferRequest struct {
+	Id string `json:"id" param:"id"`
+}
+
+type GetTransferResponse struct {
+	Transfer response.TransferResponse `json:"transfer"`
+}
+
+type GetTransferHandler struct {
+	queryService query.IAccountQueryService
+}
+
+func NewGetTransferHandler(queryService query.IAccountQueryService) *GetTransferHandler {
+	return &GetTransferHandler{
+		queryService: queryService,
+	}
+}
+
+func (h *GetTransferHandler) Handle(ctx context.Context, req *GetTransferRequest) (*GetTransferResponse, error) {
+	transfer, err := h.queryService.GetTransfer(ctx, req.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetTransferResponse{Transfer: response.ToTransferResponse(transfer)}, nil
+}
@@ -0,0 +1,61 @@
+package response
+
+import (
+	"encoding/json"
+	"kc-bank/domain"
+	"time"
+)
+
+type ScheduledTransferResponse struct {
+	Id                      string      `json:"id"`
+	FromIban                string      `json:"fromIBAN"`
+	ToIban                  string      `json:"toIBAN"`
+	Amount                  json.Number `json:"amount"`
+	Currency                string      `json:"currency"`
+	AllowCurrencyConversion bool        `json:"allowCurrencyConversion"`
+	ExecuteAt               time.Time   `json:"executeAt"`
+	Status                  string      `json:"status"`
+	TransferId              string      `json:"transferId,omitempty"`
+	Attempts                int         `json:"attempts"`
+	FailureReason           string      `json:"failureReason,omitempty"`
+	CreatedAt               time.Time   `json:"createdAt"`
+	UpdatedAt               time.Time   `json:"updatedAt"`
+	ExecutedAt              *time.Time  `json:"executedAt,omitempty"`
+	CancelledAt             *time.Time  `json:"cancelledAt,omitempty"`
+}
+
+func ToScheduledTransferResponse(scheduled *domain.ScheduledTransfer) ScheduledTransferResponse {
+	response := ScheduledTransferResponse{
+		Id:                      scheduled.Id,
+		FromIban:                scheduled.FromIban,
+		ToIban:                  scheduled.ToIban,
+		Amount:                  json.Number(scheduled.Amount.String()),
+		Currency:                scheduled.Amount.Currency,
+		AllowCurrencyConversion: scheduled.AllowCurrencyConversion,
+		ExecuteAt:               scheduled.ExecuteAt,
+		Status:                  string(scheduled.Status),
+		Attempts:                scheduled.Attempts,
+		FailureReason:           scheduled.FailureReason,
+		CreatedAt:               scheduled.CreatedAt,
+		UpdatedAt:               scheduled.UpdatedAt,
+		ExecutedAt:              scheduled.ExecutedAt,
+		CancelledAt:             scheduled.CancelledAt,
+	}
+
+	// The transfer only exists once the scheduled transfer has run.
+	if scheduled.Status == domain.ScheduledTransferStatusExecuted {
+		response.TransferId = scheduled.TransferId
+	}
+
+	return response
+}
+
+func ToScheduledTransferResponseList(scheduled []*domain.ScheduledTransfer) []ScheduledTransferResponse {
+	var response = make([]ScheduledTransferResponse, 0)
+
+	for _, transfer := range scheduled {
+		response = append(response, ToScheduledTransferResponse(transfer))
+	}
+
+	return response
+}
@@ -0,0 +1,58 @@
+package response
+
+import (
+	"encoding/json"
+	accountResponse "kc-bank/app/controllers/account/response"
+	"kc-bank/domain"
+	"time"
+)
+
+type TransferResponse struct {
+	Id                string                               `json:"id"`
+	Type              string                               `json:"type"`
+	FromIban          string                               `json:"fromIBAN,omitempty"`
+	ToIban            string                               `json:"toIBAN,omitempty"`
+	Amount            json.Number                          `json:"amount"`
+	Currency          string                               `json:"currency"`
+	ConvertedAmount   json.Number                          `json:"convertedAmount,omitempty"`
+	ConvertedCurrency string                               `json:"convertedCurrency,omitempty"`
+	ExchangeRate      string                               `json:"exchangeRate,omitempty"`
+	Channel           *accountResponse.CashChannelResponse `json:"channel,omitempty"`
+	Reference         string                               `json:"reference,omitempty"`
+	HoldId            string                               `json:"holdId,omitempty"`
+	Status            string                               `json:"status"`
+	FailureReason     string                               `json:"failureReason,omitempty"`
+	CreatedAt         time.Time                            `json:"createdAt"`
+	UpdatedAt         time.Time                            `json:"updatedAt"`
+	CompletedAt       *time.Time                           `json:"completedAt,omitempty"`
+}
+
+func ToTransferResponse(transfer *domain.Transfer) TransferResponse {
+	response := TransferResponse{
+		Id:            transfer.Id,
+		Type:          string(transfer.EffectiveType()),
+		FromIban:      transfer.FromIban,
+		ToIban:        transfer.ToIban,
+		Amount:        json.Number(transfer.Amount.String()),
+		Currency:      transfer.Amount.Currency,
+		Channel:       accountResponse.ToCashChannelResponse(transfer.Channel),
+		Reference:     transfer.Reference,
+		HoldId:        transfer.HoldId,
+		Status:        string(transfer.Status),
+		FailureReason: transfer.FailureReason,
+		CreatedAt:     transfer.CreatedAt,
+		UpdatedAt:     transfer.UpdatedAt,
+		CompletedAt:   transfer.CompletedAt,
+	}
+
+	if transfer.ConvertedAmount != nil {
+		response.ConvertedAmount = json.Number(transfer.ConvertedAmount.String())
+		response.ConvertedCurrency = transfer.ConvertedAmount.Currency
+	}
+
+	if transfer.ExchangeRate != nil {
+		response.ExchangeRate = transfer.ExchangeRate.Rate
+	}
+
+	return response
+}
@@ -0,0 +1,61 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"kc-bank/app/controllers/transfer/response"
+	"kc-bank/app/services/scheduled/command"
+	"kc-bank/pkg/handler"
+	"kc-bank/pkg/services"
+	"net/http"
+	"time"
+)
+
+type ScheduleTransferRequest struct {
+	handler.IdempotencyHeader
+	Amount                  json.Number `json:"amount" validate:"required"`
+	FromIBAN                string      `json:"fromIBAN" validate:"required"`
+	ToIBAN                  string      `json:"toIBAN" validate:"required"`
+	AllowCurrencyConversion bool        `json:"allowCurrencyConversion"`
+	ExecuteAt               time.Time   `json:"executeAt" validate:"required"`
+}
+
+func (req *ScheduleTransferRequest) ToCommand() command.ScheduleTransferCommand {
+	return command.ScheduleTransferCommand{
+		Amount:                  req.Amount.String(),
+		FromIBAN:                services.NormalizeIBAN(req.FromIBAN),
+		ToIBAN:                  services.NormalizeIBAN(req.ToIBAN),
+		AllowCurrencyConversion: req.AllowCurrencyConversion,
+		ExecuteAt:               req.ExecuteAt,
+	}
+}
+
+type ScheduleTransferResponse struct {
+	ScheduledTransfer response.ScheduledTransferResponse `json:"scheduledTransfer"`
+}
+
+// StatusCode reports 201: the scheduled transfer is stored and can be looked
+// up or cancelled by its id until it runs.
+func (res *ScheduleTransferResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+type ScheduleTransferHandler struct {
+	command command.ICommandHandler
+}
+
+func NewScheduleTransferHandler(command command.ICommandHandler) *ScheduleTransferHandler {
+	return &ScheduleTransferHandler{
+		command: command,
+	}
+}
+
+func (h *ScheduleTransferHandler) Handle(ctx context.Context, req *ScheduleTransferRequest) (*ScheduleTransferResponse, error) {
+	scheduled, err := h.command.ScheduleTransfer(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScheduleTransferResponse{ScheduledTransfer: response.ToScheduledTransferResponse(scheduled)}, nil
+}
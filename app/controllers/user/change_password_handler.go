@@ -0,0 +1,46 @@
+package user
+
+import (
+	"context"
+	"kc-bank/app/services/user/command"
+)
+
+type ChangePasswordRequest struct {
+	Id              string `json:"-" param:"id"`
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8,max=16,nefield=CurrentPassword"`
+}
+
+func (req *ChangePasswordRequest) ToCommand() command.ChangePasswordCommand {
+	return command.ChangePasswordCommand{
+		UserId:          req.Id,
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+	}
+}
+
+type ChangePasswordResponse struct {
+	Message string `json:"message"`
+}
+
+type ChangePasswordHandler struct {
+	command command.ICommandHandler
+}
+
+func NewChangePasswordHandler(command command.ICommandHandler) *ChangePasswordHandler {
+	return &ChangePasswordHandler{
+		command: command,
+	}
+}
+
+func (h *ChangePasswordHandler) Handle(ctx context.Context, req *ChangePasswordRequest) (*ChangePasswordResponse, error) {
+	err := h.command.ChangePassword(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChangePasswordResponse{
+		Message: "Password changed successfully, please log in again",
+	}, nil
+}
@@ -3,24 +3,31 @@ package user
 import (
 	"context"
 	"kc-bank/app/services/user/command"
+	"kc-bank/pkg/handler"
 )
 
 type CreateUserRequest struct {
+	handler.IdempotencyHeader
 	FirstName string `json:"firstName" validate:"required,min=2"`
 	LastName  string `json:"lastName" validate:"required"`
-	Email     string `json:"email" validate:"required"`
+	Email     string `json:"email" validate:"required,email"`
 	Password  string `json:"password" validate:"required,min=8,max=16"`
 	Age       int32  `json:"age" validate:"required"`
+	// NationalId is the TC Kimlik No; its checksum is verified by the service.
+	NationalId string `json:"nationalId" validate:"required,len=11,numeric"`
+	BirthYear  int32  `json:"birthYear" validate:"required,min=1900"`
 }
 
 func (req *CreateUserRequest) ToCommand() command.Command {
 	return command.Command{
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Email:     req.Email,
-		Password:  req.Password,
-		Age:       req.Age,
-		Id:        "",
+		FirstName:  req.FirstName,
+		LastName:   req.LastName,
+		Email:      req.Email,
+		Password:   req.Password,
+		Age:        req.Age,
+		NationalId: req.NationalId,
+		BirthYear:  req.BirthYear,
+		Id:         "",
 	}
 }
 
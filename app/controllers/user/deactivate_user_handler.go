@@ -0,0 +1,42 @@
+package user
+
+import (
+	"context"
+	"kc-bank/app/services/user/command"
+)
+
+type DeactivateUserRequest struct {
+	Id string `json:"-" param:"id"`
+}
+
+func (req *DeactivateUserRequest) ToCommand() command.DeactivateCommand {
+	return command.DeactivateCommand{
+		UserId: req.Id,
+	}
+}
+
+type DeactivateUserResponse struct {
+	Message string `json:"message"`
+}
+
+type DeactivateUserHandler struct {
+	command command.ICommandHandler
+}
+
+func NewDeactivateUserHandler(command command.ICommandHandler) *DeactivateUserHandler {
+	return &DeactivateUserHandler{
+		command: command,
+	}
+}
+
+func (h *DeactivateUserHandler) Handle(ctx context.Context, req *DeactivateUserRequest) (*DeactivateUserResponse, error) {
+	err := h.command.Deactivate(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeactivateUserResponse{
+		Message: "User deactivated successfully",
+	}, nil
+}
@@ -0,0 +1,42 @@
+package user
+
+import (
+	"context"
+	"kc-bank/app/services/user/command"
+)
+
+type DeleteUserRequest struct {
+	Id string `json:"-" param:"id"`
+}
+
+func (req *DeleteUserRequest) ToCommand() command.DeleteCommand {
+	return command.DeleteCommand{
+		UserId: req.Id,
+	}
+}
+
+type DeleteUserResponse struct {
+	Message string `json:"message"`
+}
+
+type DeleteUserHandler struct {
+	command command.ICommandHandler
+}
+
+func NewDeleteUserHandler(command command.ICommandHandler) *DeleteUserHandler {
+	return &DeleteUserHandler{
+		command: command,
+	}
+}
+
+func (h *DeleteUserHandler) Handle(ctx context.Context, req *DeleteUserRequest) (*DeleteUserResponse, error) {
+	err := h.command.Delete(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteUserResponse{
+		Message: "User deleted successfully",
+	}, nil
+}
@@ -6,24 +6,33 @@ import (
 )
 
 type UserResponse struct {
-	Id        string    `json:"id"`
-	FirstName string    `json:"firstName"`
-	LastName  string    `json:"lastName"`
-	Email     string    `json:"email"`
-	Age       int32     `json:"age"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	Id        string `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Age       int32  `json:"age"`
+	BirthYear int32  `json:"birthYear,omitempty"`
+	// NationalId is masked; the full number is never returned.
+	NationalId string    `json:"nationalId,omitempty"`
+	Role       string    `json:"role"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
 }
 
 func ToUserResponse(user *domain.User) UserResponse {
 	return UserResponse{
-		Id:        user.Id,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		Age:       user.Age,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		Id:         user.Id,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		Email:      user.Email,
+		Age:        user.Age,
+		BirthYear:  user.BirthYear,
+		NationalId: maskNationalId(user.NationalId),
+		Role:       string(user.EffectiveRole()),
+		Status:     string(userStatus(user)),
+		CreatedAt:  user.CreatedAt,
+		UpdatedAt:  user.UpdatedAt,
 	}
 }
 
@@ -36,3 +45,20 @@ func ToUserResponseList(users []*domain.User) []UserResponse {
 
 	return response
 }
+
+func maskNationalId(nationalId string) string {
+	if nationalId == "" {
+		return ""
+	}
+
+	return domain.MaskNationalId(nationalId)
+}
+
+// userStatus reports users stored before statuses existed as active.
+func userStatus(user *domain.User) domain.UserStatus {
+	if user.Status == "" {
+		return domain.UserStatusActive
+	}
+
+	return user.Status
+}
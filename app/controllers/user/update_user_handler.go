@@ -0,0 +1,49 @@
+package user
+
+import (
+	"context"
+	"kc-bank/app/services/user/command"
+)
+
+// UpdateUserRequest is a partial update: fields left out are not changed.
+type UpdateUserRequest struct {
+	Id        string  `json:"-" param:"id"`
+	FirstName *string `json:"firstName" validate:"omitempty,min=2"`
+	LastName  *string `json:"lastName" validate:"omitempty,min=1"`
+	Email     *string `json:"email" validate:"omitempty,email"`
+}
+
+func (req *UpdateUserRequest) ToCommand() command.UpdateProfileCommand {
+	return command.UpdateProfileCommand{
+		UserId:    req.Id,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Email:     req.Email,
+	}
+}
+
+type UpdateUserResponse struct {
+	Message string `json:"message"`
+}
+
+type UpdateUserHandler struct {
+	command command.ICommandHandler
+}
+
+func NewUpdateUserHandler(command command.ICommandHandler) *UpdateUserHandler {
+	return &UpdateUserHandler{
+		command: command,
+	}
+}
+
+func (h *UpdateUserHandler) Handle(ctx context.Context, req *UpdateUserRequest) (*UpdateUserResponse, error) {
+	err := h.command.UpdateProfile(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &UpdateUserResponse{
+		Message: "User updated successfully",
+	}, nil
+}
@@ -0,0 +1,49 @@
+package user
+
+import (
+	"context"
+	"kc-bank/app/services/user/command"
+)
+
+// VerifyIdentityRequest records the identity of a user registered before
+// national ids and birth years were captured.
+type VerifyIdentityRequest struct {
+	Id string `json:"-" param:"id"`
+	// NationalId is the TC Kimlik No; its checksum is verified by the service.
+	NationalId string `json:"nationalId" validate:"required,len=11,numeric"`
+	BirthYear  int32  `json:"birthYear" validate:"required,min=1900"`
+}
+
+func (req *VerifyIdentityRequest) ToCommand() command.VerifyIdentityCommand {
+	return command.VerifyIdentityCommand{
+		UserId:     req.Id,
+		NationalId: req.NationalId,
+		BirthYear:  req.BirthYear,
+	}
+}
+
+type VerifyIdentityResponse struct {
+	Message string `json:"message"`
+}
+
+type VerifyIdentityHandler struct {
+	command command.ICommandHandler
+}
+
+func NewVerifyIdentityHandler(command command.ICommandHandler) *VerifyIdentityHandler {
+	return &VerifyIdentityHandler{
+		command: command,
+	}
+}
+
+func (h *VerifyIdentityHandler) Handle(ctx context.Context, req *VerifyIdentityRequest) (*VerifyIdentityResponse, error) {
+	err := h.command.VerifyIdentity(ctx, req.ToCommand())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &VerifyIdentityResponse{
+		Message: "Identity verified successfully",
+	}, nil
+}
@@ -3,40 +3,104 @@ package repository
 import (
 	"context"
 	"errors"
-	"fmt"
 	"kc-bank/domain"
+	errorresponse "kc-bank/pkg/error_response"
+	"net/http"
 	"time"
 
 	"github.com/couchbase/gocb/v2"
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
+)
+
+var (
+	ErrBalanceNotEnough     = errorresponse.NewUnprocessableEntityError("balance is not enough")
+	errLegacyBalanceChanged = errors.New("legacy balance changed during migration")
+	ErrIbanTaken            = errorresponse.NewConflictError("iban is already allocated to another account")
+	ErrAccountNotFound      = errorresponse.NewNotFoundError("account not found")
+	ErrAccountLimitReached  = errorresponse.NewConflictError("maximum number of accounts in this currency reached")
+	ErrAccountDebitBlocked  = errorresponse.NewUnprocessableEntityError("account does not allow outgoing payments in its current status")
+	ErrAccountCreditBlocked = errorresponse.NewUnprocessableEntityError("account does not allow incoming payments in its current status")
+	ErrOwnerInactive        = errorresponse.NewForbiddenError("user is not active and can not open accounts")
+	ErrInvalidSweepAccount  = errorresponse.NewFieldError(http.StatusUnprocessableEntity, "sweepToIban", "balance can only be swept to another open account of the same owner in the same currency")
 )
 
 type IAccountRepository interface {
-	CreateAccount(ctx context.Context, account *domain.Account) error
+	CreateAccount(ctx context.Context, account *domain.Account, maxPerCurrency int) error
 	GetAccount(ctx context.Context, id string) (*domain.Account, error)
 	GetAllAccounts(ctx context.Context) ([]*domain.Account, error)
+	GetAccountsByUser(ctx context.Context, userId string) ([]*domain.Account, error)
 	FindByIban(ctx context.Context, iban string) (string, error)
-	CheckAmountForFromIban(ctx context.Context, iban string, amount float64) (bool, error)
-	TransferMoney(ctx context.Context, fromIbanId, toIbanId string, amount float64) error
+	CheckAmountForFromIban(ctx context.Context, iban string, amount domain.Money) (bool, error)
+	TransferMoney(ctx context.Context, transfer *domain.Transfer) error
+	MigrateLegacyBalances(ctx context.Context) (int, error)
+	BackfillIbanLookups(ctx context.Context, validate func(iban string) error, generate func() (string, error)) (int, error)
+	ChangeAccountStatus(ctx context.Context, accountId string, status domain.AccountStatus, reason string, audit *domain.AuditRecord) (*domain.Account, error)
+	CloseAccount(ctx context.Context, accountId, sweepAccountId, reason string, audit *domain.AuditRecord) (*domain.Account, error)
+	SetOverdraftLimit(ctx context.Context, accountId string, limit domain.Money, audit *domain.AuditRecord) (*domain.Account, error)
+	GetOverdrawnAccounts(ctx context.Context) ([]*domain.Account, error)
+	GetOverdraftBreaches(ctx context.Context) ([]*domain.Account, error)
+	AccrueOverdraftInterest(ctx context.Context, accountId string, day time.Time, rate *domain.InterestRate) (bool, error)
 }
 
 type accountRepository struct {
-	cluster *gocb.Cluster
-	bucket  *gocb.Bucket
+	cluster         *gocb.Cluster
+	bucket          *gocb.Bucket
+	userBucket      *gocb.Bucket
+	ledgerBucket    *gocb.Bucket
+	transferBucket  *gocb.Bucket
+	lookupBucket    *gocb.Bucket
+	auditBucket     *gocb.Bucket
+	scheduledBucket *gocb.Bucket
 }
 
-func NewAccountRepository(cluster *gocb.Cluster, bucket *gocb.Bucket) IAccountRepository {
+func NewAccountRepository(cluster *gocb.Cluster, bucket, userBucket, ledgerBucket, transferBucket, lookupBucket, auditBucket, scheduledBucket *gocb.Bucket) IAccountRepository {
 	return &accountRepository{
-		cluster: cluster,
-		bucket:  bucket,
+		cluster:         cluster,
+		bucket:          bucket,
+		userBucket:      userBucket,
+		ledgerBucket:    ledgerBucket,
+		transferBucket:  transferBucket,
+		lookupBucket:    lookupBucket,
+		auditBucket:     auditBucket,
+		scheduledBucket: scheduledBucket,
 	}
 }
 
-func (r *accountRepository) CreateAccount(ctx context.Context, account *domain.Account) error {
-	_, err := r.bucket.DefaultCollection().Insert(account.Id, account, &gocb.InsertOptions{
-		Timeout: 3 * time.Second,
-		Context: ctx,
+// CreateAccount stores account unless its owner already holds maxPerCurrency
+// accounts in its currency; zero means no limit.
+func (r *accountRepository) CreateAccount(ctx context.Context, account *domain.Account, maxPerCurrency int) error {
+	entry, err := domain.NewAccountOpeningJournalEntry(account.Id, account.Balance)
+	if err != nil {
+		return err
+	}
+
+	// Seeds the counter for owners whose accounts predate it.
+	existing, err := r.countAccounts(ctx, account.UserId, account.Currency)
+	if err != nil {
+		return err
+	}
+
+	// The IBAN is reserved in the same transaction, so an account is never
+	// stored with an IBAN another account already holds.
+	err = runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		if err := r.touchActiveOwner(tx, account.UserId); err != nil {
+			return err
+		}
+
+		if err := reserveLookup(tx, r.lookupBucket.DefaultCollection(), ibanLookupKey(account.Iban), account.Id, ErrIbanTaken); err != nil {
+			return err
+		}
+
+		if err := incrementAccountCount(tx, r.lookupBucket.DefaultCollection(), account, existing, maxPerCurrency); err != nil {
+			return err
+		}
+
+		if _, err := tx.Insert(r.bucket.DefaultCollection(), account.Id, account); err != nil {
+			return err
+		}
+
+		_, err := postJournalEntry(tx, r.bucket.DefaultCollection(), r.ledgerBucket.DefaultCollection(), entry)
+		return err
 	})
 
 	if err != nil {
@@ -47,14 +111,124 @@ func (r *accountRepository) CreateAccount(ctx context.Context, account *domain.A
 	return nil
 }
 
-func (r *accountRepository) UpdateAccount(ctx context.Context, account *domain.Account) error {
+// touchActiveOwner checks inside tx that the owner of a new account is still
+// active and writes their document back unchanged. Closing a user writes the
+// same document, so an account can not be opened while its owner is closed.
+func (r *accountRepository) touchActiveOwner(tx *gocb.TransactionAttemptContext, userId string) error {
+	doc, err := tx.Get(r.userBucket.DefaultCollection(), userId)
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	var owner domain.User
+	if err := doc.Content(&owner); err != nil {
+		return err
+	}
+
+	if !owner.IsActive() {
+		return ErrOwnerInactive
+	}
+
+	_, err = tx.Replace(doc, &owner)
+	return err
+}
+
+func incrementAccountCount(tx *gocb.TransactionAttemptContext, lookups *gocb.Collection, account *domain.Account, existing, maxPerCurrency int) error {
+	key := accountCountKey(account.UserId, account.Currency)
+	count := accountCount{UserId: account.UserId, Currency: account.Currency, Count: existing}
+
+	doc, err := tx.Get(lookups, key)
+	if err != nil && !errors.Is(err, gocb.ErrDocumentNotFound) {
+		return err
+	}
+
+	if doc != nil {
+		if err := doc.Content(&count); err != nil {
+			return err
+		}
+	}
+
+	if maxPerCurrency > 0 && count.Count >= maxPerCurrency {
+		return ErrAccountLimitReached
+	}
+
+	count.Count++
+
+	if doc == nil {
+		_, err = tx.Insert(lookups, key, count)
+	} else {
+		_, err = tx.Replace(doc, count)
+	}
+
+	return err
+}
+
+// decrementAccountCount frees the place of a closed account in its owner's
+// limit. Owners without a counter are counted afresh on their next account.
+func decrementAccountCount(tx *gocb.TransactionAttemptContext, lookups *gocb.Collection, account *domain.Account) error {
+	doc, err := tx.Get(lookups, accountCountKey(account.UserId, account.Currency))
+	if errors.Is(err, gocb.ErrDocumentNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var count accountCount
+	if err := doc.Content(&count); err != nil {
+		return err
+	}
+
+	if count.Count > 0 {
+		count.Count--
+	}
+
+	_, err = tx.Replace(doc, count)
+	return err
+}
+
+func (r *accountRepository) countAccounts(ctx context.Context, userId, currency string) (int, error) {
+	query := "SELECT RAW COUNT(*) FROM `accounts` a WHERE a.UserId = $userId AND a.Currency = $currency " +
+		"AND (a.Status IS NOT VALUED OR a.Status != $closed)"
+
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: map[string]interface{}{"userId": userId, "currency": currency, "closed": domain.AccountStatusClosed},
+		ScanConsistency: gocb.QueryScanConsistencyRequestPlus,
+		Adhoc:           true,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return 0, err
+	}
+
+	var count int
+	if err := rows.One(&count); err != nil {
+		zap.L().Error("Failed to read account count", zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *accountRepository) UpdateAccount(ctx context.Context, account *domain.Account, cas gocb.Cas) error {
 
 	_, err := r.bucket.DefaultCollection().Replace(account.Id, account, &gocb.ReplaceOptions{
+		Cas:     cas,
 		Timeout: 3 * time.Second,
 		Context: ctx,
 	})
 
 	if err != nil {
+		if errors.Is(err, gocb.ErrCasMismatch) {
+			return ErrConcurrentUpdate
+		}
+
 		zap.L().Error("Failed to update account", zap.Error(err))
 		return err
 	}
@@ -70,7 +244,7 @@ func (r *accountRepository) GetAccount(ctx context.Context, id string) (*domain.
 
 	if err != nil {
 		if errors.Is(err, gocb.ErrDocumentNotFound) {
-			return nil, errors.New("account not found")
+			return nil, ErrAccountNotFound
 		}
 
 		zap.L().Error("Failed to get account", zap.Error(err))
@@ -118,40 +292,69 @@ func (r *accountRepository) GetAllAccounts(ctx context.Context) ([]*domain.Accou
 	return accounts, nil
 }
 
-func (r *accountRepository) FindByIban(ctx context.Context, iban string) (string, error) {
-	query := "SELECT Id FROM `accounts` a WHERE a.Iban = $iban LIMIT 1"
+func (r *accountRepository) GetAccountsByUser(ctx context.Context, userId string) ([]*domain.Account, error) {
+	query := "SELECT META(a).id, a.* FROM `accounts` a WHERE a.UserId = $userId ORDER BY a.CreatedAt DESC"
 
+	// Closing a user checks the accounts listed here, so one just opened or
+	// funded must not be missing.
 	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
 		Context:         ctx,
-		NamedParameters: map[string]interface{}{"iban": iban},
+		NamedParameters: map[string]interface{}{"userId": userId},
+		ScanConsistency: gocb.QueryScanConsistencyRequestPlus,
 		Adhoc:           true,
 	})
 
 	if err != nil {
 		zap.L().Error("Failed to execute query", zap.Error(err))
-		return "", err
+		return nil, err
 	}
 
 	defer rows.Close()
 
-	var account struct {
-		Id string
-	}
-
-	if rows.Next() {
+	var accounts []*domain.Account
+	for rows.Next() {
+		var account domain.Account
 		if err := rows.Row(&account); err != nil {
 			zap.L().Error("Failed to scan row", zap.Error(err))
-			return "", err
+			return nil, err
 		}
+		accounts = append(accounts, &account)
+	}
 
-		return account.Id, nil
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return nil, err
 	}
 
-	return "", nil
+	return accounts, nil
 }
 
-func (r *accountRepository) CheckAmountForFromIban(ctx context.Context, iban string, amount float64) (bool, error) {
-	query := "SELECT Balance FROM `accounts` WHERE Iban = $iban LIMIT 1"
+func (r *accountRepository) FindByIban(ctx context.Context, iban string) (string, error) {
+	data, err := r.lookupBucket.DefaultCollection().Get(ibanLookupKey(iban), &gocb.GetOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return "", nil
+		}
+
+		zap.L().Error("Failed to get iban lookup", zap.Error(err))
+		return "", err
+	}
+
+	var ibanLookup lookup
+	if err := data.Content(&ibanLookup); err != nil {
+		zap.L().Error("Failed to unmarshal iban lookup", zap.Error(err))
+		return "", err
+	}
+
+	return ibanLookup.OwnerId, nil
+}
+
+func (r *accountRepository) CheckAmountForFromIban(ctx context.Context, iban string, amount domain.Money) (bool, error) {
+	query := "SELECT Balance, OverdraftLimit, HeldAmount FROM `accounts` WHERE Iban = $iban LIMIT 1"
 
 	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
 		Context:         ctx,
@@ -164,77 +367,756 @@ func (r *accountRepository) CheckAmountForFromIban(ctx context.Context, iban str
 	}
 	defer rows.Close()
 
-	var rawBalance struct {
-		Balance float64
-	}
+	var account domain.Account
 
 	// Check if there is a row
 	if rows.Next() {
-		if err := rows.Row(&rawBalance); err != nil {
+		if err := rows.Row(&account); err != nil {
 			zap.L().Error("Failed to scan row", zap.Error(err))
 			return false, err
 		}
 
-		return rawBalance.Balance >= amount, nil
+		return account.CanCover(amount)
 	}
 
 	// No matching account found
 	return false, nil
 }
 
-func (r *accountRepository) TransferMoney(ctx context.Context, fromIbanId, toIbanId string, amount float64) error {
-	var g errgroup.Group
+// TransferMoney executes transfer, a deposit or withdrawal included, and marks
+// it completed. The journal entry, the balance changes and the transfer record
+// commit together, so either the whole transfer is recorded or none of it is.
+func (r *accountRepository) TransferMoney(ctx context.Context, transfer *domain.Transfer) error {
+	if transfer.FromAccountId == transfer.ToAccountId {
+		return errors.New("cannot transfer to the same account")
+	}
+
+	entry, err := transferJournalEntry(transfer)
+	if err != nil {
+		return err
+	}
+
+	var completed domain.Transfer
+
+	err = runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		// A transfer retried under the same id, e.g. by a scheduled transfer
+		// whose worker died before recording the outcome, moves money once.
+		if err := checkTransferNotCompleted(tx, r.transferBucket.DefaultCollection(), transfer.Id); err != nil {
+			return err
+		}
+
+		accounts, err := postJournalEntry(tx, r.bucket.DefaultCollection(), r.ledgerBucket.DefaultCollection(), entry)
+		if err != nil {
+			return err
+		}
+
+		// Work on a copy so a rolled back attempt leaves the caller's transfer
+		// untouched.
+		completed = *transfer
+		completed.Complete(entry.Id, balanceAfter(accounts, transfer.FromAccountId), balanceAfter(accounts, transfer.ToAccountId))
+
+		return saveTransfer(tx, r.transferBucket.DefaultCollection(), &completed)
+	})
+
+	if err != nil {
+		zap.L().Error("Transfer transaction failed", zap.String("transferId", transfer.Id), zap.Error(err))
+		return err
+	}
+
+	*transfer = completed
+
+	return nil
+}
+
+// ChangeAccountStatus moves the account to any status but closed, which
+// CloseAccount handles, and stores audit with the change.
+func (r *accountRepository) ChangeAccountStatus(ctx context.Context, accountId string, status domain.AccountStatus, reason string, audit *domain.AuditRecord) (*domain.Account, error) {
+	if status == domain.AccountStatusClosed {
+		return nil, errors.New("accounts are closed with CloseAccount")
+	}
+
+	var changed domain.Account
 
-	// Using the errgroup to handle errors concurrently
-	g.Go(func() error {
-		fromAccount, err := r.GetAccount(ctx, fromIbanId)
+	err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		doc, account, err := r.getAccountInTx(tx, accountId)
 		if err != nil {
-			zap.L().Error("Failed to get from account", zap.String("fromIbanId", fromIbanId), zap.Error(err))
-			return fmt.Errorf("failed to get from account: %w", err)
+			return err
 		}
 
-		// Subtract the amount from the balance of the "from" account
-		newBalanceForFromAccount := fromAccount.Balance - amount
+		previous := account.EffectiveStatus()
+
+		if err := account.ChangeStatus(status, reason); err != nil {
+			return err
+		}
+
+		if _, err := tx.Replace(doc, account); err != nil {
+			return err
+		}
+
+		audit.Record("status", string(previous), string(status))
+
+		if _, err := tx.Insert(r.auditBucket.DefaultCollection(), audit.Id, audit); err != nil {
+			return err
+		}
 
-		_, err = r.bucket.DefaultCollection().MutateIn(fromIbanId, []gocb.MutateInSpec{
-			gocb.ReplaceSpec("Balance", newBalanceForFromAccount, &gocb.ReplaceSpecOptions{IsXattr: false}),
-		}, &gocb.MutateInOptions{Context: ctx})
+		changed = *account
 
+		return nil
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to change account status", zap.String("accountId", accountId), zap.Error(err))
+		return nil, err
+	}
+
+	return &changed, nil
+}
+
+// SetOverdraftLimit sets how far below zero the account may go; zero removes
+// the overdraft.
+func (r *accountRepository) SetOverdraftLimit(ctx context.Context, accountId string, limit domain.Money, audit *domain.AuditRecord) (*domain.Account, error) {
+	var changed domain.Account
+
+	err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		doc, account, err := r.getAccountInTx(tx, accountId)
 		if err != nil {
-			zap.L().Error("Failed to update balance for from account", zap.String("fromIbanId", fromIbanId), zap.Error(err))
-			return fmt.Errorf("failed to update balance for from account: %w", err)
+			return err
+		}
+
+		previous := account.EffectiveOverdraftLimit()
+
+		if err := account.SetOverdraftLimit(limit); err != nil {
+			return err
+		}
+
+		if _, err := account.TrackOverdraftBreach(account.UpdatedAt); err != nil {
+			return err
 		}
 
+		if _, err := tx.Replace(doc, account); err != nil {
+			return err
+		}
+
+		audit.Record("overdraftLimit", previous.String(), account.EffectiveOverdraftLimit().String())
+
+		if audit.HasChanges() {
+			if _, err := tx.Insert(r.auditBucket.DefaultCollection(), audit.Id, audit); err != nil {
+				return err
+			}
+		}
+
+		changed = *account
+
 		return nil
 	})
 
-	// Second Goroutine for updating the "to" account
-	g.Go(func() error {
-		toAccount, err := r.GetAccount(ctx, toIbanId)
+	if err != nil {
+		zap.L().Error("Failed to set overdraft limit", zap.String("accountId", accountId), zap.Error(err))
+		return nil, err
+	}
+
+	return &changed, nil
+}
+
+// GetOverdrawnAccounts returns the accounts with a negative balance.
+func (r *accountRepository) GetOverdrawnAccounts(ctx context.Context) ([]*domain.Account, error) {
+	return r.queryAccounts(ctx, "SELECT META(a).id, a.* FROM `accounts` a WHERE a.Balance.Minor < 0")
+}
+
+// GetOverdraftBreaches returns the accounts whose balance is past their
+// overdraft limit, longest breached first.
+func (r *accountRepository) GetOverdraftBreaches(ctx context.Context) ([]*domain.Account, error) {
+	return r.queryAccounts(ctx, "SELECT META(a).id, a.* FROM `accounts` a WHERE a.OverdraftBreachedAt IS VALUED ORDER BY a.OverdraftBreachedAt ASC")
+}
+
+// AccrueOverdraftInterest charges the account a day of interest at rate on its
+// balance as read in the transaction, and reports whether it did. Each account
+// is charged at most once for day, and not at all once it is back in credit.
+func (r *accountRepository) AccrueOverdraftInterest(ctx context.Context, accountId string, day time.Time, rate *domain.InterestRate) (bool, error) {
+	accrued := false
+
+	err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		accrued = false
+
+		_, err := tx.Get(r.ledgerBucket.DefaultCollection(), domain.OverdraftInterestEntryId(accountId, day))
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, gocb.ErrDocumentNotFound) {
+			return err
+		}
+
+		_, account, err := r.getAccountInTx(tx, accountId)
 		if err != nil {
-			zap.L().Error("Failed to get to account", zap.String("toIbanId", toIbanId), zap.Error(err))
-			return fmt.Errorf("failed to get to account: %w", err)
+			return err
 		}
 
-		// Add the amount to the balance of the "to" account
-		newBalanceForToAccount := toAccount.Balance + amount
+		if !account.Balance.IsNegative() {
+			return nil
+		}
 
-		_, err = r.bucket.DefaultCollection().MutateIn(toIbanId, []gocb.MutateInSpec{
-			gocb.ReplaceSpec("Balance", newBalanceForToAccount, &gocb.ReplaceSpecOptions{IsXattr: false}),
-		}, &gocb.MutateInOptions{Context: ctx})
+		interest, err := rate.DailyInterest(account.Balance)
+		if err != nil {
+			return err
+		}
 
+		if !interest.IsPositive() {
+			return nil
+		}
+
+		entry, err := domain.NewOverdraftInterestJournalEntry(accountId, day, interest)
 		if err != nil {
-			zap.L().Error("Failed to update balance for to account", zap.String("toIbanId", toIbanId), zap.Error(err))
-			return fmt.Errorf("failed to update balance for to account: %w", err)
+			return err
+		}
+
+		if _, err := postJournalEntry(tx, r.bucket.DefaultCollection(), r.ledgerBucket.DefaultCollection(), entry); err != nil {
+			return err
 		}
 
+		accrued = true
+
 		return nil
 	})
 
-	// Wait for both Goroutines to complete and return the first error if any
-	if err := g.Wait(); err != nil {
+	if err != nil {
+		zap.L().Error("Failed to accrue overdraft interest", zap.String("accountId", accountId), zap.Error(err))
+		return false, err
+	}
+
+	return accrued, nil
+}
+
+func (r *accountRepository) queryAccounts(ctx context.Context, query string) ([]*domain.Account, error) {
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context: ctx,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var accounts []*domain.Account
+	for rows.Next() {
+		var account domain.Account
+		if err := rows.Row(&account); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		accounts = append(accounts, &account)
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// CloseAccount closes the account. A positive balance is first swept to
+// sweepAccountId, which must be another open account of the same owner in the
+// same currency; without one only an empty account can be closed.
+func (r *accountRepository) CloseAccount(ctx context.Context, accountId, sweepAccountId, reason string, audit *domain.AuditRecord) (*domain.Account, error) {
+	var closed domain.Account
+
+	err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		_, account, err := r.getAccountInTx(tx, accountId)
+		if err != nil {
+			return err
+		}
+
+		previous := account.EffectiveStatus()
+
+		// Checked before sweeping, so the money of a frozen account stays put.
+		if err := account.CanChangeStatus(domain.AccountStatusClosed); err != nil {
+			return err
+		}
+
+		if account.HasHolds() {
+			return domain.ErrAccountHasHolds
+		}
+
+		if sweepAccountId != "" && account.Balance.IsPositive() {
+			transferId, err := r.sweepBalance(tx, account, sweepAccountId)
+			if err != nil {
+				return err
+			}
+
+			audit.Record("sweepTransferId", "", transferId)
+		}
+
+		// Read again to see the balance after the sweep.
+		doc, account, err := r.getAccountInTx(tx, accountId)
+		if err != nil {
+			return err
+		}
+
+		if err := account.ChangeStatus(domain.AccountStatusClosed, reason); err != nil {
+			return err
+		}
+
+		if _, err := tx.Replace(doc, account); err != nil {
+			return err
+		}
+
+		if err := decrementAccountCount(tx, r.lookupBucket.DefaultCollection(), account); err != nil {
+			return err
+		}
+
+		audit.Record("status", string(previous), string(domain.AccountStatusClosed))
+
+		if _, err := tx.Insert(r.auditBucket.DefaultCollection(), audit.Id, audit); err != nil {
+			return err
+		}
+
+		closed = *account
+
+		return nil
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to close account", zap.String("accountId", accountId), zap.Error(err))
+		return nil, err
+	}
+
+	return &closed, nil
+}
+
+// sweepBalance moves the whole balance of account to sweepAccountId as a
+// completed transfer and returns the transfer id.
+func (r *accountRepository) sweepBalance(tx *gocb.TransactionAttemptContext, account *domain.Account, sweepAccountId string) (string, error) {
+	_, target, err := r.getAccountInTx(tx, sweepAccountId)
+	if errors.Is(err, ErrAccountNotFound) {
+		return "", ErrInvalidSweepAccount
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if target.Id == account.Id || target.UserId != account.UserId || target.Currency != account.Currency || !target.CanCredit() {
+		return "", ErrInvalidSweepAccount
+	}
+
+	transfer := domain.NewTransfer(account.Id, account.Iban, target.Id, target.Iban, account.Balance)
+
+	entry, err := domain.NewJournalEntry(domain.JournalEntryAccountClosing, transfer.Id,
+		domain.Debit(account.Id, account.Balance),
+		domain.Credit(target.Id, account.Balance),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	accounts, err := postJournalEntry(tx, r.bucket.DefaultCollection(), r.ledgerBucket.DefaultCollection(), entry)
+	if err != nil {
+		return "", err
+	}
+
+	transfer.Complete(entry.Id, balanceAfter(accounts, account.Id), balanceAfter(accounts, target.Id))
+
+	if err := saveTransfer(tx, r.transferBucket.DefaultCollection(), transfer); err != nil {
+		return "", err
+	}
+
+	return transfer.Id, nil
+}
+
+func (r *accountRepository) getAccountInTx(tx *gocb.TransactionAttemptContext, accountId string) (*gocb.TransactionGetResult, *domain.Account, error) {
+	return getAccountInTx(tx, r.bucket.DefaultCollection(), accountId)
+}
+
+func getAccountInTx(tx *gocb.TransactionAttemptContext, accounts *gocb.Collection, accountId string) (*gocb.TransactionGetResult, *domain.Account, error) {
+	doc, err := tx.Get(accounts, accountId)
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return nil, nil, ErrAccountNotFound
+		}
+		return nil, nil, err
+	}
+
+	var account domain.Account
+	if err := doc.Content(&account); err != nil {
+		return nil, nil, err
+	}
+
+	return doc, &account, nil
+}
+
+func transferJournalEntry(transfer *domain.Transfer) (*domain.JournalEntry, error) {
+	switch transfer.EffectiveType() {
+	case domain.TransferTypeDeposit:
+		return domain.NewDepositJournalEntry(transfer.Id, transfer.ToAccountId, transfer.Amount)
+	case domain.TransferTypeWithdrawal:
+		return domain.NewWithdrawalJournalEntry(transfer.Id, transfer.FromAccountId, transfer.Amount)
+	}
+
+	if transfer.ConvertedAmount != nil {
+		spread := domain.ZeroMoney(transfer.ConvertedAmount.Currency)
+		if transfer.FxSpread != nil {
+			spread = *transfer.FxSpread
+		}
+
+		return domain.NewCrossCurrencyTransferJournalEntry(transfer.Id, transfer.FromAccountId, transfer.ToAccountId, transfer.Amount, *transfer.ConvertedAmount, spread)
+	}
+
+	return domain.NewTransferJournalEntry(transfer.Id, transfer.FromAccountId, transfer.ToAccountId, transfer.Amount)
+}
+
+// balanceAfter is the balance of accountId after the postings, or nil for the
+// bank's own accounts.
+func balanceAfter(accounts map[string]*domain.Account, accountId string) *domain.Money {
+	account, ok := accounts[accountId]
+	if !ok {
+		return nil
+	}
+
+	return &account.Balance
+}
+
+// MigrateLegacyBalances rewrites accounts whose Balance is still stored as a
+// float into minor units. Documents changed concurrently are skipped and picked
+// up by the next run.
+func (r *accountRepository) MigrateLegacyBalances(ctx context.Context) (int, error) {
+	query := "SELECT META(a).id AS Id, a.Balance, a.Currency FROM `accounts` a WHERE IS_NUMBER(a.Balance)"
+
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context: ctx,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	type legacyAccountBalance struct {
+		Id       string
+		Balance  float64
+		Currency string
+	}
+
+	var legacyAccounts []legacyAccountBalance
+	for rows.Next() {
+		var legacyAccount legacyAccountBalance
+
+		if err := rows.Row(&legacyAccount); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return 0, err
+		}
+
+		legacyAccounts = append(legacyAccounts, legacyAccount)
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return 0, err
+	}
+
+	migrated := 0
+	for _, legacyAccount := range legacyAccounts {
+		balance, err := domain.MoneyFromFloat(legacyAccount.Balance, legacyAccount.Currency)
+		if err != nil {
+			zap.L().Error("Failed to convert legacy balance", zap.String("accountId", legacyAccount.Id), zap.Error(err))
+			continue
+		}
+
+		// The legacy balance is booked against the migration equity account so
+		// the ledger explains the amount the account starts from.
+		entry, err := domain.NewJournalEntry(domain.JournalEntryLegacyBalance, legacyAccount.Id,
+			balanceSourcePosting(balance),
+			balanceTargetPosting(legacyAccount.Id, balance),
+		)
+		if err != nil {
+			return migrated, err
+		}
+
+		err = runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+			doc, err := tx.Get(r.bucket.DefaultCollection(), legacyAccount.Id)
+			if err != nil {
+				return err
+			}
+
+			var account map[string]interface{}
+			if err := doc.Content(&account); err != nil {
+				return err
+			}
+
+			// The query result may be stale; only rewrite if the stored value
+			// is still the float we converted.
+			if currentBalance, ok := account["Balance"].(float64); !ok || currentBalance != legacyAccount.Balance {
+				return errLegacyBalanceChanged
+			}
+
+			account["Balance"] = balance
+
+			if _, err := tx.Replace(doc, account); err != nil {
+				return err
+			}
+
+			_, err = tx.Insert(r.ledgerBucket.DefaultCollection(), entry.Id, entry)
+			return err
+		})
+
+		if err != nil {
+			if errors.Is(err, errLegacyBalanceChanged) || errors.Is(err, ErrConcurrentUpdate) {
+				continue
+			}
+
+			zap.L().Error("Failed to migrate legacy balance", zap.String("accountId", legacyAccount.Id), zap.Error(err))
+			return migrated, err
+		}
+
+		migrated++
+	}
+
+	return migrated, nil
+}
+
+// balanceSourcePosting and balanceTargetPosting book a legacy balance, which
+// may be negative, as a pair of non-negative postings.
+func balanceSourcePosting(balance domain.Money) domain.Posting {
+	source := domain.InternalLedgerAccount("migration-equity", balance.Currency)
+
+	if balance.IsNegative() {
+		return domain.Credit(source, balance.Negate())
+	}
+
+	return domain.Debit(source, balance)
+}
+
+func balanceTargetPosting(accountId string, balance domain.Money) domain.Posting {
+	if balance.IsNegative() {
+		return domain.Debit(accountId, balance.Negate())
+	}
+
+	return domain.Credit(accountId, balance)
+}
+
+// maxIbanReissueAttempts bounds how often a reissue draws a new IBAN after
+// drawing one that is already taken.
+const maxIbanReissueAttempts = 5
+
+// BackfillIbanLookups reserves the IBANs of accounts created before IBANs were
+// reserved, so FindByIban can resolve them, and reissues the IBANs validate
+// rejects: every account opened before IBANs carried real check digits holds
+// one. Duplicate IBANs among valid ones cannot be reserved twice; they are
+// logged and left to be fixed by hand. It returns how many accounts changed.
+func (r *accountRepository) BackfillIbanLookups(ctx context.Context, validate func(iban string) error, generate func() (string, error)) (int, error) {
+	query := "SELECT META(a).id AS Id, a.Iban FROM `accounts` a WHERE a.Iban IS VALUED"
+
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		ScanConsistency: gocb.QueryScanConsistencyRequestPlus,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	lookups := r.lookupBucket.DefaultCollection()
+	backfilled := 0
+
+	for rows.Next() {
+		var account struct {
+			Id   string
+			Iban string
+		}
+
+		if err := rows.Row(&account); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return backfilled, err
+		}
+
+		if validate(account.Iban) != nil {
+			if err := r.reissueIban(ctx, account.Id, account.Iban, generate); err != nil {
+				return backfilled, err
+			}
+
+			backfilled++
+			continue
+		}
+
+		key := ibanLookupKey(account.Iban)
+
+		_, err := lookups.Insert(key, lookup{Key: key, OwnerId: account.Id, CreatedAt: time.Now()}, &gocb.InsertOptions{
+			Timeout: 3 * time.Second,
+			Context: ctx,
+		})
+
+		if err != nil {
+			if !errors.Is(err, gocb.ErrDocumentExists) {
+				return backfilled, err
+			}
+
+			if ownerId, err := r.FindByIban(ctx, account.Iban); err == nil && ownerId != account.Id {
+				zap.L().Warn("IBAN is shared by more than one account", zap.String("iban", account.Iban), zap.String("accountId", account.Id), zap.String("ownerId", ownerId))
+			}
+
+			continue
+		}
+
+		backfilled++
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return backfilled, err
+	}
+
+	return backfilled, nil
+}
+
+// reissueIban gives the account a newly generated IBAN in place of oldIban.
+// The new IBAN is reserved, the account and its pending scheduled transfers
+// rewritten and the old lookup released in one transaction, so the account is
+// never without an IBAN that resolves to it. An account whose IBAN already
+// changed is left alone.
+func (r *accountRepository) reissueIban(ctx context.Context, accountId, oldIban string, generate func() (string, error)) error {
+	scheduledIds, err := r.scheduledTransfersNaming(ctx, oldIban)
+	if err != nil {
 		return err
 	}
 
+	for attempt := 1; ; attempt++ {
+		newIban, err := generate()
+		if err != nil {
+			return err
+		}
+
+		err = runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+			doc, account, err := r.getAccountInTx(tx, accountId)
+			if err != nil {
+				return err
+			}
+
+			if account.Iban != oldIban {
+				return nil
+			}
+
+			if err := reserveLookup(tx, r.lookupBucket.DefaultCollection(), ibanLookupKey(newIban), accountId, ErrIbanTaken); err != nil {
+				return err
+			}
+
+			if err := releaseLookup(tx, r.lookupBucket.DefaultCollection(), ibanLookupKey(oldIban), accountId); err != nil {
+				return err
+			}
+
+			account.Iban = newIban
+			account.UpdatedAt = time.Now()
+
+			if _, err := tx.Replace(doc, account); err != nil {
+				return err
+			}
+
+			if err := reissueScheduledIban(tx, r.scheduledBucket.DefaultCollection(), scheduledIds, oldIban, newIban); err != nil {
+				return err
+			}
+
+			audit := domain.NewAuditRecord("account", accountId, domain.AuditAccountIbanReissued, domain.AuditSystemActor)
+			audit.Record("iban", oldIban, newIban)
+			audit.Reason = "IBAN was issued without valid check digits"
+
+			_, err = tx.Insert(r.auditBucket.DefaultCollection(), audit.Id, audit)
+			return err
+		})
+
+		if errors.Is(err, ErrIbanTaken) && attempt < maxIbanReissueAttempts {
+			continue
+		}
+
+		if err != nil {
+			zap.L().Error("Failed to reissue IBAN", zap.String("accountId", accountId), zap.Error(err))
+			return err
+		}
+
+		zap.L().Info("Reissued IBAN", zap.String("accountId", accountId), zap.String("oldIban", oldIban), zap.String("newIban", newIban))
+
+		return nil
+	}
+}
+
+// scheduledTransfersNaming returns the ids of scheduled transfers still to be
+// executed that name iban on either side.
+func (r *accountRepository) scheduledTransfersNaming(ctx context.Context, iban string) ([]string, error) {
+	query := "SELECT RAW META(s).id FROM `scheduled_transfers` s WHERE s.Status = $status AND (s.FromIban = $iban OR s.ToIban = $iban)"
+
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: map[string]interface{}{"status": domain.ScheduledTransferStatusScheduled, "iban": iban},
+		ScanConsistency: gocb.QueryScanConsistencyRequestPlus,
+		Adhoc:           true,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Row(&id); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// reissueScheduledIban replaces oldIban with newIban on the scheduled
+// transfers ids that are still to be executed.
+func reissueScheduledIban(tx *gocb.TransactionAttemptContext, scheduledTransfers *gocb.Collection, ids []string, oldIban, newIban string) error {
+	for _, id := range ids {
+		doc, err := tx.Get(scheduledTransfers, id)
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var scheduled domain.ScheduledTransfer
+		if err := doc.Content(&scheduled); err != nil {
+			return err
+		}
+
+		if scheduled.Status != domain.ScheduledTransferStatusScheduled {
+			continue
+		}
+
+		if scheduled.FromIban == oldIban {
+			scheduled.FromIban = newIban
+		}
+
+		if scheduled.ToIban == oldIban {
+			scheduled.ToIban = newIban
+		}
+
+		scheduled.UpdatedAt = time.Now()
+
+		if _, err := tx.Replace(doc, &scheduled); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"kc-bank/domain"
+	errorresponse "kc-bank/pkg/error_response"
+	"net/http"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"go.uber.org/zap"
+)
+
+// ErrFxQuoteNotFound covers expired quotes too: they are removed once they
+// expire.
+var ErrFxQuoteNotFound = errorresponse.NewFieldError(http.StatusUnprocessableEntity, "quoteId", "quote does not exist or has expired")
+
+type IFxQuoteRepository interface {
+	CreateQuote(ctx context.Context, quote *domain.FxQuote) error
+	GetQuote(ctx context.Context, id string) (*domain.FxQuote, error)
+}
+
+type fxQuoteRepository struct {
+	bucket *gocb.Bucket
+}
+
+func NewFxQuoteRepository(bucket *gocb.Bucket) IFxQuoteRepository {
+	return &fxQuoteRepository{
+		bucket: bucket,
+	}
+}
+
+func (r *fxQuoteRepository) CreateQuote(ctx context.Context, quote *domain.FxQuote) error {
+	// Kept a little past expiry, so a quote used at the last moment is still
+	// found and reported as expired by its ExpiresAt.
+	_, err := r.bucket.DefaultCollection().Insert(quote.Id, quote, &gocb.InsertOptions{
+		Expiry:  time.Until(quote.ExpiresAt) + time.Minute,
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to create fx quote", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// GetQuote returns ErrFxQuoteNotFound for unknown and expired quotes.
+func (r *fxQuoteRepository) GetQuote(ctx context.Context, id string) (*domain.FxQuote, error) {
+	data, err := r.bucket.DefaultCollection().Get(id, &gocb.GetOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return nil, ErrFxQuoteNotFound
+		}
+
+		zap.L().Error("Failed to get fx quote", zap.Error(err))
+		return nil, err
+	}
+
+	var quote domain.FxQuote
+	if err := data.Content(&quote); err != nil {
+		zap.L().Error("Failed to unmarshal fx quote", zap.Error(err))
+		return nil, err
+	}
+
+	if quote.IsExpired() {
+		return nil, ErrFxQuoteNotFound
+	}
+
+	return &quote, nil
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"kc-bank/domain"
+	errorresponse "kc-bank/pkg/error_response"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"go.uber.org/zap"
+)
+
+var ErrHoldNotFound = errorresponse.NewNotFoundError("hold not found")
+
+type IHoldRepository interface {
+	CreateHold(ctx context.Context, hold *domain.Hold) error
+	GetHold(ctx context.Context, id string) (*domain.Hold, error)
+	GetHoldsByAccount(ctx context.Context, accountId string) ([]*domain.Hold, error)
+	GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]*domain.Hold, error)
+	CaptureHold(ctx context.Context, holdId string, transfer *domain.Transfer) (*domain.Hold, error)
+	EndHold(ctx context.Context, holdId string, status domain.HoldStatus) (*domain.Hold, error)
+}
+
+type holdRepository struct {
+	cluster        *gocb.Cluster
+	bucket         *gocb.Bucket
+	accountBucket  *gocb.Bucket
+	ledgerBucket   *gocb.Bucket
+	transferBucket *gocb.Bucket
+}
+
+func NewHoldRepository(cluster *gocb.Cluster, bucket, accountBucket, ledgerBucket, transferBucket *gocb.Bucket) IHoldRepository {
+	return &holdRepository{
+		cluster:        cluster,
+		bucket:         bucket,
+		accountBucket:  accountBucket,
+		ledgerBucket:   ledgerBucket,
+		transferBucket: transferBucket,
+	}
+}
+
+// CreateHold stores hold and adds it to its account's held amount, checking in
+// the same transaction that the account may pay it.
+func (r *holdRepository) CreateHold(ctx context.Context, hold *domain.Hold) error {
+	err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		doc, account, err := getAccountInTx(tx, r.accountBucket.DefaultCollection(), hold.AccountId)
+		if err != nil {
+			return err
+		}
+
+		if !account.CanDebit() {
+			return ErrAccountDebitBlocked
+		}
+
+		canCover, err := account.CanCover(hold.Amount)
+		if err != nil {
+			return err
+		}
+
+		if !canCover {
+			return ErrBalanceNotEnough
+		}
+
+		if err := account.AddHeld(hold.Amount); err != nil {
+			return err
+		}
+
+		if _, err := tx.Replace(doc, account); err != nil {
+			return err
+		}
+
+		_, err = tx.Insert(r.bucket.DefaultCollection(), hold.Id, hold)
+		return err
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to create hold", zap.String("holdId", hold.Id), zap.String("accountId", hold.AccountId), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *holdRepository) GetHold(ctx context.Context, id string) (*domain.Hold, error) {
+	data, err := r.bucket.DefaultCollection().Get(id, &gocb.GetOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return nil, ErrHoldNotFound
+		}
+
+		zap.L().Error("Failed to get hold", zap.Error(err))
+		return nil, err
+	}
+
+	var hold domain.Hold
+	if err := data.Content(&hold); err != nil {
+		zap.L().Error("Failed to unmarshal hold", zap.Error(err))
+		return nil, err
+	}
+
+	return &hold, nil
+}
+
+func (r *holdRepository) GetHoldsByAccount(ctx context.Context, accountId string) ([]*domain.Hold, error) {
+	query := "SELECT META(h).id, h.* FROM `holds` h WHERE h.AccountId = $accountId ORDER BY STR_TO_MILLIS(h.CreatedAt) DESC"
+
+	return r.queryHolds(ctx, query, map[string]interface{}{"accountId": accountId})
+}
+
+// GetExpiredHolds returns up to limit active holds that expired by now, the
+// longest expired first.
+func (r *holdRepository) GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]*domain.Hold, error) {
+	query := "SELECT META(h).id, h.* FROM `holds` h WHERE h.Status = $status AND STR_TO_MILLIS(h.ExpiresAt) <= $now ORDER BY STR_TO_MILLIS(h.ExpiresAt) ASC LIMIT $limit"
+
+	return r.queryHolds(ctx, query, map[string]interface{}{
+		"status": domain.HoldStatusActive,
+		"now":    now.UnixMilli(),
+		"limit":  limit,
+	})
+}
+
+// CaptureHold ends the hold by executing transfer, which must take no more
+// than the hold from the hold's account. The hold, the account's held amount,
+// the journal entry and the transfer record commit together.
+func (r *holdRepository) CaptureHold(ctx context.Context, holdId string, transfer *domain.Transfer) (*domain.Hold, error) {
+	entry, err := transferJournalEntry(transfer)
+	if err != nil {
+		return nil, err
+	}
+
+	var captured domain.Hold
+	var completed domain.Transfer
+
+	err = runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		holdDoc, hold, err := r.getHoldInTx(tx, holdId)
+		if err != nil {
+			return err
+		}
+
+		if transfer.FromAccountId != hold.AccountId {
+			return errors.New("capture must be paid from the held account")
+		}
+
+		if err := hold.Capture(transfer.Amount, transfer.Id, time.Now()); err != nil {
+			return err
+		}
+
+		// The whole hold is freed before the capture is posted, so what was
+		// reserved for it counts as available again.
+		if err := r.releaseHeld(tx, hold); err != nil {
+			return err
+		}
+
+		accounts, err := postJournalEntry(tx, r.accountBucket.DefaultCollection(), r.ledgerBucket.DefaultCollection(), entry)
+		if err != nil {
+			return err
+		}
+
+		completed = *transfer
+		completed.Complete(entry.Id, balanceAfter(accounts, transfer.FromAccountId), balanceAfter(accounts, transfer.ToAccountId))
+
+		if err := saveTransfer(tx, r.transferBucket.DefaultCollection(), &completed); err != nil {
+			return err
+		}
+
+		if _, err := tx.Replace(holdDoc, hold); err != nil {
+			return err
+		}
+
+		captured = *hold
+
+		return nil
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to capture hold", zap.String("holdId", holdId), zap.String("transferId", transfer.Id), zap.Error(err))
+		return nil, err
+	}
+
+	*transfer = completed
+
+	return &captured, nil
+}
+
+// EndHold releases the hold with status RELEASED or EXPIRED and returns its
+// amount to its account's available balance.
+func (r *holdRepository) EndHold(ctx context.Context, holdId string, status domain.HoldStatus) (*domain.Hold, error) {
+	var ended domain.Hold
+
+	err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		holdDoc, hold, err := r.getHoldInTx(tx, holdId)
+		if err != nil {
+			return err
+		}
+
+		if err := hold.End(status, time.Now()); err != nil {
+			return err
+		}
+
+		if err := r.releaseHeld(tx, hold); err != nil {
+			return err
+		}
+
+		if _, err := tx.Replace(holdDoc, hold); err != nil {
+			return err
+		}
+
+		ended = *hold
+
+		return nil
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to end hold", zap.String("holdId", holdId), zap.String("status", string(status)), zap.Error(err))
+		return nil, err
+	}
+
+	return &ended, nil
+}
+
+// releaseHeld takes the amount of hold off its account's held amount.
+func (r *holdRepository) releaseHeld(tx *gocb.TransactionAttemptContext, hold *domain.Hold) error {
+	doc, account, err := getAccountInTx(tx, r.accountBucket.DefaultCollection(), hold.AccountId)
+	if err != nil {
+		return err
+	}
+
+	if err := account.AddHeld(hold.Amount.Negate()); err != nil {
+		return err
+	}
+
+	_, err = tx.Replace(doc, account)
+	return err
+}
+
+func (r *holdRepository) getHoldInTx(tx *gocb.TransactionAttemptContext, holdId string) (*gocb.TransactionGetResult, *domain.Hold, error) {
+	doc, err := tx.Get(r.bucket.DefaultCollection(), holdId)
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return nil, nil, ErrHoldNotFound
+		}
+		return nil, nil, err
+	}
+
+	var hold domain.Hold
+	if err := doc.Content(&hold); err != nil {
+		return nil, nil, err
+	}
+
+	return doc, &hold, nil
+}
+
+func (r *holdRepository) queryHolds(ctx context.Context, query string, params map[string]interface{}) ([]*domain.Hold, error) {
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: params,
+		Adhoc:           true,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var holds []*domain.Hold
+	for rows.Next() {
+		var hold domain.Hold
+		if err := rows.Row(&hold); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		holds = append(holds, &hold)
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return nil, err
+	}
+
+	return holds, nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"kc-bank/domain"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"go.uber.org/zap"
+)
+
+type IIdempotencyRepository interface {
+	Reserve(ctx context.Context, key, requestHash string) (*domain.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, record *domain.IdempotencyRecord) error
+	MarkAmbiguous(ctx context.Context, record *domain.IdempotencyRecord) error
+	Release(ctx context.Context, key string) error
+}
+
+type idempotencyRepository struct {
+	bucket   *gocb.Bucket
+	ttl      time.Duration
+	leaseTTL time.Duration
+}
+
+// NewIdempotencyRepository keeps outcomes for ttl. A reservation expires after
+// leaseTTL unless its request completes, so a request that crashed mid-way
+// does not block its key for the full ttl.
+func NewIdempotencyRepository(bucket *gocb.Bucket, ttl, leaseTTL time.Duration) IIdempotencyRepository {
+	return &idempotencyRepository{
+		bucket:   bucket,
+		ttl:      ttl,
+		leaseTTL: leaseTTL,
+	}
+}
+
+// Reserve claims key for a new request. If the key is already taken, the
+// stored record is returned instead and the boolean is false. The insert is
+// atomic, so of two concurrent requests with the same key only one runs.
+func (r *idempotencyRepository) Reserve(ctx context.Context, key, requestHash string) (*domain.IdempotencyRecord, bool, error) {
+	record := &domain.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   time.Now(),
+	}
+
+	_, err := r.bucket.DefaultCollection().Insert(key, record, &gocb.InsertOptions{
+		Expiry:  r.leaseTTL,
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err == nil {
+		return record, true, nil
+	}
+
+	if !errors.Is(err, gocb.ErrDocumentExists) {
+		zap.L().Error("Failed to reserve idempotency key", zap.Error(err))
+		return nil, false, err
+	}
+
+	data, err := r.bucket.DefaultCollection().Get(key, &gocb.GetOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to get idempotency record", zap.Error(err))
+		return nil, false, err
+	}
+
+	var existing domain.IdempotencyRecord
+	if err := data.Content(&existing); err != nil {
+		zap.L().Error("Failed to unmarshal idempotency record", zap.Error(err))
+		return nil, false, err
+	}
+
+	return &existing, false, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, record *domain.IdempotencyRecord) error {
+	record.Completed = true
+
+	_, err := r.bucket.DefaultCollection().Replace(record.Key, record, &gocb.ReplaceOptions{
+		Expiry:  r.ttl,
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to complete idempotency record", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// MarkAmbiguous keeps the key reserved for the full ttl, so retries of a
+// request that may have taken effect are refused rather than run again.
+func (r *idempotencyRepository) MarkAmbiguous(ctx context.Context, record *domain.IdempotencyRecord) error {
+	record.Ambiguous = true
+
+	_, err := r.bucket.DefaultCollection().Replace(record.Key, record, &gocb.ReplaceOptions{
+		Expiry:  r.ttl,
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to mark idempotency record ambiguous", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key string) error {
+	_, err := r.bucket.DefaultCollection().Remove(key, &gocb.RemoveOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil && !errors.Is(err, gocb.ErrDocumentNotFound) {
+		zap.L().Error("Failed to release idempotency key", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
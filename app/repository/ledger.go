@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"kc-bank/domain"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"go.uber.org/zap"
+)
+
+// postJournalEntry appends entry to the ledger and applies its postings to the
+// cached balances of the customer accounts it touches, all inside tx. A
+// posting that would take a customer balance past its overdraft limit fails
+// the whole transaction with ErrBalanceNotEnough; interest is charged
+// regardless, and an account it takes past the limit is reported as breached.
+func postJournalEntry(tx *gocb.TransactionAttemptContext, accounts, ledger *gocb.Collection, entry *domain.JournalEntry) (map[string]*domain.Account, error) {
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Group postings by account so an account that appears more than once is
+	// read and written a single time.
+	var accountIds []string
+	effects := make(map[string][]domain.Posting)
+
+	for _, posting := range entry.Postings {
+		if domain.IsInternalLedgerAccount(posting.AccountId) {
+			continue
+		}
+
+		if _, ok := effects[posting.AccountId]; !ok {
+			accountIds = append(accountIds, posting.AccountId)
+		}
+
+		effects[posting.AccountId] = append(effects[posting.AccountId], posting)
+	}
+
+	updated := make(map[string]*domain.Account, len(accountIds))
+
+	for _, accountId := range accountIds {
+		doc, err := tx.Get(accounts, accountId)
+		if err != nil {
+			zap.L().Error("Failed to get account for posting", zap.String("accountId", accountId), zap.Error(err))
+			return nil, err
+		}
+
+		var account domain.Account
+		if err := doc.Content(&account); err != nil {
+			zap.L().Error("Failed to unmarshal account", zap.String("accountId", accountId), zap.Error(err))
+			return nil, err
+		}
+
+		breached, err := applyPostings(&account, entry.Type, effects[accountId], time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		if breached {
+			zap.L().Warn("Account overdraft limit breached", zap.String("accountId", accountId), zap.String("balance", account.Balance.String()), zap.String("overdraftLimit", account.EffectiveOverdraftLimit().String()), zap.String("entryId", entry.Id))
+		}
+
+		if _, err := tx.Replace(doc, account); err != nil {
+			zap.L().Error("Failed to update balance", zap.String("accountId", accountId), zap.Error(err))
+			return nil, err
+		}
+
+		updated[accountId] = &account
+	}
+
+	if _, err := tx.Insert(ledger, entry.Id, entry); err != nil {
+		zap.L().Error("Failed to insert journal entry", zap.String("entryId", entry.Id), zap.Error(err))
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// applyPostings books postings, all of them for account, onto its balance. It
+// refuses postings the account status does not allow and a decrease that
+// would take the balance below what the account may spend; interest is
+// charged regardless. It reports whether the account newly breached its
+// overdraft limit.
+func applyPostings(account *domain.Account, entryType domain.JournalEntryType, postings []domain.Posting, now time.Time) (bool, error) {
+	if err := checkPostingsAllowed(account, entryType, postings); err != nil {
+		return false, err
+	}
+
+	// The balance is checked on the snapshot read by this transaction, not on
+	// an earlier query result that may already be stale.
+	newBalance := account.Balance
+	for _, posting := range postings {
+		var err error
+		if newBalance, err = newBalance.Add(posting.BalanceEffect()); err != nil {
+			return false, err
+		}
+	}
+
+	decreased := newBalance.Minor < account.Balance.Minor
+
+	account.Balance = newBalance
+	account.UpdatedAt = now
+
+	available, err := account.AvailableBalance()
+	if err != nil {
+		return false, err
+	}
+
+	if decreased && available.IsNegative() && entryType != domain.JournalEntryInterest {
+		return false, ErrBalanceNotEnough
+	}
+
+	return account.TrackOverdraftBreach(now)
+}
+
+// checkPostingsAllowed enforces the account status on postings, inside the
+// transaction so a freeze applies to transfers already in flight. Closing
+// sweeps are exempt: they are what empties an account an admin is closing. So
+// is interest, which a freeze does not stop from accruing.
+func checkPostingsAllowed(account *domain.Account, entryType domain.JournalEntryType, postings []domain.Posting) error {
+	if entryType == domain.JournalEntryAccountClosing || entryType == domain.JournalEntryInterest {
+		return nil
+	}
+
+	for _, posting := range postings {
+		if posting.Direction == domain.PostingDebit && !account.CanDebit() {
+			return ErrAccountDebitBlocked
+		}
+
+		if posting.Direction == domain.PostingCredit && !account.CanCredit() {
+			return ErrAccountCreditBlocked
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"kc-bank/domain"
+
+	"github.com/couchbase/gocb/v2"
+	"go.uber.org/zap"
+)
+
+type ILedgerRepository interface {
+	GetJournalEntriesByAccount(ctx context.Context, accountId string) ([]*domain.JournalEntry, error)
+	GetAccountBalance(ctx context.Context, accountId, currency string) (domain.Money, error)
+}
+
+type ledgerRepository struct {
+	cluster *gocb.Cluster
+	bucket  *gocb.Bucket
+}
+
+func NewLedgerRepository(cluster *gocb.Cluster, bucket *gocb.Bucket) ILedgerRepository {
+	return &ledgerRepository{
+		cluster: cluster,
+		bucket:  bucket,
+	}
+}
+
+func (r *ledgerRepository) GetJournalEntriesByAccount(ctx context.Context, accountId string) ([]*domain.JournalEntry, error) {
+	// CreatedAt is RFC 3339 text, which does not sort lexically; order on
+	// epoch millis and break ties on the id so the order is stable.
+	query := "SELECT l.* FROM `ledger` l WHERE ANY p IN l.Postings SATISFIES p.AccountId = $accountId END " +
+		"ORDER BY STR_TO_MILLIS(l.CreatedAt) ASC, l.Id ASC"
+
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: map[string]interface{}{"accountId": accountId},
+		Adhoc:           true,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var entries []*domain.JournalEntry
+	for rows.Next() {
+		var entry domain.JournalEntry
+		if err := rows.Row(&entry); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetAccountBalance derives an account balance from its postings alone. It is
+// the source of truth that the cached Account.Balance is reconciled against.
+func (r *ledgerRepository) GetAccountBalance(ctx context.Context, accountId, currency string) (domain.Money, error) {
+	query := "SELECT RAW SUM(CASE WHEN p.Direction = 'CREDIT' THEN p.Amount.Minor ELSE -p.Amount.Minor END) " +
+		"FROM `ledger` l UNNEST l.Postings p WHERE p.AccountId = $accountId AND p.Amount.Currency = $currency"
+
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: map[string]interface{}{"accountId": accountId, "currency": currency},
+		Adhoc:           true,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return domain.Money{}, err
+	}
+
+	defer rows.Close()
+
+	var minor *int64
+	if rows.Next() {
+		if err := rows.Row(&minor); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return domain.Money{}, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return domain.Money{}, err
+	}
+
+	// SUM over no postings yields null.
+	if minor == nil {
+		return domain.ZeroMoney(currency), nil
+	}
+
+	return domain.NewMoney(*minor, currency), nil
+}
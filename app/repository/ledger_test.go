@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"errors"
+	"kc-bank/domain"
+	"testing"
+	"time"
+)
+
+func TestPostJournalEntryRejectsUnbalancedEntries(t *testing.T) {
+	tests := []struct {
+		name     string
+		postings []domain.Posting
+	}{
+		{
+			name:     "debits exceed credits",
+			postings: []domain.Posting{domain.Debit("a", domain.NewMoney(1000, "TRY")), domain.Credit("b", domain.NewMoney(10, "TRY"))},
+		},
+		{
+			name:     "zero against non-zero",
+			postings: []domain.Posting{domain.Debit("a", domain.ZeroMoney("TRY")), domain.Credit("b", domain.NewMoney(10, "TRY"))},
+		},
+		{
+			name:     "mixed currencies",
+			postings: []domain.Posting{domain.Debit("a", domain.NewMoney(1000, "TRY")), domain.Credit("b", domain.NewMoney(1000, "EUR"))},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Built by hand: the domain constructors refuse these entries too.
+			entry := &domain.JournalEntry{Id: "entry", Type: domain.JournalEntryTransfer, Postings: tt.postings}
+
+			// An unbalanced entry must be refused before the transaction is
+			// touched, so no transaction is needed.
+			_, err := postJournalEntry(nil, nil, nil, entry)
+
+			if !errors.Is(err, domain.ErrUnbalancedJournalEntry) {
+				t.Fatalf("postJournalEntry() error = %v, want %v", err, domain.ErrUnbalancedJournalEntry)
+			}
+		})
+	}
+}
+
+func TestApplyPostings(t *testing.T) {
+	try := func(minor int64) domain.Money { return domain.NewMoney(minor, "TRY") }
+	ptr := func(m domain.Money) *domain.Money { return &m }
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		account      domain.Account
+		entryType    domain.JournalEntryType
+		postings     []domain.Posting
+		wantBalance  int64
+		wantBreached bool
+		wantErr      error
+	}{
+		{
+			name:        "credit",
+			account:     domain.Account{Balance: try(1000)},
+			postings:    []domain.Posting{domain.Credit("a", try(500))},
+			wantBalance: 1500,
+		},
+		{
+			name:        "debit within balance",
+			account:     domain.Account{Balance: try(1000)},
+			postings:    []domain.Posting{domain.Debit("a", try(1000))},
+			wantBalance: 0,
+		},
+		{
+			name:     "debit beyond balance",
+			account:  domain.Account{Balance: try(1000)},
+			postings: []domain.Posting{domain.Debit("a", try(1001))},
+			wantErr:  ErrBalanceNotEnough,
+		},
+		{
+			name:        "zero debit",
+			account:     domain.Account{Balance: try(0)},
+			postings:    []domain.Posting{domain.Debit("a", try(0))},
+			wantBalance: 0,
+		},
+		{
+			name:        "debit and credit of the same account net out",
+			account:     domain.Account{Balance: try(100)},
+			postings:    []domain.Posting{domain.Debit("a", try(500)), domain.Credit("a", try(450))},
+			wantBalance: 50,
+		},
+		{
+			name:        "debit into overdraft",
+			account:     domain.Account{Balance: try(1000), OverdraftLimit: ptr(try(5000))},
+			postings:    []domain.Posting{domain.Debit("a", try(6000))},
+			wantBalance: -5000,
+		},
+		{
+			name:     "debit beyond overdraft",
+			account:  domain.Account{Balance: try(1000), OverdraftLimit: ptr(try(5000))},
+			postings: []domain.Posting{domain.Debit("a", try(6001))},
+			wantErr:  ErrBalanceNotEnough,
+		},
+		{
+			name:     "debit of held money",
+			account:  domain.Account{Balance: try(1000), HeldAmount: ptr(try(600))},
+			postings: []domain.Posting{domain.Debit("a", try(500))},
+			wantErr:  ErrBalanceNotEnough,
+		},
+		{
+			name:         "interest may breach the overdraft",
+			account:      domain.Account{Balance: try(-5000), OverdraftLimit: ptr(try(5000))},
+			entryType:    domain.JournalEntryInterest,
+			postings:     []domain.Posting{domain.Debit("a", try(3))},
+			wantBalance:  -5003,
+			wantBreached: true,
+		},
+		{
+			name:     "debit of a frozen account",
+			account:  domain.Account{Balance: try(1000), Status: domain.AccountStatusFrozenDebit},
+			postings: []domain.Posting{domain.Debit("a", try(1))},
+			wantErr:  ErrAccountDebitBlocked,
+		},
+		{
+			name:     "credit of a fully frozen account",
+			account:  domain.Account{Balance: try(1000), Status: domain.AccountStatusFrozenAll},
+			postings: []domain.Posting{domain.Credit("a", try(1))},
+			wantErr:  ErrAccountCreditBlocked,
+		},
+		{
+			name:     "posting in another currency",
+			account:  domain.Account{Balance: try(1000)},
+			postings: []domain.Posting{domain.Credit("a", domain.NewMoney(1, "USD"))},
+			wantErr:  domain.ErrCurrencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := tt.account
+			entryType := tt.entryType
+			if entryType == "" {
+				entryType = domain.JournalEntryTransfer
+			}
+
+			breached, err := applyPostings(&account, entryType, tt.postings, now)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("applyPostings() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if account.Balance.Minor != tt.wantBalance {
+				t.Errorf("balance = %d, want %d", account.Balance.Minor, tt.wantBalance)
+			}
+
+			if breached != tt.wantBreached {
+				t.Errorf("breached = %v, want %v", breached, tt.wantBreached)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+)
+
+// lookup is a document in the lookups bucket whose key is a unique business
+// value, e.g. "iban::TR33...". Key uniqueness is what enforces uniqueness of
+// the value: inserting a second lookup with the same key fails on every node.
+type lookup struct {
+	Key       string
+	OwnerId   string
+	CreatedAt time.Time
+}
+
+func ibanLookupKey(iban string) string {
+	return "iban::" + iban
+}
+
+func emailLookupKey(canonicalEmail string) string {
+	return "email::" + canonicalEmail
+}
+
+// nationalIdLookupKey takes a blind index rather than the national id, which
+// must not appear in plain text anywhere in the database.
+func nationalIdLookupKey(nationalIdIndex string) string {
+	return "national-id::" + nationalIdIndex
+}
+
+// accountCount tracks how many accounts a user holds in one currency. Every
+// account creation updates it in its transaction, so concurrent creations are
+// serialised on it and cannot exceed the limit together.
+type accountCount struct {
+	UserId   string
+	Currency string
+	Count    int
+}
+
+func accountCountKey(userId, currency string) string {
+	return "account-count::" + userId + "::" + currency
+}
+
+// reserveLookup claims key for ownerId inside tx and returns taken if another
+// owner already holds it.
+func reserveLookup(tx *gocb.TransactionAttemptContext, lookups *gocb.Collection, key, ownerId string, taken error) error {
+	_, err := tx.Insert(lookups, key, lookup{Key: key, OwnerId: ownerId, CreatedAt: time.Now()})
+	if errors.Is(err, gocb.ErrDocumentExists) {
+		return taken
+	}
+
+	return err
+}
+
+// releaseLookup removes key inside tx if ownerId holds it. Lookups that are
+// missing or held by someone else are left alone.
+func releaseLookup(tx *gocb.TransactionAttemptContext, lookups *gocb.Collection, key, ownerId string) error {
+	doc, err := tx.Get(lookups, key)
+	if errors.Is(err, gocb.ErrDocumentNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var held lookup
+	if err := doc.Content(&held); err != nil {
+		return err
+	}
+
+	if held.OwnerId != ownerId {
+		return nil
+	}
+
+	return tx.Remove(doc)
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"kc-bank/domain"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"go.uber.org/zap"
+)
+
+// sentOutboxEventRetention is how long published events are kept for
+// troubleshooting before Couchbase expires them.
+const sentOutboxEventRetention = 7 * 24 * time.Hour
+
+type IOutboxRepository interface {
+	GetPendingEvents(ctx context.Context, now time.Time, limit int) ([]*domain.OutboxEvent, error)
+	ClaimEvent(ctx context.Context, id, owner string, ttl time.Duration) (*domain.OutboxEvent, error)
+	MarkSent(ctx context.Context, id, owner string) error
+	MarkFailedAttempt(ctx context.Context, id, owner string, cause error) error
+}
+
+type outboxRepository struct {
+	cluster *gocb.Cluster
+	bucket  *gocb.Bucket
+}
+
+func NewOutboxRepository(cluster *gocb.Cluster, bucket *gocb.Bucket) IOutboxRepository {
+	return &outboxRepository{
+		cluster: cluster,
+		bucket:  bucket,
+	}
+}
+
+// GetPendingEvents returns pending events no relay holds a lease on at now.
+// They still have to be claimed before they are published.
+func (r *outboxRepository) GetPendingEvents(ctx context.Context, now time.Time, limit int) ([]*domain.OutboxEvent, error) {
+	query := "SELECT o.* FROM `outbox` o WHERE o.Status = $status AND (o.LeaseExpiresAt IS NOT VALUED OR STR_TO_MILLIS(o.LeaseExpiresAt) <= $now) " +
+		"ORDER BY STR_TO_MILLIS(o.CreatedAt) ASC LIMIT $limit"
+
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: map[string]interface{}{"status": domain.OutboxStatusPending, "now": now.UnixMilli(), "limit": limit},
+		Adhoc:           true,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var events []*domain.OutboxEvent
+	for rows.Next() {
+		var event domain.OutboxEvent
+		if err := rows.Row(&event); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// ClaimEvent leases the event to owner for ttl. It fails with
+// domain.ErrOutboxEventLeased or ErrConcurrentUpdate when another relay claimed
+// it first, and with domain.ErrOutboxEventNotPending once it has been sent.
+func (r *outboxRepository) ClaimEvent(ctx context.Context, id, owner string, ttl time.Duration) (*domain.OutboxEvent, error) {
+	return r.updateEvent(ctx, id, 0, func(event *domain.OutboxEvent) error {
+		return event.Claim(owner, time.Now(), ttl)
+	})
+}
+
+// MarkSent records that owner published the event, provided owner still holds
+// its lease. It fails with domain.ErrOutboxLeaseLost or ErrConcurrentUpdate
+// when another relay took the event over, which then decides its state.
+func (r *outboxRepository) MarkSent(ctx context.Context, id, owner string) error {
+	_, err := r.updateEvent(ctx, id, sentOutboxEventRetention, func(event *domain.OutboxEvent) error {
+		if !event.HoldsLease(owner) {
+			return domain.ErrOutboxLeaseLost
+		}
+
+		event.MarkSent(time.Now())
+		return nil
+	})
+
+	return err
+}
+
+// MarkFailedAttempt records that owner failed to publish the event, provided
+// owner still holds its lease.
+func (r *outboxRepository) MarkFailedAttempt(ctx context.Context, id, owner string, cause error) error {
+	_, err := r.updateEvent(ctx, id, 0, func(event *domain.OutboxEvent) error {
+		if !event.HoldsLease(owner) {
+			return domain.ErrOutboxLeaseLost
+		}
+
+		event.RecordFailedAttempt(cause)
+		return nil
+	})
+
+	return err
+}
+
+// updateEvent applies update to the stored event and writes it back against
+// the CAS it was read with; expiry, when set, makes Couchbase drop the event
+// after it.
+func (r *outboxRepository) updateEvent(ctx context.Context, id string, expiry time.Duration, update func(*domain.OutboxEvent) error) (*domain.OutboxEvent, error) {
+	collection := r.bucket.DefaultCollection()
+
+	data, err := collection.Get(id, &gocb.GetOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to get outbox event", zap.String("eventId", id), zap.Error(err))
+		return nil, err
+	}
+
+	var event domain.OutboxEvent
+	if err := data.Content(&event); err != nil {
+		zap.L().Error("Failed to unmarshal outbox event", zap.String("eventId", id), zap.Error(err))
+		return nil, err
+	}
+
+	if err := update(&event); err != nil {
+		return nil, err
+	}
+
+	_, err = collection.Replace(id, &event, &gocb.ReplaceOptions{
+		Cas:     data.Cas(),
+		Expiry:  expiry,
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		if errors.Is(err, gocb.ErrCasMismatch) {
+			return nil, ErrConcurrentUpdate
+		}
+
+		zap.L().Error("Failed to update outbox event", zap.String("eventId", id), zap.Error(err))
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// insertOutboxEvent stages event inside tx, next to the state change it
+// announces.
+func insertOutboxEvent(tx *gocb.TransactionAttemptContext, outbox *gocb.Collection, event *domain.OutboxEvent) error {
+	_, err := tx.Insert(outbox, event.Id, event)
+	return err
+}
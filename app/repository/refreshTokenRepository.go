@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"kc-bank/domain"
+	errorresponse "kc-bank/pkg/error_response"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidRefreshToken = errorresponse.NewUnauthorizedError("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errorresponse.NewUnauthorizedError("refresh token was already used, please log in again")
+)
+
+type IRefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	GetRefreshToken(ctx context.Context, id string) (*domain.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, currentId, nextId string, ttl time.Duration) (*domain.RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string) error
+	RevokeUserRefreshTokens(ctx context.Context, userId string) error
+}
+
+type refreshTokenRepository struct {
+	cluster *gocb.Cluster
+	bucket  *gocb.Bucket
+}
+
+func NewRefreshTokenRepository(cluster *gocb.Cluster, bucket *gocb.Bucket) IRefreshTokenRepository {
+	return &refreshTokenRepository{
+		cluster: cluster,
+		bucket:  bucket,
+	}
+}
+
+func (r *refreshTokenRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	_, err := r.bucket.DefaultCollection().Insert(token.Id, token, &gocb.InsertOptions{
+		Expiry:  time.Until(token.ExpiresAt),
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to create refresh token", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// GetRefreshToken returns ErrInvalidRefreshToken for unknown or expired
+// tokens.
+func (r *refreshTokenRepository) GetRefreshToken(ctx context.Context, id string) (*domain.RefreshToken, error) {
+	token, _, err := r.getRefreshToken(ctx, id)
+	return token, err
+}
+
+// RotateRefreshToken revokes the token stored under currentId and issues its
+// successor under nextId in the same family. Presenting a token that was
+// already rotated or revoked returns it together with ErrRefreshTokenReused,
+// since it has most likely been stolen.
+func (r *refreshTokenRepository) RotateRefreshToken(ctx context.Context, currentId, nextId string, ttl time.Duration) (*domain.RefreshToken, error) {
+	current, cas, err := r.getRefreshToken(ctx, currentId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if current.RevokedAt != nil {
+		return current, ErrRefreshTokenReused
+	}
+
+	if !current.IsActive(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	next := domain.NewRefreshToken(nextId, current.UserId, current.FamilyId, ttl)
+
+	current.RevokedAt = &now
+	current.ReplacedBy = next.Id
+
+	// The CAS makes rotation single-use: of two concurrent refreshes with
+	// the same token only one succeeds, the other is treated as reuse.
+	_, err = r.bucket.DefaultCollection().Replace(current.Id, current, &gocb.ReplaceOptions{
+		Cas:            cas,
+		PreserveExpiry: true,
+		Timeout:        3 * time.Second,
+		Context:        ctx,
+	})
+
+	if err != nil {
+		if errors.Is(err, gocb.ErrCasMismatch) {
+			return current, ErrRefreshTokenReused
+		}
+
+		zap.L().Error("Failed to revoke refresh token", zap.Error(err))
+		return nil, err
+	}
+
+	if err := r.CreateRefreshToken(ctx, next); err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+func (r *refreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	// Setting the expiration to itself keeps the TTL, which UPDATE would
+	// otherwise clear.
+	query := "UPDATE `refresh_tokens` t SET t.RevokedAt = $now, META(t).expiration = META(t).expiration " +
+		"WHERE t.FamilyId = $familyId AND t.RevokedAt IS NULL"
+
+	_, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: map[string]interface{}{"familyId": familyId, "now": time.Now()},
+		ScanConsistency: gocb.QueryScanConsistencyRequestPlus,
+		Adhoc:           true,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to revoke refresh token family", zap.String("familyId", familyId), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens logs userId out of every session, e.g. after a
+// password change.
+func (r *refreshTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userId string) error {
+	query := "UPDATE `refresh_tokens` t SET t.RevokedAt = $now, META(t).expiration = META(t).expiration " +
+		"WHERE t.UserId = $userId AND t.RevokedAt IS NULL"
+
+	_, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: map[string]interface{}{"userId": userId, "now": time.Now()},
+		ScanConsistency: gocb.QueryScanConsistencyRequestPlus,
+		Adhoc:           true,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to revoke user refresh tokens", zap.String("userId", userId), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) getRefreshToken(ctx context.Context, id string) (*domain.RefreshToken, gocb.Cas, error) {
+	data, err := r.bucket.DefaultCollection().Get(id, &gocb.GetOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return nil, 0, ErrInvalidRefreshToken
+		}
+
+		zap.L().Error("Failed to get refresh token", zap.Error(err))
+		return nil, 0, err
+	}
+
+	var token domain.RefreshToken
+	if err := data.Content(&token); err != nil {
+		zap.L().Error("Failed to unmarshal refresh token", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return &token, data.Cas(), nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"kc-bank/domain"
+	errorresponse "kc-bank/pkg/error_response"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"go.uber.org/zap"
+)
+
+var ErrScheduledTransferNotFound = errorresponse.NewNotFoundError("scheduled transfer not found")
+
+type IScheduledTransferRepository interface {
+	CreateScheduledTransfer(ctx context.Context, scheduled *domain.ScheduledTransfer) error
+	GetScheduledTransfer(ctx context.Context, id string) (*domain.ScheduledTransfer, error)
+	GetScheduledTransfersByUser(ctx context.Context, userId string) ([]*domain.ScheduledTransfer, error)
+	GetDueScheduledTransfers(ctx context.Context, now time.Time, limit int) ([]*domain.ScheduledTransfer, error)
+	UpdateScheduledTransfer(ctx context.Context, id string, update func(*domain.ScheduledTransfer) error) (*domain.ScheduledTransfer, error)
+}
+
+type scheduledTransferRepository struct {
+	cluster *gocb.Cluster
+	bucket  *gocb.Bucket
+}
+
+func NewScheduledTransferRepository(cluster *gocb.Cluster, bucket *gocb.Bucket) IScheduledTransferRepository {
+	return &scheduledTransferRepository{
+		cluster: cluster,
+		bucket:  bucket,
+	}
+}
+
+func (r *scheduledTransferRepository) CreateScheduledTransfer(ctx context.Context, scheduled *domain.ScheduledTransfer) error {
+	_, err := r.bucket.DefaultCollection().Insert(scheduled.Id, scheduled, &gocb.InsertOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to create scheduled transfer", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *scheduledTransferRepository) GetScheduledTransfer(ctx context.Context, id string) (*domain.ScheduledTransfer, error) {
+	scheduled, _, err := r.getScheduledTransfer(ctx, id)
+	return scheduled, err
+}
+
+func (r *scheduledTransferRepository) GetScheduledTransfersByUser(ctx context.Context, userId string) ([]*domain.ScheduledTransfer, error) {
+	query := "SELECT META(s).id, s.* FROM `scheduled_transfers` s WHERE s.UserId = $userId ORDER BY STR_TO_MILLIS(s.ExecuteAt) ASC"
+
+	return r.queryScheduledTransfers(ctx, query, map[string]interface{}{"userId": userId})
+}
+
+// GetDueScheduledTransfers returns up to limit scheduled transfers due by now
+// that no worker holds a lease on, the longest due first.
+func (r *scheduledTransferRepository) GetDueScheduledTransfers(ctx context.Context, now time.Time, limit int) ([]*domain.ScheduledTransfer, error) {
+	query := "SELECT META(s).id, s.* FROM `scheduled_transfers` s WHERE s.Status = $status AND STR_TO_MILLIS(s.ExecuteAt) <= $now " +
+		"AND (s.LeaseExpiresAt IS NOT VALUED OR STR_TO_MILLIS(s.LeaseExpiresAt) <= $now) ORDER BY STR_TO_MILLIS(s.ExecuteAt) ASC LIMIT $limit"
+
+	return r.queryScheduledTransfers(ctx, query, map[string]interface{}{
+		"status": domain.ScheduledTransferStatusScheduled,
+		"now":    now.UnixMilli(),
+		"limit":  limit,
+	})
+}
+
+// UpdateScheduledTransfer applies update to the stored scheduled transfer and
+// saves it. The CAS makes the read and the write one step: of two workers
+// updating the same transfer, e.g. both taking its lease, only one succeeds
+// and the other gets ErrConcurrentUpdate. Errors from update are returned
+// unchanged and nothing is saved.
+func (r *scheduledTransferRepository) UpdateScheduledTransfer(ctx context.Context, id string, update func(*domain.ScheduledTransfer) error) (*domain.ScheduledTransfer, error) {
+	scheduled, cas, err := r.getScheduledTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := update(scheduled); err != nil {
+		return nil, err
+	}
+
+	_, err = r.bucket.DefaultCollection().Replace(scheduled.Id, scheduled, &gocb.ReplaceOptions{
+		Cas:     cas,
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		if errors.Is(err, gocb.ErrCasMismatch) {
+			return nil, ErrConcurrentUpdate
+		}
+
+		zap.L().Error("Failed to update scheduled transfer", zap.String("scheduledTransferId", id), zap.Error(err))
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+func (r *scheduledTransferRepository) getScheduledTransfer(ctx context.Context, id string) (*domain.ScheduledTransfer, gocb.Cas, error) {
+	data, err := r.bucket.DefaultCollection().Get(id, &gocb.GetOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return nil, 0, ErrScheduledTransferNotFound
+		}
+
+		zap.L().Error("Failed to get scheduled transfer", zap.Error(err))
+		return nil, 0, err
+	}
+
+	var scheduled domain.ScheduledTransfer
+	if err := data.Content(&scheduled); err != nil {
+		zap.L().Error("Failed to unmarshal scheduled transfer", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return &scheduled, data.Cas(), nil
+}
+
+func (r *scheduledTransferRepository) queryScheduledTransfers(ctx context.Context, query string, params map[string]interface{}) ([]*domain.ScheduledTransfer, error) {
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: params,
+		Adhoc:           true,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var scheduled []*domain.ScheduledTransfer
+	for rows.Next() {
+		var transfer domain.ScheduledTransfer
+		if err := rows.Row(&transfer); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		scheduled = append(scheduled, &transfer)
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return nil, err
+	}
+
+	return scheduled, nil
+}
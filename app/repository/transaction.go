@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	errorresponse "kc-bank/pkg/error_response"
+)
+
+const defaultTransactionTimeout = 3 * time.Second
+
+// maxTransactionAttempts bounds how many times a transaction is retried after
+// losing a CAS race against a concurrent writer.
+const maxTransactionAttempts = 5
+
+var ErrConcurrentUpdate = errorresponse.NewConflictError("account was modified concurrently, please retry")
+
+// runTransaction runs fn as a single Couchbase transaction. Every document
+// written through tx is checked against the CAS it was read with; when a
+// concurrent writer wins, fn is re-run on fresh snapshots until
+// maxTransactionAttempts is reached. The error returned by fn is passed back
+// unwrapped, so callers can match it with errors.Is.
+func runTransaction(ctx context.Context, cluster *gocb.Cluster, fn func(tx *gocb.TransactionAttemptContext) error) error {
+	options, err := transactionOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	attempts := 0
+
+	_, err = cluster.Transactions().Run(func(tx *gocb.TransactionAttemptContext) error {
+		attempts++
+		if attempts > maxTransactionAttempts {
+			return ErrConcurrentUpdate
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		return fn(tx)
+	}, options)
+
+	if err == nil {
+		return nil
+	}
+
+	if isConcurrentUpdate(err, attempts) {
+		return ErrConcurrentUpdate
+	}
+
+	var failed *gocb.TransactionFailedError
+	if errors.As(err, &failed) && failed.Unwrap() != nil {
+		return failed.Unwrap()
+	}
+
+	return err
+}
+
+// transactionOptions bounds a transaction by the request deadline, since the
+// transactions API does not accept a context of its own. A context that is
+// already done is refused: a zero timeout would fall back to the cluster
+// default instead of failing.
+func transactionOptions(ctx context.Context) (*gocb.TransactionOptions, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	timeout := defaultTransactionTimeout
+
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	if timeout <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+
+	return &gocb.TransactionOptions{
+		Timeout: timeout,
+	}, nil
+}
+
+// isConcurrentUpdate reports whether a failed transaction was caused by
+// conflicting writers rather than by the operation itself.
+func isConcurrentUpdate(err error, attempts int) bool {
+	if errors.Is(err, ErrConcurrentUpdate) ||
+		errors.Is(err, gocb.ErrWriteWriteConflict) ||
+		errors.Is(err, gocb.ErrCasMismatch) {
+		return true
+	}
+
+	// A transaction that had to be retried and then ran out of time was
+	// spinning on conflicts.
+	var expired *gocb.TransactionExpiredError
+	return errors.As(err, &expired) && attempts > 1
+}
+
+// IsTransientError reports whether err is an infrastructure failure that may
+// succeed if the operation is retried later, as opposed to a rejection of the
+// operation itself.
+func IsTransientError(err error) bool {
+	return errors.Is(err, ErrConcurrentUpdate) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, gocb.ErrTimeout) ||
+		errors.Is(err, gocb.ErrTemporaryFailure) ||
+		errors.Is(err, gocb.ErrServiceNotAvailable) ||
+		errors.Is(err, gocb.ErrOverload) ||
+		errors.Is(err, gocb.ErrRequestCanceled) ||
+		errors.Is(err, gocb.ErrAmbiguousTimeout) ||
+		errors.Is(err, gocb.ErrUnambiguousTimeout) ||
+		errors.Is(err, gocb.ErrDocumentLocked)
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransactionOptions(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	bounded, cancelBounded := context.WithTimeout(context.Background(), time.Second)
+	defer cancelBounded()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		wantErr     error
+		wantTimeout time.Duration
+	}{
+		{name: "no deadline", ctx: context.Background(), wantTimeout: defaultTransactionTimeout},
+		{name: "deadline ahead", ctx: bounded, wantTimeout: time.Second},
+		{name: "deadline passed", ctx: expired, wantErr: context.DeadlineExceeded},
+		{name: "canceled", ctx: canceled, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, err := transactionOptions(tt.ctx)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("transactionOptions() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("transactionOptions() error = %v", err)
+			}
+
+			if options.Timeout <= 0 || options.Timeout > tt.wantTimeout {
+				t.Errorf("transactionOptions() timeout = %v, want in (0, %v]", options.Timeout, tt.wantTimeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"kc-bank/domain"
+	errorresponse "kc-bank/pkg/error_response"
+	"strings"
+	"time"
+
+	"github.com/couchbase/gocb/v2"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrTransferNotFound         = errorresponse.NewNotFoundError("transfer not found")
+	ErrTransferAlreadyCompleted = errorresponse.NewConflictError("transfer has already been executed")
+)
+
+// TransferFilter narrows the transfers of one account. Zero values mean "no
+// constraint". Results are ordered newest first; After continues a previous
+// page from the last transfer it returned.
+type TransferFilter struct {
+	AccountId string
+	From      *time.Time
+	To        *time.Time
+	MinAmount *int64
+	MaxAmount *int64
+	Direction domain.TransferDirection
+	After     *TransferCursor
+	Limit     int
+}
+
+type TransferCursor struct {
+	CreatedAtMillis int64
+	Id              string
+}
+
+type ITransferRepository interface {
+	FailTransfer(ctx context.Context, transfer *domain.Transfer, reason string) error
+	GetTransfer(ctx context.Context, id string) (*domain.Transfer, error)
+	GetTransfersByAccount(ctx context.Context, filter TransferFilter) ([]*domain.Transfer, error)
+	CreateTransferWithEvent(ctx context.Context, transfer *domain.Transfer, event *domain.OutboxEvent) error
+}
+
+type transferRepository struct {
+	cluster      *gocb.Cluster
+	bucket       *gocb.Bucket
+	outboxBucket *gocb.Bucket
+}
+
+func NewTransferRepository(cluster *gocb.Cluster, bucket *gocb.Bucket, outboxBucket *gocb.Bucket) ITransferRepository {
+	return &transferRepository{
+		cluster:      cluster,
+		bucket:       bucket,
+		outboxBucket: outboxBucket,
+	}
+}
+
+// FailTransfer records transfer as FAILED with reason. Only a transfer that is
+// not stored yet or still PENDING is changed: a transfer another attempt has
+// completed in the meantime is left alone and ErrTransferAlreadyCompleted
+// returned, and one already FAILED is kept as it is.
+func (r *transferRepository) FailTransfer(ctx context.Context, transfer *domain.Transfer, reason string) error {
+	collection := r.bucket.DefaultCollection()
+
+	for attempt := 0; attempt < 3; attempt++ {
+		doc, err := collection.Get(transfer.Id, &gocb.GetOptions{
+			Timeout: 3 * time.Second,
+			Context: ctx,
+		})
+
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			transfer.Fail(reason)
+
+			_, err = collection.Insert(transfer.Id, transfer, &gocb.InsertOptions{
+				Timeout: 3 * time.Second,
+				Context: ctx,
+			})
+
+			if errors.Is(err, gocb.ErrDocumentExists) {
+				continue
+			}
+
+			return err
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var stored domain.Transfer
+		if err := doc.Content(&stored); err != nil {
+			return err
+		}
+
+		switch stored.Status {
+		case domain.TransferStatusCompleted:
+			return ErrTransferAlreadyCompleted
+		case domain.TransferStatusFailed:
+			return nil
+		}
+
+		transfer.Fail(reason)
+
+		_, err = collection.Replace(transfer.Id, transfer, &gocb.ReplaceOptions{
+			Cas:     doc.Cas(),
+			Timeout: 3 * time.Second,
+			Context: ctx,
+		})
+
+		if errors.Is(err, gocb.ErrCasMismatch) {
+			continue
+		}
+
+		return err
+	}
+
+	return ErrConcurrentUpdate
+}
+
+// CreateTransferWithEvent stores a new transfer together with the outbox event
+// announcing it. Either both are written or neither is.
+func (r *transferRepository) CreateTransferWithEvent(ctx context.Context, transfer *domain.Transfer, event *domain.OutboxEvent) error {
+	transfers := r.bucket.DefaultCollection()
+	outbox := r.outboxBucket.DefaultCollection()
+
+	err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		if _, err := tx.Insert(transfers, transfer.Id, transfer); err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(tx, outbox, event)
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to create transfer", zap.String("transferId", transfer.Id), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *transferRepository) GetTransfer(ctx context.Context, id string) (*domain.Transfer, error) {
+	data, err := r.bucket.DefaultCollection().Get(id, &gocb.GetOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return nil, ErrTransferNotFound
+		}
+
+		zap.L().Error("Failed to get transfer", zap.Error(err))
+		return nil, err
+	}
+
+	var transfer domain.Transfer
+	if err := data.Content(&transfer); err != nil {
+		zap.L().Error("Failed to unmarshal transfer", zap.Error(err))
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+// transferAccountAmount is what a transfer moved on the queried account, in
+// its currency: the sent amount for outgoing transfers and the converted one
+// for incoming transfers that crossed currencies.
+const transferAccountAmount = "(CASE WHEN t.ToAccountId = $accountId AND t.ConvertedAmount IS VALUED THEN t.ConvertedAmount.Minor ELSE t.Amount.Minor END)"
+
+func (r *transferRepository) GetTransfersByAccount(ctx context.Context, filter TransferFilter) ([]*domain.Transfer, error) {
+	// CreatedAt is stored as RFC 3339 text with a variable-length fraction,
+	// which does not sort lexically; compare and order on epoch millis.
+	conditions := []string{"(t.FromAccountId = $accountId OR t.ToAccountId = $accountId)"}
+	params := map[string]interface{}{
+		"accountId": filter.AccountId,
+		"limit":     filter.Limit,
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "STR_TO_MILLIS(t.CreatedAt) >= $from")
+		params["from"] = filter.From.UnixMilli()
+	}
+
+	if filter.To != nil {
+		conditions = append(conditions, "STR_TO_MILLIS(t.CreatedAt) <= $to")
+		params["to"] = filter.To.UnixMilli()
+	}
+
+	if filter.MinAmount != nil {
+		conditions = append(conditions, transferAccountAmount+" >= $minAmount")
+		params["minAmount"] = *filter.MinAmount
+	}
+
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, transferAccountAmount+" <= $maxAmount")
+		params["maxAmount"] = *filter.MaxAmount
+	}
+
+	switch filter.Direction {
+	case domain.TransferDirectionIn:
+		conditions = append(conditions, "t.ToAccountId = $accountId")
+	case domain.TransferDirectionOut:
+		conditions = append(conditions, "t.FromAccountId = $accountId")
+	}
+
+	if filter.After != nil {
+		conditions = append(conditions, "(STR_TO_MILLIS(t.CreatedAt) < $cursorCreatedAt OR (STR_TO_MILLIS(t.CreatedAt) = $cursorCreatedAt AND t.Id < $cursorId))")
+		params["cursorCreatedAt"] = filter.After.CreatedAtMillis
+		params["cursorId"] = filter.After.Id
+	}
+
+	query := "SELECT t.* FROM `transfers` t WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY STR_TO_MILLIS(t.CreatedAt) DESC, t.Id DESC LIMIT $limit"
+
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: params,
+		Adhoc:           true,
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var transfers []*domain.Transfer
+	for rows.Next() {
+		var transfer domain.Transfer
+		if err := rows.Row(&transfer); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		transfers = append(transfers, &transfer)
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// saveTransfer writes transfer inside tx, inserting it or replacing the
+// version that is already stored.
+func saveTransfer(tx *gocb.TransactionAttemptContext, transfers *gocb.Collection, transfer *domain.Transfer) error {
+	doc, err := tx.Get(transfers, transfer.Id)
+	if err != nil {
+		if !errors.Is(err, gocb.ErrDocumentNotFound) {
+			return err
+		}
+
+		_, err = tx.Insert(transfers, transfer.Id, transfer)
+		return err
+	}
+
+	_, err = tx.Replace(doc, transfer)
+	return err
+}
+
+func checkTransferNotCompleted(tx *gocb.TransactionAttemptContext, transfers *gocb.Collection, transferId string) error {
+	doc, err := tx.Get(transfers, transferId)
+	if errors.Is(err, gocb.ErrDocumentNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var existing domain.Transfer
+	if err := doc.Content(&existing); err != nil {
+		return err
+	}
+
+	if existing.Status == domain.TransferStatusCompleted {
+		return ErrTransferAlreadyCompleted
+	}
+
+	return nil
+}
@@ -4,34 +4,77 @@ import (
 	"context"
 	"errors"
 	"kc-bank/domain"
+	errorresponse "kc-bank/pkg/error_response"
+	"kc-bank/pkg/services"
+	"net/http"
 	"time"
 
 	"github.com/couchbase/gocb/v2"
 	"go.uber.org/zap"
 )
 
+var (
+	ErrUserNotFound = errorresponse.NewNotFoundError("user not found")
+	ErrEmailTaken   = errorresponse.NewFieldError(http.StatusConflict, "email", "email is already registered")
+
+	ErrNationalIdTaken = errorresponse.NewFieldError(http.StatusConflict, "nationalId", "national id is already registered")
+
+	ErrUserHoldsFunds = errorresponse.NewConflictError("user still holds accounts with a non-zero balance or funds on hold")
+)
+
 type IUserRepository interface {
 	CreateUser(ctx context.Context, user *domain.User) error
+	UpdateUser(ctx context.Context, user *domain.User, audit *domain.AuditRecord) error
+	CloseUser(ctx context.Context, user *domain.User, accountIds []string, audit *domain.AuditRecord) error
 	GetUser(ctx context.Context, id string) (*domain.User, error)
 	GetAllUsers(ctx context.Context) ([]*domain.User, error)
+	FindByEmail(ctx context.Context, canonicalEmail string) (*domain.User, error)
+	BackfillEmailLookups(ctx context.Context, canonicalize func(email string) string) (int, error)
 }
 
 type userRepository struct {
-	cluster *gocb.Cluster
-	bucket  *gocb.Bucket
+	cluster       *gocb.Cluster
+	bucket        *gocb.Bucket
+	accountBucket *gocb.Bucket
+	lookupBucket  *gocb.Bucket
+	auditBucket   *gocb.Bucket
+	fieldCipher   services.IFieldCipher
 }
 
-func NewUserRepository(cluster *gocb.Cluster, bucket *gocb.Bucket) IUserRepository {
+func NewUserRepository(cluster *gocb.Cluster, bucket, accountBucket, lookupBucket, auditBucket *gocb.Bucket, fieldCipher services.IFieldCipher) IUserRepository {
 	return &userRepository{
-		cluster: cluster,
-		bucket:  bucket,
+		cluster:       cluster,
+		bucket:        bucket,
+		accountBucket: accountBucket,
+		lookupBucket:  lookupBucket,
+		auditBucket:   auditBucket,
+		fieldCipher:   fieldCipher,
 	}
 }
 
+// CreateUser stores user and reserves its canonical email and national id in
+// one transaction, so two registrations of the same person can not both
+// succeed.
 func (r *userRepository) CreateUser(ctx context.Context, user *domain.User) error {
-	_, err := r.bucket.DefaultCollection().Insert(user.Id, user, &gocb.InsertOptions{
-		Timeout: 3 * time.Second,
-		Context: ctx,
+	if err := r.sealNationalId(user); err != nil {
+		return err
+	}
+
+	err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		if err := reserveLookup(tx, r.lookupBucket.DefaultCollection(), emailLookupKey(user.CanonicalEmail), user.Id, ErrEmailTaken); err != nil {
+			return err
+		}
+
+		if user.NationalId != "" {
+			key := nationalIdLookupKey(r.fieldCipher.BlindIndex(user.NationalId))
+
+			if err := reserveLookup(tx, r.lookupBucket.DefaultCollection(), key, user.Id, ErrNationalIdTaken); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Insert(r.bucket.DefaultCollection(), user.Id, user)
+		return err
 	})
 
 	if err != nil {
@@ -42,11 +85,16 @@ func (r *userRepository) CreateUser(ctx context.Context, user *domain.User) erro
 	return nil
 }
 
-func (r *userRepository) UpdateUser(ctx context.Context, user *domain.User) error {
+// UpdateUser replaces user and stores audit with it in one transaction. A
+// changed canonical email or national id is reserved and the previous one
+// released, so the old value can be registered again.
+func (r *userRepository) UpdateUser(ctx context.Context, user *domain.User, audit *domain.AuditRecord) error {
+	if err := r.sealNationalId(user); err != nil {
+		return err
+	}
 
-	_, err := r.bucket.DefaultCollection().Replace(user.Id, user, &gocb.ReplaceOptions{
-		Timeout: 3 * time.Second,
-		Context: ctx,
+	err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		return r.updateUserInTx(tx, user, audit)
 	})
 
 	if err != nil {
@@ -57,6 +105,136 @@ func (r *userRepository) UpdateUser(ctx context.Context, user *domain.User) erro
 	return nil
 }
 
+// CloseUser stores user, moved to a closed status, in the same transaction
+// that checks none of accountIds holds money or holds. Each account is written
+// back unchanged, so a transfer racing the close conflicts with it instead of
+// landing after the check; opening an account writes the user document, so it
+// conflicts the same way. A deleted user gives up their email and national id,
+// so the same person can register again.
+func (r *userRepository) CloseUser(ctx context.Context, user *domain.User, accountIds []string, audit *domain.AuditRecord) error {
+	if err := r.sealNationalId(user); err != nil {
+		return err
+	}
+
+	err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+		for _, accountId := range accountIds {
+			doc, account, err := getAccountInTx(tx, r.accountBucket.DefaultCollection(), accountId)
+			if err != nil {
+				return err
+			}
+
+			if !account.Balance.IsZero() || account.HasHolds() {
+				return ErrUserHoldsFunds
+			}
+
+			if _, err := tx.Replace(doc, account); err != nil {
+				return err
+			}
+		}
+
+		if err := r.updateUserInTx(tx, user, audit); err != nil {
+			return err
+		}
+
+		if user.Status == domain.UserStatusDeleted {
+			return r.releaseIdentityLookups(tx, user)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to close user", zap.String("userId", user.Id), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// releaseIdentityLookups frees the email and national id user holds.
+func (r *userRepository) releaseIdentityLookups(tx *gocb.TransactionAttemptContext, user *domain.User) error {
+	lookups := r.lookupBucket.DefaultCollection()
+
+	if err := releaseLookup(tx, lookups, emailLookupKey(user.CanonicalEmail), user.Id); err != nil {
+		return err
+	}
+
+	if user.NationalId == "" {
+		return nil
+	}
+
+	return releaseLookup(tx, lookups, nationalIdLookupKey(r.fieldCipher.BlindIndex(user.NationalId)), user.Id)
+}
+
+// updateUserInTx replaces the stored user, moving its email and national id
+// lookups along when they changed, and stores audit when it records any
+// change.
+func (r *userRepository) updateUserInTx(tx *gocb.TransactionAttemptContext, user *domain.User, audit *domain.AuditRecord) error {
+	doc, err := tx.Get(r.bucket.DefaultCollection(), user.Id)
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	var current domain.User
+	if err := doc.Content(&current); err != nil {
+		return err
+	}
+
+	if user.CanonicalEmail != current.CanonicalEmail {
+		if err := reserveLookup(tx, r.lookupBucket.DefaultCollection(), emailLookupKey(user.CanonicalEmail), user.Id, ErrEmailTaken); err != nil {
+			return err
+		}
+
+		if err := releaseLookup(tx, r.lookupBucket.DefaultCollection(), emailLookupKey(current.CanonicalEmail), user.Id); err != nil {
+			return err
+		}
+	}
+
+	if err := r.moveNationalIdLookup(tx, &current, user); err != nil {
+		return err
+	}
+
+	if _, err := tx.Replace(doc, user); err != nil {
+		return err
+	}
+
+	if audit != nil && audit.HasChanges() {
+		_, err = tx.Insert(r.auditBucket.DefaultCollection(), audit.Id, audit)
+	}
+
+	return err
+}
+
+// moveNationalIdLookup reserves the national id of user and releases the one
+// stored on current when they differ. The stored value is decrypted to compare
+// them, since encryption is randomised.
+func (r *userRepository) moveNationalIdLookup(tx *gocb.TransactionAttemptContext, current, user *domain.User) error {
+	if err := r.openNationalId(current); err != nil {
+		return err
+	}
+
+	if user.NationalId == current.NationalId {
+		return nil
+	}
+
+	lookups := r.lookupBucket.DefaultCollection()
+
+	if user.NationalId != "" {
+		if err := reserveLookup(tx, lookups, nationalIdLookupKey(r.fieldCipher.BlindIndex(user.NationalId)), user.Id, ErrNationalIdTaken); err != nil {
+			return err
+		}
+	}
+
+	if current.NationalId != "" {
+		return releaseLookup(tx, lookups, nationalIdLookupKey(r.fieldCipher.BlindIndex(current.NationalId)), user.Id)
+	}
+
+	return nil
+}
+
 func (r *userRepository) GetUser(ctx context.Context, id string) (*domain.User, error) {
 	data, err := r.bucket.DefaultCollection().Get(id, &gocb.GetOptions{
 		Timeout: 3 * time.Second,
@@ -65,7 +243,7 @@ func (r *userRepository) GetUser(ctx context.Context, id string) (*domain.User,
 
 	if err != nil {
 		if errors.Is(err, gocb.ErrDocumentNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 
 		zap.L().Error("Failed to get user", zap.Error(err))
@@ -78,14 +256,19 @@ func (r *userRepository) GetUser(ctx context.Context, id string) (*domain.User,
 		return nil, err
 	}
 
+	if err := r.openNationalId(&user); err != nil {
+		return nil, err
+	}
+
 	return &user, nil
 }
 
 func (r *userRepository) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
-	query := "SELECT META(u).id, u.* FROM `users` u ORDER BY u.CreatedAt DESC"
+	query := "SELECT META(u).id, u.* FROM `users` u WHERE u.Status IS NOT VALUED OR u.Status != $deleted ORDER BY u.CreatedAt DESC"
 
 	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
-		Context: ctx,
+		Context:         ctx,
+		NamedParameters: map[string]interface{}{"deleted": domain.UserStatusDeleted},
 	})
 
 	if err != nil {
@@ -102,6 +285,11 @@ func (r *userRepository) GetAllUsers(ctx context.Context) ([]*domain.User, error
 			zap.L().Error("Failed to scan row", zap.Error(err))
 			return nil, err
 		}
+
+		if err := r.openNationalId(&user); err != nil {
+			return nil, err
+		}
+
 		users = append(users, &user)
 	}
 
@@ -112,3 +300,153 @@ func (r *userRepository) GetAllUsers(ctx context.Context) ([]*domain.User, error
 
 	return users, nil
 }
+
+// FindByEmail returns nil without an error when no user, or only a deleted
+// one, has the email.
+func (r *userRepository) FindByEmail(ctx context.Context, canonicalEmail string) (*domain.User, error) {
+	data, err := r.lookupBucket.DefaultCollection().Get(emailLookupKey(canonicalEmail), &gocb.GetOptions{
+		Timeout: 3 * time.Second,
+		Context: ctx,
+	})
+
+	if err != nil {
+		if errors.Is(err, gocb.ErrDocumentNotFound) {
+			return nil, nil
+		}
+
+		zap.L().Error("Failed to get email lookup", zap.Error(err))
+		return nil, err
+	}
+
+	var emailLookup lookup
+	if err := data.Content(&emailLookup); err != nil {
+		zap.L().Error("Failed to unmarshal email lookup", zap.Error(err))
+		return nil, err
+	}
+
+	user, err := r.GetUser(ctx, emailLookup.OwnerId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users deleted before deletion released their email still hold it.
+	if user.Status == domain.UserStatusDeleted {
+		return nil, nil
+	}
+
+	return user, nil
+}
+
+// BackfillEmailLookups reserves the emails of users registered before emails
+// were reserved and stores their canonical email. Users sharing an email keep
+// working, but only the first one found can log in; the others are logged.
+func (r *userRepository) BackfillEmailLookups(ctx context.Context, canonicalize func(email string) string) (int, error) {
+	query := "SELECT META(u).id AS Id, u.Email FROM `users` u WHERE (u.CanonicalEmail IS NOT VALUED OR u.CanonicalEmail = '') " +
+		"AND (u.Status IS NOT VALUED OR u.Status != $deleted)"
+
+	rows, err := r.cluster.Query(query, &gocb.QueryOptions{
+		Context:         ctx,
+		NamedParameters: map[string]interface{}{"deleted": domain.UserStatusDeleted},
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to execute query", zap.Error(err))
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	type legacyUser struct {
+		Id    string
+		Email string
+	}
+
+	var legacyUsers []legacyUser
+	for rows.Next() {
+		var user legacyUser
+		if err := rows.Row(&user); err != nil {
+			zap.L().Error("Failed to scan row", zap.Error(err))
+			return 0, err
+		}
+		legacyUsers = append(legacyUsers, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		zap.L().Error("Error iterating rows", zap.Error(err))
+		return 0, err
+	}
+
+	backfilled := 0
+
+	for _, legacy := range legacyUsers {
+		canonicalEmail := canonicalize(legacy.Email)
+
+		err := runTransaction(ctx, r.cluster, func(tx *gocb.TransactionAttemptContext) error {
+			if err := reserveLookup(tx, r.lookupBucket.DefaultCollection(), emailLookupKey(canonicalEmail), legacy.Id, ErrEmailTaken); err != nil {
+				return err
+			}
+
+			doc, err := tx.Get(r.bucket.DefaultCollection(), legacy.Id)
+			if err != nil {
+				return err
+			}
+
+			var user domain.User
+			if err := doc.Content(&user); err != nil {
+				return err
+			}
+
+			user.CanonicalEmail = canonicalEmail
+
+			_, err = tx.Replace(doc, user)
+			return err
+		})
+
+		if errors.Is(err, ErrEmailTaken) {
+			zap.L().Warn("Email is shared by more than one user", zap.String("userId", legacy.Id))
+			continue
+		}
+
+		if err != nil {
+			return backfilled, err
+		}
+
+		backfilled++
+	}
+
+	return backfilled, nil
+}
+
+// sealNationalId encrypts the national id of user for storage.
+func (r *userRepository) sealNationalId(user *domain.User) error {
+	if user.NationalId == "" {
+		return nil
+	}
+
+	encrypted, err := r.fieldCipher.Encrypt(user.NationalId)
+	if err != nil {
+		zap.L().Error("Failed to encrypt national id", zap.String("userId", user.Id), zap.Error(err))
+		return err
+	}
+
+	user.EncryptedNationalId = encrypted
+
+	return nil
+}
+
+// openNationalId decrypts the stored national id of user.
+func (r *userRepository) openNationalId(user *domain.User) error {
+	if user.EncryptedNationalId == "" {
+		return nil
+	}
+
+	nationalId, err := r.fieldCipher.Decrypt(user.EncryptedNationalId)
+	if err != nil {
+		zap.L().Error("Failed to decrypt national id", zap.String("userId", user.Id), zap.Error(err))
+		return err
+	}
+
+	user.NationalId = nationalId
+
+	return nil
+}
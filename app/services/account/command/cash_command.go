@@ -0,0 +1,13 @@
+package command
+
+import "kc-bank/domain"
+
+// CashCommand pays cash into or out of an account.
+type CashCommand struct {
+	AccountId string
+	// Amount is the exact decimal text sent by the client, in the account's
+	// currency.
+	Amount    string
+	Channel   domain.CashChannel
+	Reference string
+}
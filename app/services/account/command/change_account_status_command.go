@@ -0,0 +1,9 @@
+package command
+
+type ChangeAccountStatusCommand struct {
+	AccountId string
+	Status    string
+	Reason    string
+	// SweepToIban receives the remaining balance when the account is closed.
+	SweepToIban string
+}
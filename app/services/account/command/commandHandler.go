@@ -4,138 +4,471 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"kc-bank/app/repository"
 	"kc-bank/domain"
 	"kc-bank/infra/rabbitmq"
+	"kc-bank/pkg/auth"
+	errorresponse "kc-bank/pkg/error_response"
 	"kc-bank/pkg/services"
 	"log"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+const maxIbanAllocationAttempts = 5
+
 type ICommandHandler interface {
 	Save(ctx context.Context, command Command) error
 	TransferMoney(ctx context.Context, command TransferMoneyCommand) error
-	validateTransferMoney(ctx context.Context, command TransferMoneyCommand) (string, string, error)
-	TransferMoneyWithRabbitMQPublisher(ctx context.Context, command TransferMoneyCommand) error
+	ExecuteTransfer(ctx context.Context, command TransferMoneyCommand) (*domain.Transfer, error)
+	validateTransferMoney(ctx context.Context, command TransferMoneyCommand) (*domain.Transfer, error)
+	TransferMoneyWithRabbitMQPublisher(ctx context.Context, command TransferMoneyCommand) (*domain.Transfer, error)
 	TransferMoneyWithRabbitMQConsumer()
+	GetDeadLetterTransfers(ctx context.Context, limit int) ([]rabbitmq.DeadLetter, error)
+	ReplayDeadLetterTransfers(ctx context.Context, limit int) (int, error)
+	ChangeAccountStatus(ctx context.Context, command ChangeAccountStatusCommand) (*domain.Account, error)
+	Deposit(ctx context.Context, command CashCommand) (*domain.Transfer, error)
+	Withdraw(ctx context.Context, command CashCommand) (*domain.Transfer, error)
+	SetOverdraftLimit(ctx context.Context, command SetOverdraftLimitCommand) (*domain.Account, error)
+}
+
+// AccountPolicy decides who may open accounts and how many.
+type AccountPolicy struct {
+	MinimumOwnerAge        int32
+	MaxAccountsPerCurrency int
 }
 
 type commandHandler struct {
-	accountRepository repository.IAccountRepository
-	ibanService       services.IIbanService
-	rmqService        rabbitmq.IRabbitMQService
-	exchangeName      string
+	accountRepository  repository.IAccountRepository
+	transferRepository repository.ITransferRepository
+	userRepository     repository.IUserRepository
+	accountPolicy      AccountPolicy
+	ibanService        services.IIbanService
+	fxQuoteRepository  repository.IFxQuoteRepository
+	fxPricer           services.IFxPricer
+	rmqService         rabbitmq.IRabbitMQService
+	exchangeName       string
 }
 
 func NewCommandHandler(
 	accountRepository repository.IAccountRepository,
+	transferRepository repository.ITransferRepository,
+	userRepository repository.IUserRepository,
+	accountPolicy AccountPolicy,
 	ibanService services.IIbanService,
+	fxQuoteRepository repository.IFxQuoteRepository,
+	fxPricer services.IFxPricer,
 	rmqService rabbitmq.IRabbitMQService,
 	exchangeName string,
 ) ICommandHandler {
 	return &commandHandler{
-		accountRepository: accountRepository,
-		ibanService:       ibanService,
-		rmqService:        rmqService,
-		exchangeName:      exchangeName,
+		accountRepository:  accountRepository,
+		transferRepository: transferRepository,
+		userRepository:     userRepository,
+		accountPolicy:      accountPolicy,
+		ibanService:        ibanService,
+		fxQuoteRepository:  fxQuoteRepository,
+		fxPricer:           fxPricer,
+		rmqService:         rmqService,
+		exchangeName:       exchangeName,
 	}
 }
 
+var (
+	ErrFromIbanNotFound  = errorresponse.NewNotFoundError("from iban does not exist")
+	ErrToIbanNotFound    = errorresponse.NewNotFoundError("to iban does not exist")
+	ErrOwnerAgeUnknown   = errorresponse.NewUnprocessableEntityError("user has no verified birth year; verify their identity before opening an account")
+	ErrSweepIbanNotFound = errorresponse.NewFieldError(http.StatusNotFound, "sweepToIban", "sweep iban does not exist")
+)
+
 func (c *commandHandler) Save(ctx context.Context, command Command) error {
-	// TODO: check user id for existence
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return err
+	}
 
-	iban := c.ibanService.GenerateIBAN("TR", 5, 16)
+	// Accounts are opened by their owner.
+	if command.UserId == "" {
+		command.UserId = principal.UserId
+	} else if command.UserId != principal.UserId {
+		return errorresponse.NewForbiddenError("accounts can only be opened for yourself")
+	}
+
+	if _, err := domain.CurrencyScale(command.Currency); err != nil {
+		return errorresponse.NewBadRequestError(err.Error())
+	}
 
-	newAccount := c.BuildEntity(command, iban)
+	if err := c.validateAccountOwner(ctx, command.UserId); err != nil {
+		return err
+	}
+
+	// A freshly generated IBAN can collide with an issued one, possibly
+	// issued by another instance at the same moment; the repository detects
+	// that and we try another.
+	for attempt := 1; ; attempt++ {
+		iban, err := c.ibanService.GenerateIBAN("TR")
+
+		if err != nil {
+			return err
+		}
 
-	err := c.accountRepository.CreateAccount(ctx, newAccount)
+		newAccount := c.BuildEntity(command, iban)
+
+		err = c.accountRepository.CreateAccount(ctx, newAccount, c.accountPolicy.MaxAccountsPerCurrency)
+
+		if errors.Is(err, repository.ErrIbanTaken) && attempt < maxIbanAllocationAttempts {
+			zap.L().Warn("Generated IBAN is already allocated, retrying", zap.Int("attempt", attempt))
+			continue
+		}
+
+		return err
+	}
+}
+
+// validateAccountOwner rejects users that do not exist, are not active or are
+// younger than the policy allows.
+func (c *commandHandler) validateAccountOwner(ctx context.Context, userId string) error {
+	user, err := c.userRepository.GetUser(ctx, userId)
 
 	if err != nil {
 		return err
 	}
 
+	if !user.IsActive() {
+		return repository.ErrOwnerInactive
+	}
+
+	// The self-declared age is not trusted; the birth year was verified.
+	age, ok := user.AgeAt(time.Now())
+
+	if !ok {
+		return ErrOwnerAgeUnknown
+	}
+
+	if age < c.accountPolicy.MinimumOwnerAge {
+		return errorresponse.NewUnprocessableEntityError(fmt.Sprintf("user must be at least %d years old to open an account", c.accountPolicy.MinimumOwnerAge))
+	}
+
 	return nil
 }
 
-func (c *commandHandler) validateTransferMoney(ctx context.Context, command TransferMoneyCommand) (string, string, error) {
-	// TODO: check user id for existence
+// validateTransferMoney checks the transfer and returns it priced, not yet
+// stored. Accounts in different currencies are refused unless the caller gave
+// a quote or allowed a conversion at the current rate.
+func (c *commandHandler) validateTransferMoney(ctx context.Context, command TransferMoneyCommand) (*domain.Transfer, error) {
+	transfer, toCurrency, err := c.checkTransferMoney(ctx, command)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if toCurrency == transfer.Amount.Currency {
+		if command.QuoteId != "" {
+			return nil, errorresponse.NewFieldError(http.StatusUnprocessableEntity, "quoteId", "both accounts hold the same currency, no quote is needed")
+		}
+
+		return transfer, nil
+	}
+
+	if err := c.convertTransfer(ctx, command, transfer, toCurrency); err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// checkTransferMoney checks the transfer against current balances and account
+// statuses and returns it unpriced, with the destination currency. An account
+// the caller does not own is reported exactly like a missing one.
+func (c *commandHandler) checkTransferMoney(ctx context.Context, command TransferMoneyCommand) (*domain.Transfer, string, error) {
+	if err := c.validateIbans(ctx, command); err != nil {
+		return nil, "", err
+	}
 
 	fromIbanId, err := c.accountRepository.FindByIban(ctx, command.FromIBAN)
 
 	if err != nil {
-		return "", "", err
+		return nil, "", err
 	}
 
 	if len(fromIbanId) == 0 {
-		return "", "", errors.New("from iban does not exist")
+		return nil, "", ErrFromIbanNotFound
 	}
 
 	toIbanId, err := c.accountRepository.FindByIban(ctx, command.ToIBAN)
 
 	if err != nil {
-		return "", "", err
+		return nil, "", err
 	}
 
 	if len(toIbanId) == 0 {
-		return "", "", errors.New("to iban does not exist")
+		return nil, "", ErrToIbanNotFound
 	}
 
-	isBalanceEnough, err := c.accountRepository.CheckAmountForFromIban(ctx, command.FromIBAN, command.Amount)
+	fromAccount, err := c.accountRepository.GetAccount(ctx, fromIbanId)
 
 	if err != nil {
-		return "", "", err
+		return nil, "", err
+	}
+
+	if fromAccount.UserId != command.UserId {
+		return nil, "", ErrFromIbanNotFound
+	}
+
+	if !fromAccount.CanDebit() {
+		return nil, "", repository.ErrAccountDebitBlocked
+	}
+
+	toAccount, err := c.accountRepository.GetAccount(ctx, toIbanId)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !toAccount.CanCredit() {
+		return nil, "", repository.ErrAccountCreditBlocked
+	}
+
+	// The amount is interpreted in the source account's currency, which also
+	// decides how many decimal places are allowed.
+	amount, err := domain.ParseMoney(command.Amount, fromAccount.Currency)
+
+	if err != nil {
+		return nil, "", errorresponse.NewBadRequestError(err.Error())
+	}
+
+	if !amount.IsPositive() {
+		return nil, "", errorresponse.NewBadRequestError("amount must be greater than zero")
+	}
+
+	isBalanceEnough, err := c.accountRepository.CheckAmountForFromIban(ctx, command.FromIBAN, amount)
+
+	if err != nil {
+		return nil, "", err
 	}
 
 	if !isBalanceEnough {
-		return "", "", errors.New("balance is not enough")
+		return nil, "", repository.ErrBalanceNotEnough
 	}
 
-	return fromIbanId, toIbanId, nil
+	transfer := domain.NewTransfer(fromIbanId, command.FromIBAN, toIbanId, command.ToIBAN, amount)
+
+	return transfer, toAccount.Currency, nil
+}
+
+// convertTransfer prices the destination leg of a cross-currency transfer at
+// the caller's quote, or at the current rate if they allowed a conversion.
+func (c *commandHandler) convertTransfer(ctx context.Context, command TransferMoneyCommand, transfer *domain.Transfer, toCurrency string) error {
+	quote, err := c.quoteTransfer(ctx, command, transfer.Amount.Currency, toCurrency)
+
+	if err != nil {
+		return err
+	}
+
+	if err := transfer.Convert(quote); err != nil {
+		return err
+	}
+
+	if !transfer.ConvertedAmount.IsPositive() {
+		return errorresponse.NewBadRequestError("amount is too small to convert")
+	}
+
+	return nil
+}
+
+func (c *commandHandler) quoteTransfer(ctx context.Context, command TransferMoneyCommand, fromCurrency, toCurrency string) (*domain.FxQuote, error) {
+	if command.QuoteId != "" {
+		quote, err := c.fxQuoteRepository.GetQuote(ctx, command.QuoteId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Someone else's quote is reported exactly like a missing one.
+		if quote.UserId != command.UserId {
+			return nil, repository.ErrFxQuoteNotFound
+		}
+
+		if quote.From != fromCurrency || quote.To != toCurrency {
+			return nil, errorresponse.NewFieldError(http.StatusUnprocessableEntity, "quoteId", fmt.Sprintf("quote is for %s to %s, the transfer is from %s to %s", quote.From, quote.To, fromCurrency, toCurrency))
+		}
+
+		return quote, nil
+	}
+
+	if !command.AllowCurrencyConversion {
+		return nil, errorresponse.NewFieldError(http.StatusUnprocessableEntity, "toIBAN", fmt.Sprintf("destination account holds %s, not %s; give a quoteId or set allowCurrencyConversion to convert", toCurrency, fromCurrency))
+	}
+
+	quote, err := c.fxPricer.SpotQuote(ctx, command.UserId, fromCurrency, toCurrency)
+
+	if errors.Is(err, services.ErrExchangeRateUnavailable) {
+		return nil, errorresponse.NewUnprocessableEntityError(err.Error())
+	}
+
+	return quote, err
+}
+
+// validateIbans rejects malformed IBANs before they reach the repository,
+// reporting every offending field at once.
+func (c *commandHandler) validateIbans(ctx context.Context, command TransferMoneyCommand) error {
+	return ValidateTransferIbans(ctx, c.ibanService, c.accountRepository, command.FromIBAN, command.ToIBAN)
+}
+
+// ValidateTransferIbans checks both IBANs of a transfer and reports every
+// offending field at once. IBANs issued before check digits were validated do
+// not pass, but are accepted while they still resolve to an account, until
+// the startup backfill reissues them.
+func ValidateTransferIbans(ctx context.Context, ibanService services.IIbanService, accountRepository repository.IAccountRepository, fromIban, toIban string) error {
+	var details []errorresponse.ErrorDetail
+
+	for _, field := range []struct{ name, iban string }{{"fromIBAN", fromIban}, {"toIBAN", toIban}} {
+		err := ibanService.ValidateIBAN(field.iban)
+
+		if err == nil {
+			continue
+		}
+
+		accountId, lookupErr := accountRepository.FindByIban(ctx, field.iban)
+
+		if lookupErr != nil {
+			return lookupErr
+		}
+
+		if len(accountId) == 0 {
+			details = append(details, errorresponse.ErrorDetail{FieldName: field.name, Description: err.Error()})
+		}
+	}
+
+	if len(details) > 0 {
+		return errorresponse.NewValidationError(details...)
+	}
+
+	return nil
 }
 
 func (c *commandHandler) TransferMoney(ctx context.Context, command TransferMoneyCommand) error {
-	fromIbanId, toIbanId, err := c.validateTransferMoney(ctx, command)
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	command.UserId = principal.UserId
+
+	return c.transferMoney(ctx, command)
+}
+
+func (c *commandHandler) transferMoney(ctx context.Context, command TransferMoneyCommand) error {
+	transfer, err := c.validateTransferMoney(ctx, command)
 
 	if err != nil {
 		return err
 	}
 
-	err = c.accountRepository.TransferMoney(ctx, fromIbanId, toIbanId, command.Amount)
+	err = c.accountRepository.TransferMoney(ctx, transfer)
 
 	if err != nil {
+		c.recordFailedTransfer(ctx, transfer, err)
 		return err
 	}
 
 	return nil
 }
 
-func (c *commandHandler) TransferMoneyWithRabbitMQPublisher(ctx context.Context, command TransferMoneyCommand) error {
-	_, _, err := c.validateTransferMoney(ctx, command)
+// ExecuteTransfer runs a transfer the application makes on a customer's
+// behalf, e.g. a scheduled one, with the same checks as TransferMoney.
+// command.UserId must be set since there is no caller; command.TransferId,
+// when set, is the id the transfer is recorded under and makes retries of it
+// fail with repository.ErrTransferAlreadyCompleted once it has succeeded.
+func (c *commandHandler) ExecuteTransfer(ctx context.Context, command TransferMoneyCommand) (*domain.Transfer, error) {
+	if command.UserId == "" {
+		return nil, errors.New("transfer executed without a user")
+	}
+
+	transfer, err := c.validateTransferMoney(ctx, command)
 
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if command.TransferId != "" {
+		transfer.Id = command.TransferId
+	}
+
+	err = c.accountRepository.TransferMoney(ctx, transfer)
+
+	if err != nil {
+		if !repository.IsTransientError(err) && !errors.Is(err, repository.ErrTransferAlreadyCompleted) {
+			c.recordFailedTransfer(ctx, transfer, err)
+		}
+
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// recordFailedTransfer keeps failed transfers visible in the account history.
+// It runs detached from ctx, which has usually expired by the time a transfer
+// fails on a timeout. It returns repository.ErrTransferAlreadyCompleted when
+// another attempt completed the transfer, which is then left as it is.
+func (c *commandHandler) recordFailedTransfer(ctx context.Context, transfer *domain.Transfer, cause error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+
+	err := c.transferRepository.FailTransfer(ctx, transfer, cause.Error())
+
+	if err != nil && !errors.Is(err, repository.ErrTransferAlreadyCompleted) {
+		zap.L().Error("Failed to record failed transfer", zap.String("transferId", transfer.Id), zap.Error(err))
+	}
+
+	return err
+}
+
+// TransferMoneyWithRabbitMQPublisher records the transfer as PENDING together
+// with the event that hands it to the consumer; the outbox relay publishes the
+// event. The returned transfer can be polled for its outcome.
+func (c *commandHandler) TransferMoneyWithRabbitMQPublisher(ctx context.Context, command TransferMoneyCommand) (*domain.Transfer, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	command.UserId = principal.UserId
+
+	transfer, err := c.validateTransferMoney(ctx, command)
+
+	if err != nil {
+		return nil, err
 	}
 
+	command.TransferId = transfer.Id
+
 	serializedData, err := json.Marshal(command)
 
 	if err != nil {
 		zap.L().Error("Failed to serialize data", zap.Error(err))
-		return err
+		return nil, err
 	}
 
-	err = c.rmqService.Publish(c.exchangeName, "", serializedData)
+	event := domain.NewOutboxEvent(domain.OutboxEventTransferRequested, transfer.Id, c.exchangeName, "", serializedData)
+
+	err = c.transferRepository.CreateTransferWithEvent(ctx, transfer, event)
 
 	if err != nil {
-		zap.L().Error("Failed to publish message", zap.Error(err))
-		return err
+		return nil, err
 	}
 
-	zap.L().Info("Message published successfully")
+	zap.L().Info("Transfer queued", zap.String("transferId", transfer.Id), zap.String("eventId", event.Id))
 
-	return nil
+	return transfer, nil
 }
 
 func (c *commandHandler) TransferMoneyWithRabbitMQConsumer() {
@@ -149,19 +482,316 @@ func (c *commandHandler) TransferMoneyWithRabbitMQConsumer() {
 	for msg := range msgs {
 		var transferReq TransferMoneyCommand
 		if err := json.Unmarshal(msg.Body, &transferReq); err != nil {
-			log.Println("Failed to parse transfer request:", err)
+			zap.L().Error("Failed to parse transfer request", zap.Error(err))
+
+			// A message that cannot be parsed will never succeed.
+			if err := c.rmqService.DeadLetter(msg, err); err != nil {
+				zap.L().Error("Failed to dead-letter message", zap.Error(err))
+			}
+
 			continue
 		}
 
-		err := c.TransferMoney(context.Background(), transferReq)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := c.processQueuedTransfer(ctx, transferReq)
+		cancel()
+
+		switch {
+		case err == nil:
+			if err := msg.Ack(false); err != nil {
+				zap.L().Error("Failed to ack message", zap.Error(err))
+			}
+
+			zap.L().Info("Message consumed successfully", zap.String("transferId", transferReq.TransferId))
+
+		case repository.IsTransientError(err):
+			retried, retryErr := c.rmqService.Retry(msg, err)
+
+			if retryErr != nil {
+				zap.L().Error("Failed to schedule retry", zap.String("transferId", transferReq.TransferId), zap.Error(retryErr))
+			} else if !retried {
+				zap.L().Error("Transfer retries exhausted, moved to dead-letter queue", zap.String("transferId", transferReq.TransferId), zap.Error(err))
+			} else {
+				zap.L().Warn("Transfer failed transiently, will retry", zap.String("transferId", transferReq.TransferId), zap.Error(err))
+			}
+
+		default:
+			// The transfer was rejected and marked FAILED; retrying would not
+			// change the outcome.
+			if err := msg.Ack(false); err != nil {
+				zap.L().Error("Failed to ack message", zap.Error(err))
+			}
+
+			zap.L().Error("Failed to transfer money", zap.String("transferId", transferReq.TransferId), zap.Error(err))
+		}
+	}
+
+	// The channel survives reconnects and is only closed on shutdown.
+	zap.L().Info("Stopped consuming transfer requests")
+}
+
+func (c *commandHandler) GetDeadLetterTransfers(ctx context.Context, limit int) ([]rabbitmq.DeadLetter, error) {
+	return c.rmqService.PeekDeadLetters(limit)
+}
+
+func (c *commandHandler) ReplayDeadLetterTransfers(ctx context.Context, limit int) (int, error) {
+	replayed, err := c.rmqService.ReplayDeadLetters(limit)
+
+	if err != nil {
+		zap.L().Error("Failed to replay dead-lettered transfers", zap.Int("replayed", replayed), zap.Error(err))
+		return replayed, err
+	}
+
+	zap.L().Info("Replayed dead-lettered transfers", zap.Int("replayed", replayed))
+
+	return replayed, nil
+}
+
+// processQueuedTransfer moves the PENDING transfer created by the publisher to
+// COMPLETED or FAILED. A transfer that is no longer PENDING has already been
+// processed by an earlier delivery of the same message and is skipped.
+func (c *commandHandler) processQueuedTransfer(ctx context.Context, command TransferMoneyCommand) error {
+	// Transfers queued before callers were recorded were accepted without an
+	// ownership check; attribute them to the owner of the source account.
+	if command.UserId == "" {
+		fromIbanId, err := c.accountRepository.FindByIban(ctx, command.FromIBAN)
 
 		if err != nil {
-			zap.L().Error("Failed to transfer money", zap.Error(err))
-			log.Println("Failed to transfer money:", err)
+			return err
+		}
+
+		if fromIbanId != "" {
+			fromAccount, err := c.accountRepository.GetAccount(ctx, fromIbanId)
+
+			if err != nil {
+				return err
+			}
+
+			command.UserId = fromAccount.UserId
 		}
+	}
+
+	// Messages published before transfers were tracked carry no id.
+	if command.TransferId == "" {
+		return c.transferMoney(ctx, command)
+	}
+
+	transfer, err := c.transferRepository.GetTransfer(ctx, command.TransferId)
+
+	if err != nil {
+		return err
+	}
+
+	if transfer.Status != domain.TransferStatusPending {
+		zap.L().Info("Skipping transfer that was already processed", zap.String("transferId", transfer.Id), zap.String("status", string(transfer.Status)))
+		return nil
+	}
+
+	// Balances may have changed since the transfer was accepted. A converted
+	// transfer keeps the price it was accepted at, even if its quote has
+	// expired since.
+	_, _, err = c.checkTransferMoney(ctx, command)
+
+	if err == nil {
+		err = c.accountRepository.TransferMoney(ctx, transfer)
+	}
+
+	// Another delivery of the same message got here first. The PENDING check
+	// above is only a shortcut; the posting transaction and the FAILED write
+	// both refuse to touch a transfer that has completed.
+	if errors.Is(err, repository.ErrTransferAlreadyCompleted) {
+		zap.L().Info("Skipping transfer that was already completed", zap.String("transferId", transfer.Id))
+		return nil
+	}
+
+	// Transient failures stay PENDING so a retry of the message can still
+	// complete the transfer.
+	if err != nil && !repository.IsTransientError(err) {
+		if recordErr := c.recordFailedTransfer(ctx, transfer, err); errors.Is(recordErr, repository.ErrTransferAlreadyCompleted) {
+			zap.L().Info("Skipping transfer that was already completed", zap.String("transferId", transfer.Id))
+			return nil
+		}
+	}
+
+	return err
+}
+
+// Deposit pays cash into an account through a branch or ATM.
+func (c *commandHandler) Deposit(ctx context.Context, command CashCommand) (*domain.Transfer, error) {
+	account, amount, channel, err := c.validateCashCommand(ctx, command)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !account.CanCredit() {
+		return nil, repository.ErrAccountCreditBlocked
+	}
+
+	return c.executeCashTransfer(ctx, domain.NewDeposit(account.Id, account.Iban, amount, channel, command.Reference))
+}
+
+// Withdraw pays cash out of an account through a branch or ATM.
+func (c *commandHandler) Withdraw(ctx context.Context, command CashCommand) (*domain.Transfer, error) {
+	account, amount, channel, err := c.validateCashCommand(ctx, command)
+
+	if err != nil {
+		return nil, err
+	}
 
-		zap.L().Info("Message consumed successfully")
+	if !account.CanDebit() {
+		return nil, repository.ErrAccountDebitBlocked
 	}
+
+	// Checked again on the balance the transaction reads.
+	canCover, err := account.CanCover(amount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !canCover {
+		return nil, repository.ErrBalanceNotEnough
+	}
+
+	return c.executeCashTransfer(ctx, domain.NewWithdrawal(account.Id, account.Iban, amount, channel, command.Reference))
+}
+
+// validateCashCommand returns the account, the amount in its currency and the
+// channel, with the caller recorded as the teller for branch operations.
+func (c *commandHandler) validateCashCommand(ctx context.Context, command CashCommand) (*domain.Account, domain.Money, domain.CashChannel, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, domain.Money{}, domain.CashChannel{}, err
+	}
+
+	account, err := c.accountRepository.GetAccount(ctx, command.AccountId)
+
+	if err != nil {
+		return nil, domain.Money{}, domain.CashChannel{}, err
+	}
+
+	amount, err := domain.ParseMoney(command.Amount, account.Currency)
+
+	if err != nil {
+		return nil, domain.Money{}, domain.CashChannel{}, errorresponse.NewBadRequestError(err.Error())
+	}
+
+	if !amount.IsPositive() {
+		return nil, domain.Money{}, domain.CashChannel{}, errorresponse.NewBadRequestError("amount must be greater than zero")
+	}
+
+	channel := command.Channel
+	if channel.Type == domain.CashChannelBranch {
+		channel.TellerId = principal.UserId
+	}
+
+	return account, amount, channel, nil
+}
+
+func (c *commandHandler) executeCashTransfer(ctx context.Context, transfer *domain.Transfer) (*domain.Transfer, error) {
+	err := c.accountRepository.TransferMoney(ctx, transfer)
+
+	if err != nil {
+		c.recordFailedTransfer(ctx, transfer, err)
+		return nil, err
+	}
+
+	zap.L().Info("Cash operation completed", zap.String("transferId", transfer.Id), zap.String("type", string(transfer.Type)), zap.String("channel", string(transfer.Channel.Type)))
+
+	return transfer, nil
+}
+
+// ChangeAccountStatus freezes, unfreezes, marks dormant or closes an account.
+// Closing an account with money in it requires SweepToIban.
+func (c *commandHandler) ChangeAccountStatus(ctx context.Context, command ChangeAccountStatusCommand) (*domain.Account, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	status := domain.AccountStatus(command.Status)
+
+	audit := domain.NewAuditRecord("account", command.AccountId, domain.AuditAccountStatusChanged, principal.UserId)
+	audit.Reason = command.Reason
+
+	var account *domain.Account
+
+	if status == domain.AccountStatusClosed {
+		sweepAccountId := ""
+
+		if command.SweepToIban != "" {
+			if sweepAccountId, err = c.accountRepository.FindByIban(ctx, command.SweepToIban); err != nil {
+				return nil, err
+			}
+
+			if sweepAccountId == "" {
+				return nil, ErrSweepIbanNotFound
+			}
+		}
+
+		account, err = c.accountRepository.CloseAccount(ctx, command.AccountId, sweepAccountId, command.Reason, audit)
+	} else {
+		account, err = c.accountRepository.ChangeAccountStatus(ctx, command.AccountId, status, command.Reason, audit)
+	}
+
+	switch {
+	case errors.Is(err, domain.ErrInvalidAccountStatus):
+		return nil, errorresponse.NewBadRequestError(err.Error())
+	case errors.Is(err, domain.ErrInvalidAccountStatusTransition):
+		return nil, errorresponse.NewConflictError(err.Error())
+	case errors.Is(err, domain.ErrAccountHasBalance):
+		return nil, errorresponse.NewConflictError("account balance must be zero to close it, or swept to another account with sweepToIban")
+	case errors.Is(err, domain.ErrAccountHasHolds):
+		return nil, errorresponse.NewConflictError("account has funds on hold; capture or release them before closing it")
+	case err != nil:
+		return nil, err
+	}
+
+	zap.L().Info("Account status changed", zap.String("accountId", account.Id), zap.String("status", string(account.Status)), zap.String("changedBy", principal.UserId), zap.String("reason", command.Reason))
+
+	return account, nil
+}
+
+// SetOverdraftLimit sets how far below zero an account may go.
+func (c *commandHandler) SetOverdraftLimit(ctx context.Context, command SetOverdraftLimitCommand) (*domain.Account, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := c.accountRepository.GetAccount(ctx, command.AccountId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := domain.ParseMoney(command.Limit, account.Currency)
+
+	if err != nil {
+		return nil, errorresponse.NewFieldError(http.StatusBadRequest, "limit", err.Error())
+	}
+
+	audit := domain.NewAuditRecord("account", account.Id, domain.AuditAccountOverdraftChanged, principal.UserId)
+	audit.Reason = command.Reason
+
+	account, err = c.accountRepository.SetOverdraftLimit(ctx, account.Id, limit, audit)
+
+	switch {
+	case errors.Is(err, domain.ErrInvalidOverdraftLimit):
+		return nil, errorresponse.NewFieldError(http.StatusBadRequest, "limit", err.Error())
+	case errors.Is(err, domain.ErrOverdraftLimitBelowBalance):
+		return nil, errorresponse.NewConflictError(err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	zap.L().Info("Account overdraft limit changed", zap.String("accountId", account.Id), zap.String("limit", account.EffectiveOverdraftLimit().String()), zap.String("changedBy", principal.UserId), zap.String("reason", command.Reason))
+
+	return account, nil
 }
 
 func (c *commandHandler) BuildEntity(command Command, iban string) *domain.Account {
@@ -169,8 +799,9 @@ func (c *commandHandler) BuildEntity(command Command, iban string) *domain.Accou
 		Id:        uuid.New().String(),
 		Currency:  command.Currency,
 		Iban:      iban,
-		Balance:   0.0,
+		Balance:   domain.ZeroMoney(command.Currency),
 		UserId:    command.UserId,
+		Status:    domain.AccountStatusActive,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
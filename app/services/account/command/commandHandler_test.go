@@ -0,0 +1,89 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"kc-bank/app/repository"
+	errorresponse "kc-bank/pkg/error_response"
+	"kc-bank/pkg/services"
+	"testing"
+)
+
+// ibanLookups is an account repository that only resolves IBANs.
+type ibanLookups struct {
+	repository.IAccountRepository
+	accountIds map[string]string
+	err        error
+}
+
+func (r *ibanLookups) FindByIban(_ context.Context, iban string) (string, error) {
+	return r.accountIds[iban], r.err
+}
+
+func TestValidateTransferIbans(t *testing.T) {
+	ibanService := services.NewIbanService()
+
+	valid, err := ibanService.GenerateIBAN("TR")
+	if err != nil {
+		t.Fatalf("GenerateIBAN() error = %v", err)
+	}
+
+	// Issued by the baseline GenerateIBAN("TR", 5, 16): 25 characters with
+	// random check digits.
+	const legacy = "TR12345678901234567890123"
+	const unknownLegacy = "TR98765432109876543210987"
+
+	lookups := &ibanLookups{accountIds: map[string]string{valid: "valid-account", legacy: "legacy-account"}}
+
+	tests := []struct {
+		name       string
+		fromIban   string
+		toIban     string
+		wantFields []string
+	}{
+		{name: "valid IBANs", fromIban: valid, toIban: valid},
+		{name: "legacy IBAN of an existing account", fromIban: legacy, toIban: valid},
+		{name: "legacy IBANs on both sides", fromIban: legacy, toIban: legacy},
+		{name: "legacy-format IBAN of no account", fromIban: valid, toIban: unknownLegacy, wantFields: []string{"toIBAN"}},
+		{name: "malformed IBANs", fromIban: "TR00", toIban: "not an iban", wantFields: []string{"fromIBAN", "toIBAN"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTransferIbans(context.Background(), ibanService, lookups, tt.fromIban, tt.toIban)
+
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateTransferIbans() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var response *errorresponse.ErrorResponse
+			if !errors.As(err, &response) {
+				t.Fatalf("ValidateTransferIbans() error = %v, want a validation error", err)
+			}
+
+			if len(response.ErrorDetail) != len(tt.wantFields) {
+				t.Fatalf("ValidateTransferIbans() details = %+v, want fields %v", response.ErrorDetail, tt.wantFields)
+			}
+
+			for i, field := range tt.wantFields {
+				if response.ErrorDetail[i].FieldName != field {
+					t.Errorf("detail %d field = %q, want %q", i, response.ErrorDetail[i].FieldName, field)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateTransferIbansReportsLookupFailures(t *testing.T) {
+	lookupErr := errors.New("lookup failed")
+	lookups := &ibanLookups{err: lookupErr}
+
+	err := ValidateTransferIbans(context.Background(), services.NewIbanService(), lookups, "TR12345678901234567890123", "TR12345678901234567890123")
+
+	if !errors.Is(err, lookupErr) {
+		t.Fatalf("ValidateTransferIbans() error = %v, want %v", err, lookupErr)
+	}
+}
@@ -0,0 +1,9 @@
+package command
+
+type SetOverdraftLimitCommand struct {
+	AccountId string
+	// Limit is decimal text in the account's currency; zero removes the
+	// overdraft.
+	Limit  string
+	Reason string
+}
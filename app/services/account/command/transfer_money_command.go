@@ -1,7 +1,24 @@
 package command
 
 type TransferMoneyCommand struct {
-	Amount   float64
+	// Amount is the exact decimal text sent by the client. It is parsed into
+	// domain.Money once the source account's currency is known.
+	Amount   string
 	FromIBAN string
 	ToIBAN   string
+
+	// AllowCurrencyConversion lets the transfer reach an account in another
+	// currency, converted at the current rate. QuoteId converts it at the rate
+	// of a quote the caller obtained instead.
+	AllowCurrencyConversion bool
+	QuoteId                 string
+
+	// UserId is the caller, who must own the FromIBAN account. It is set from
+	// the authenticated principal and travels with queued transfers, so the
+	// consumer checks ownership against the same caller.
+	UserId string
+
+	// TransferId is set by the RabbitMQ publisher, so the consumer can pick up
+	// the PENDING transfer record it created.
+	TransferId string
 }
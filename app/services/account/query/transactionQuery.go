@@ -0,0 +1,137 @@
+package query
+
+import (
+	"context"
+	"encoding/base64"
+	"kc-bank/app/repository"
+	"kc-bank/domain"
+	errorresponse "kc-bank/pkg/error_response"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTransactionPageSize = 20
+	maxTransactionPageSize     = 100
+)
+
+// TransactionQuery filters the transfer history of an account. Amounts are
+// decimal strings in the account's currency; Cursor is the NextCursor of a
+// previous page.
+type TransactionQuery struct {
+	AccountId string
+	From      *time.Time
+	To        *time.Time
+	MinAmount string
+	MaxAmount string
+	Direction domain.TransferDirection
+	Cursor    string
+	Limit     int
+}
+
+type TransactionPage struct {
+	Account    *domain.Account
+	Transfers  []*domain.Transfer
+	NextCursor string
+}
+
+func (u *accountQueryService) GetAccountTransactions(ctx context.Context, query TransactionQuery) (*TransactionPage, error) {
+	account, err := u.GetAccount(ctx, query.AccountId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filter := repository.TransferFilter{
+		AccountId: account.Id,
+		From:      query.From,
+		To:        query.To,
+		Direction: query.Direction,
+		Limit:     query.Limit,
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultTransactionPageSize
+	}
+
+	if filter.Limit > maxTransactionPageSize {
+		filter.Limit = maxTransactionPageSize
+	}
+
+	if filter.MinAmount, err = parseAmountFilter(query.MinAmount, account.Currency); err != nil {
+		return nil, err
+	}
+
+	if filter.MaxAmount, err = parseAmountFilter(query.MaxAmount, account.Currency); err != nil {
+		return nil, err
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeTransferCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		filter.After = cursor
+	}
+
+	pageSize := filter.Limit
+
+	// Ask for one extra row to learn whether another page exists.
+	filter.Limit++
+
+	transfers, err := u.transferRepository.GetTransfersByAccount(ctx, filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TransactionPage{Account: account, Transfers: transfers}
+
+	if len(transfers) > pageSize {
+		page.Transfers = transfers[:pageSize]
+		page.NextCursor = encodeTransferCursor(page.Transfers[pageSize-1])
+	}
+
+	return page, nil
+}
+
+func parseAmountFilter(amount, currency string) (*int64, error) {
+	if amount == "" {
+		return nil, nil
+	}
+
+	money, err := domain.ParseMoney(amount, currency)
+	if err != nil {
+		return nil, errorresponse.NewBadRequestError(err.Error())
+	}
+
+	return &money.Minor, nil
+}
+
+func encodeTransferCursor(transfer *domain.Transfer) string {
+	raw := strconv.FormatInt(transfer.CreatedAt.UnixMilli(), 10) + ":" + transfer.Id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTransferCursor(cursor string) (*repository.TransferCursor, error) {
+	invalid := errorresponse.NewBadRequestError("invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+
+	millis, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return nil, invalid
+	}
+
+	createdAtMillis, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+
+	return &repository.TransferCursor{CreatedAtMillis: createdAtMillis, Id: id}, nil
+}
@@ -5,39 +5,81 @@ import (
 	"errors"
 	"kc-bank/app/repository"
 	"kc-bank/domain"
+	"kc-bank/pkg/auth"
 )
 
 type IAccountQueryService interface {
 	GetAccount(ctx context.Context, Id string) (*domain.Account, error)
 	GetAllAccounts(ctx context.Context) ([]*domain.Account, error)
+	GetAccountLedger(ctx context.Context, Id string) (*AccountLedger, error)
+	GetAccountTransactions(ctx context.Context, query TransactionQuery) (*TransactionPage, error)
+	GetTransfer(ctx context.Context, Id string) (*domain.Transfer, error)
+	GetOverdraftBreaches(ctx context.Context) ([]*domain.Account, error)
+}
+
+// AccountLedger pairs the cached balance of an account with the balance
+// derived from its journal entries.
+type AccountLedger struct {
+	Account       *domain.Account
+	LedgerBalance domain.Money
+	Entries       []*domain.JournalEntry
 }
 
 type accountQueryService struct {
-	accountRepository repository.IAccountRepository
+	accountRepository  repository.IAccountRepository
+	ledgerRepository   repository.ILedgerRepository
+	transferRepository repository.ITransferRepository
 }
 
-func NewAccountQueryService(accountRepository repository.IAccountRepository) IAccountQueryService {
+func NewAccountQueryService(
+	accountRepository repository.IAccountRepository,
+	ledgerRepository repository.ILedgerRepository,
+	transferRepository repository.ITransferRepository,
+) IAccountQueryService {
 	return &accountQueryService{
-		accountRepository: accountRepository,
+		accountRepository:  accountRepository,
+		ledgerRepository:   ledgerRepository,
+		transferRepository: transferRepository,
 	}
 }
 
+// GetAccount returns the account to its owner and to staff allowed to read
+// any account. Other callers get the same not found error as for a missing
+// account, so account ids can not be probed.
 func (u *accountQueryService) GetAccount(ctx context.Context, Id string) (*domain.Account, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
 	account, err := u.accountRepository.GetAccount(ctx, Id)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if account == nil {
-		return nil, errors.New("account not found")
+	if account == nil || (account.UserId != principal.UserId && !principal.Can(auth.PermissionAccountReadAny)) {
+		return nil, repository.ErrAccountNotFound
 	}
 
 	return account, nil
 }
 
+// GetAllAccounts returns the caller's accounts, or every account to staff
+// allowed to read any account.
 func (u *accountQueryService) GetAllAccounts(ctx context.Context) ([]*domain.Account, error) {
-	accounts, err := u.accountRepository.GetAllAccounts(ctx)
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if principal.Can(auth.PermissionAccountReadAny) {
+		return u.accountRepository.GetAllAccounts(ctx)
+	}
+
+	accounts, err := u.accountRepository.GetAccountsByUser(ctx, principal.UserId)
 
 	if err != nil {
 		return nil, err
@@ -45,3 +87,59 @@ func (u *accountQueryService) GetAllAccounts(ctx context.Context) ([]*domain.Acc
 
 	return accounts, nil
 }
+
+func (u *accountQueryService) GetAccountLedger(ctx context.Context, Id string) (*AccountLedger, error) {
+	account, err := u.GetAccount(ctx, Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ledgerBalance, err := u.ledgerRepository.GetAccountBalance(ctx, account.Id, account.Currency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := u.ledgerRepository.GetJournalEntriesByAccount(ctx, account.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountLedger{
+		Account:       account,
+		LedgerBalance: ledgerBalance,
+		Entries:       entries,
+	}, nil
+}
+
+// GetTransfer returns the transfer only to the owners of its source and
+// destination accounts.
+func (u *accountQueryService) GetTransfer(ctx context.Context, Id string) (*domain.Transfer, error) {
+	transfer, err := u.transferRepository.GetTransfer(ctx, Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, accountId := range []string{transfer.FromAccountId, transfer.ToAccountId} {
+		_, err := u.GetAccount(ctx, accountId)
+
+		if err == nil {
+			return transfer, nil
+		}
+
+		if !errors.Is(err, repository.ErrAccountNotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, repository.ErrTransferNotFound
+}
+
+// GetOverdraftBreaches returns the accounts whose balance is past their
+// overdraft limit.
+func (u *accountQueryService) GetOverdraftBreaches(ctx context.Context) ([]*domain.Account, error) {
+	return u.accountRepository.GetOverdraftBreaches(ctx)
+}
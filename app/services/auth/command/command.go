@@ -0,0 +1,14 @@
+package command
+
+type LoginCommand struct {
+	Email    string
+	Password string
+}
+
+type RefreshCommand struct {
+	RefreshToken string
+}
+
+type LogoutCommand struct {
+	RefreshToken string
+}
@@ -0,0 +1,181 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"kc-bank/app/repository"
+	"kc-bank/domain"
+	"kc-bank/pkg/auth"
+	errorresponse "kc-bank/pkg/error_response"
+	"kc-bank/pkg/services"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidCredentials = errorresponse.NewUnauthorizedError("invalid email or password")
+	ErrUserInactive       = errorresponse.NewForbiddenError("user is not active")
+)
+
+// dummyPasswordHash is compared against when the email is unknown, so a login
+// takes as long for unknown emails as for wrong passwords.
+const dummyPasswordHash = "$2a$10$yRif2Hi7EP0a7Qvr2mdFiO2.t776ADRbGG6AzYVM80DWYIZRq0ru2"
+
+type TokenPair struct {
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+type ICommandHandler interface {
+	Login(ctx context.Context, command LoginCommand) (*TokenPair, error)
+	Refresh(ctx context.Context, command RefreshCommand) (*TokenPair, error)
+	Logout(ctx context.Context, command LogoutCommand) error
+}
+
+type commandHandler struct {
+	userRepository         repository.IUserRepository
+	refreshTokenRepository repository.IRefreshTokenRepository
+	passwordService        services.IPasswordService
+	tokenService           services.ITokenService
+	emailNormalizer        services.EmailNormalizer
+	refreshTokenTTL        time.Duration
+}
+
+func NewCommandHandler(
+	userRepository repository.IUserRepository,
+	refreshTokenRepository repository.IRefreshTokenRepository,
+	passwordService services.IPasswordService,
+	tokenService services.ITokenService,
+	emailNormalizer services.EmailNormalizer,
+	refreshTokenTTL time.Duration,
+) ICommandHandler {
+	return &commandHandler{
+		userRepository:         userRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		passwordService:        passwordService,
+		tokenService:           tokenService,
+		emailNormalizer:        emailNormalizer,
+		refreshTokenTTL:        refreshTokenTTL,
+	}
+}
+
+func (c *commandHandler) Login(ctx context.Context, command LoginCommand) (*TokenPair, error) {
+	user, err := c.userRepository.FindByEmail(ctx, c.emailNormalizer.Canonical(command.Email))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// A deleted user is answered like a missing one, so the response does not
+	// reveal that the account ever existed.
+	if user == nil || user.Status == domain.UserStatusDeleted {
+		c.passwordService.CheckPasswordHash(command.Password, dummyPasswordHash)
+		return nil, ErrInvalidCredentials
+	}
+
+	if !c.passwordService.CheckPasswordHash(command.Password, user.Password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Checked after the password, so the response does not reveal the status
+	// of an account to someone who does not know its password.
+	if !user.IsActive() {
+		return nil, ErrUserInactive
+	}
+
+	refreshToken, err := c.tokenService.NewRefreshToken()
+
+	if err != nil {
+		return nil, err
+	}
+
+	stored := domain.NewRefreshToken(c.tokenService.HashRefreshToken(refreshToken), user.Id, "", c.refreshTokenTTL)
+
+	if err := c.refreshTokenRepository.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("User logged in", zap.String("userId", user.Id))
+
+	return c.issueTokenPair(&auth.Principal{UserId: user.Id, Role: user.EffectiveRole()}, refreshToken, stored.ExpiresAt)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token; the presented one can not be used again. Presenting a token that was
+// already exchanged revokes every token of its family.
+func (c *commandHandler) Refresh(ctx context.Context, command RefreshCommand) (*TokenPair, error) {
+	refreshToken, err := c.tokenService.NewRefreshToken()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// On reuse the repository returns the presented token instead of a new one.
+	stored, err := c.refreshTokenRepository.RotateRefreshToken(ctx, c.tokenService.HashRefreshToken(command.RefreshToken), c.tokenService.HashRefreshToken(refreshToken), c.refreshTokenTTL)
+
+	if errors.Is(err, repository.ErrRefreshTokenReused) {
+		zap.L().Warn("Refresh token reused, revoking its family", zap.String("userId", stored.UserId), zap.String("familyId", stored.FamilyId))
+
+		if revokeErr := c.refreshTokenRepository.RevokeRefreshTokenFamily(ctx, stored.FamilyId); revokeErr != nil {
+			return nil, revokeErr
+		}
+
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The role is read again, so role changes apply from the next refresh.
+	user, err := c.userRepository.GetUser(ctx, stored.UserId)
+
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, repository.ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive() {
+		return nil, repository.ErrInvalidRefreshToken
+	}
+
+	return c.issueTokenPair(&auth.Principal{UserId: user.Id, Role: user.EffectiveRole()}, refreshToken, stored.ExpiresAt)
+}
+
+// Logout revokes the refresh token and every token rotated from the same
+// login. Access tokens already issued stay valid until they expire.
+func (c *commandHandler) Logout(ctx context.Context, command LogoutCommand) error {
+	stored, err := c.refreshTokenRepository.GetRefreshToken(ctx, c.tokenService.HashRefreshToken(command.RefreshToken))
+
+	// Logging out twice is not an error.
+	if errors.Is(err, repository.ErrInvalidRefreshToken) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.refreshTokenRepository.RevokeRefreshTokenFamily(ctx, stored.FamilyId)
+}
+
+func (c *commandHandler) issueTokenPair(principal *auth.Principal, refreshToken string, refreshTokenExpiresAt time.Time) (*TokenPair, error) {
+	accessToken, accessTokenExpiresAt, err := c.tokenService.IssueAccessToken(principal)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessTokenExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshTokenExpiresAt,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"kc-bank/app/repository"
+	"kc-bank/domain"
+	"kc-bank/pkg/services"
+	"testing"
+)
+
+// usersByEmail is a user repository that finds a single user by email.
+type usersByEmail struct {
+	repository.IUserRepository
+	user *domain.User
+}
+
+func (r *usersByEmail) FindByEmail(_ context.Context, canonicalEmail string) (*domain.User, error) {
+	if r.user == nil || r.user.CanonicalEmail != canonicalEmail {
+		return nil, nil
+	}
+
+	return r.user, nil
+}
+
+// plainPasswords compares passwords with their "hash" as they are.
+type plainPasswords struct{}
+
+func (plainPasswords) HashPassword(password string) (string, error) { return password, nil }
+
+func (plainPasswords) CheckPasswordHash(password, hash string) bool { return password == hash }
+
+func TestLoginRefusals(t *testing.T) {
+	user := func(status domain.UserStatus) *domain.User {
+		return &domain.User{Id: "u1", CanonicalEmail: "ayse@example.com", Password: "secret", Status: status}
+	}
+
+	tests := []struct {
+		name     string
+		user     *domain.User
+		password string
+		wantErr  error
+	}{
+		{name: "unknown email", wantErr: ErrInvalidCredentials},
+		{name: "wrong password", user: user(domain.UserStatusActive), password: "guess", wantErr: ErrInvalidCredentials},
+		{name: "deactivated user", user: user(domain.UserStatusDeactivated), password: "secret", wantErr: ErrUserInactive},
+		{name: "deleted user looks unknown", user: user(domain.UserStatusDeleted), password: "secret", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &commandHandler{userRepository: &usersByEmail{user: tt.user}, passwordService: plainPasswords{}, emailNormalizer: services.EmailNormalizer{}}
+
+			_, err := handler.Login(context.Background(), LoginCommand{Email: "ayse@example.com", Password: tt.password})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
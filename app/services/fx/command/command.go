@@ -0,0 +1,9 @@
+package command
+
+type CreateQuoteCommand struct {
+	FromCurrency string
+	ToCurrency   string
+	// Amount, in FromCurrency, is optional; when set the quote result also
+	// shows what it converts to.
+	Amount string
+}
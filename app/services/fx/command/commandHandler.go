@@ -0,0 +1,120 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"kc-bank/app/repository"
+	"kc-bank/domain"
+	"kc-bank/pkg/auth"
+	errorresponse "kc-bank/pkg/error_response"
+	"kc-bank/pkg/services"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// QuoteResult is a stored quote and, when an amount was given, what it
+// converts to at the quote.
+type QuoteResult struct {
+	Quote     *domain.FxQuote
+	Amount    *domain.Money
+	Converted *domain.Money
+}
+
+type ICommandHandler interface {
+	CreateQuote(ctx context.Context, command CreateQuoteCommand) (*QuoteResult, error)
+}
+
+type commandHandler struct {
+	fxQuoteRepository repository.IFxQuoteRepository
+	fxPricer          services.IFxPricer
+}
+
+func NewCommandHandler(fxQuoteRepository repository.IFxQuoteRepository, fxPricer services.IFxPricer) ICommandHandler {
+	return &commandHandler{
+		fxQuoteRepository: fxQuoteRepository,
+		fxPricer:          fxPricer,
+	}
+}
+
+// CreateQuote locks the current rate for the caller. The quote id can then be
+// given to a transfer between accounts in the two currencies until it expires.
+func (c *commandHandler) CreateQuote(ctx context.Context, command CreateQuoteCommand) (*QuoteResult, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCurrencies(command); err != nil {
+		return nil, err
+	}
+
+	result := &QuoteResult{}
+
+	if command.Amount != "" {
+		amount, err := domain.ParseMoney(command.Amount, command.FromCurrency)
+
+		if err != nil {
+			return nil, errorresponse.NewFieldError(http.StatusBadRequest, "amount", err.Error())
+		}
+
+		if !amount.IsPositive() {
+			return nil, errorresponse.NewFieldError(http.StatusBadRequest, "amount", "amount must be greater than zero")
+		}
+
+		result.Amount = &amount
+	}
+
+	quote, err := c.fxPricer.Quote(ctx, principal.UserId, command.FromCurrency, command.ToCurrency)
+
+	if errors.Is(err, services.ErrExchangeRateUnavailable) {
+		return nil, errorresponse.NewUnprocessableEntityError(err.Error())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result.Quote = quote
+
+	if result.Amount != nil {
+		converted, _, err := quote.Convert(*result.Amount)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.Converted = &converted
+	}
+
+	if err := c.fxQuoteRepository.CreateQuote(ctx, quote); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("FX quote created", zap.String("quoteId", quote.Id), zap.String("userId", principal.UserId), zap.String("pair", quote.From+"/"+quote.To), zap.String("rate", quote.Rate.Rate))
+
+	return result, nil
+}
+
+func validateCurrencies(command CreateQuoteCommand) error {
+	var details []errorresponse.ErrorDetail
+
+	if _, err := domain.CurrencyScale(command.FromCurrency); err != nil {
+		details = append(details, errorresponse.ErrorDetail{FieldName: "fromCurrency", Description: err.Error()})
+	}
+
+	if _, err := domain.CurrencyScale(command.ToCurrency); err != nil {
+		details = append(details, errorresponse.ErrorDetail{FieldName: "toCurrency", Description: err.Error()})
+	}
+
+	if command.FromCurrency == command.ToCurrency {
+		details = append(details, errorresponse.ErrorDetail{FieldName: "toCurrency", Description: "currencies must differ"})
+	}
+
+	if len(details) > 0 {
+		return errorresponse.NewValidationError(details...)
+	}
+
+	return nil
+}
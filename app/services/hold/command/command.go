@@ -0,0 +1,20 @@
+package command
+
+import "time"
+
+type CreateHoldCommand struct {
+	AccountId string
+	// Amount is decimal text in the account's currency.
+	Amount    string
+	Reference string
+	// ExpiresAt defaults to the policy's default TTL from now.
+	ExpiresAt *time.Time
+}
+
+type CaptureHoldCommand struct {
+	HoldId string
+	// Amount defaults to the whole hold; less captures part of it and
+	// releases the rest.
+	Amount string
+	ToIBAN string
+}
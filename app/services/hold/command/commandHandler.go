@@ -0,0 +1,256 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"kc-bank/app/repository"
+	"kc-bank/domain"
+	"kc-bank/pkg/auth"
+	errorresponse "kc-bank/pkg/error_response"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrToIbanNotFound   = errorresponse.NewFieldError(http.StatusNotFound, "toIBAN", "to iban does not exist")
+	ErrCaptureToSelf    = errorresponse.NewFieldError(http.StatusBadRequest, "toIBAN", "a hold can not be captured into its own account")
+	ErrCaptureCurrency  = errorresponse.NewFieldError(http.StatusUnprocessableEntity, "toIBAN", "a hold can only be captured into an account in its currency")
+	ErrInvalidExpiresAt = errorresponse.NewFieldError(http.StatusBadRequest, "expiresAt", "expiresAt must be in the future and within the maximum hold duration")
+	ErrHoldManageDenied = errorresponse.NewForbiddenError("you do not have permission to manage holds")
+)
+
+// HoldPolicy bounds how long a hold may reserve money.
+type HoldPolicy struct {
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+type ICommandHandler interface {
+	CreateHold(ctx context.Context, command CreateHoldCommand) (*domain.Hold, error)
+	CaptureHold(ctx context.Context, command CaptureHoldCommand) (*domain.Hold, *domain.Transfer, error)
+	ReleaseHold(ctx context.Context, holdId string) (*domain.Hold, error)
+	ReleaseExpiredHolds(ctx context.Context, limit int) (int, error)
+}
+
+type commandHandler struct {
+	holdRepository    repository.IHoldRepository
+	accountRepository repository.IAccountRepository
+	policy            HoldPolicy
+}
+
+func NewCommandHandler(holdRepository repository.IHoldRepository, accountRepository repository.IAccountRepository, policy HoldPolicy) ICommandHandler {
+	return &commandHandler{
+		holdRepository:    holdRepository,
+		accountRepository: accountRepository,
+		policy:            policy,
+	}
+}
+
+// CreateHold reserves money of an account until the hold is captured,
+// released or expires.
+func (c *commandHandler) CreateHold(ctx context.Context, command CreateHoldCommand) (*domain.Hold, error) {
+	principal, account, err := c.getManagedAccount(ctx, command.AccountId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := domain.ParseMoney(command.Amount, account.Currency)
+
+	if err != nil {
+		return nil, errorresponse.NewFieldError(http.StatusBadRequest, "amount", err.Error())
+	}
+
+	if !amount.IsPositive() {
+		return nil, errorresponse.NewFieldError(http.StatusBadRequest, "amount", "amount must be greater than zero")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(c.policy.DefaultTTL)
+
+	if command.ExpiresAt != nil {
+		if !command.ExpiresAt.After(now) || command.ExpiresAt.Sub(now) > c.policy.MaxTTL {
+			return nil, ErrInvalidExpiresAt
+		}
+
+		expiresAt = *command.ExpiresAt
+	}
+
+	hold := domain.NewHold(account.Id, amount, command.Reference, principal.UserId, expiresAt)
+
+	if err := c.holdRepository.CreateHold(ctx, hold); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("Hold created", zap.String("holdId", hold.Id), zap.String("accountId", account.Id), zap.String("amount", amount.String()), zap.String("createdBy", principal.UserId))
+
+	return hold, nil
+}
+
+// CaptureHold moves the held money, or part of it, to the account with ToIBAN
+// and ends the hold.
+func (c *commandHandler) CaptureHold(ctx context.Context, command CaptureHoldCommand) (*domain.Hold, *domain.Transfer, error) {
+	hold, account, err := c.getManagedHold(ctx, command.HoldId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	amount := hold.Amount
+
+	if command.Amount != "" {
+		if amount, err = domain.ParseMoney(command.Amount, hold.Amount.Currency); err != nil {
+			return nil, nil, errorresponse.NewFieldError(http.StatusBadRequest, "amount", err.Error())
+		}
+
+		if !amount.IsPositive() {
+			return nil, nil, errorresponse.NewFieldError(http.StatusBadRequest, "amount", "amount must be greater than zero")
+		}
+	}
+
+	toAccountId, err := c.accountRepository.FindByIban(ctx, command.ToIBAN)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if toAccountId == "" {
+		return nil, nil, ErrToIbanNotFound
+	}
+
+	if toAccountId == account.Id {
+		return nil, nil, ErrCaptureToSelf
+	}
+
+	toAccount, err := c.accountRepository.GetAccount(ctx, toAccountId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if toAccount.Currency != account.Currency {
+		return nil, nil, ErrCaptureCurrency
+	}
+
+	transfer := domain.NewTransfer(account.Id, account.Iban, toAccount.Id, toAccount.Iban, amount)
+	transfer.HoldId = hold.Id
+	transfer.Reference = hold.Reference
+
+	hold, err = c.holdRepository.CaptureHold(ctx, hold.Id, transfer)
+
+	if err != nil {
+		return nil, nil, mapHoldError(err)
+	}
+
+	zap.L().Info("Hold captured", zap.String("holdId", hold.Id), zap.String("transferId", transfer.Id), zap.String("amount", amount.String()))
+
+	return hold, transfer, nil
+}
+
+// ReleaseHold ends the hold without moving any money.
+func (c *commandHandler) ReleaseHold(ctx context.Context, holdId string) (*domain.Hold, error) {
+	hold, _, err := c.getManagedHold(ctx, holdId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err = c.holdRepository.EndHold(ctx, hold.Id, domain.HoldStatusReleased)
+
+	if err != nil {
+		return nil, mapHoldError(err)
+	}
+
+	zap.L().Info("Hold released", zap.String("holdId", hold.Id))
+
+	return hold, nil
+}
+
+// ReleaseExpiredHolds ends up to limit holds past their expiry and returns how
+// many it ended. Holds captured or released in the meantime are skipped.
+func (c *commandHandler) ReleaseExpiredHolds(ctx context.Context, limit int) (int, error) {
+	holds, err := c.holdRepository.GetExpiredHolds(ctx, time.Now(), limit)
+
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+
+	for _, hold := range holds {
+		_, err := c.holdRepository.EndHold(ctx, hold.Id, domain.HoldStatusExpired)
+
+		if errors.Is(err, domain.ErrHoldNotActive) {
+			continue
+		}
+
+		if err != nil {
+			return released, err
+		}
+
+		released++
+	}
+
+	return released, nil
+}
+
+// getManagedAccount returns the account to place or settle a hold on. Holds
+// are placed by the bank, e.g. for card authorizations and pending payments,
+// so only staff allowed to manage holds may do so, even on their own
+// accounts: an owner releasing a hold could spend the money it reserves.
+func (c *commandHandler) getManagedAccount(ctx context.Context, accountId string) (*auth.Principal, *domain.Account, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !principal.Can(auth.PermissionHoldManage) {
+		return nil, nil, ErrHoldManageDenied
+	}
+
+	account, err := c.accountRepository.GetAccount(ctx, accountId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if account == nil {
+		return nil, nil, repository.ErrAccountNotFound
+	}
+
+	return principal, account, nil
+}
+
+func (c *commandHandler) getManagedHold(ctx context.Context, holdId string) (*domain.Hold, *domain.Account, error) {
+	hold, err := c.holdRepository.GetHold(ctx, holdId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, account, err := c.getManagedAccount(ctx, hold.AccountId)
+
+	if errors.Is(err, repository.ErrAccountNotFound) {
+		return nil, nil, repository.ErrHoldNotFound
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return hold, account, nil
+}
+
+func mapHoldError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrHoldNotActive), errors.Is(err, domain.ErrHoldExpired):
+		return errorresponse.NewConflictError(err.Error())
+	case errors.Is(err, domain.ErrHoldCaptureExceeded):
+		return errorresponse.NewFieldError(http.StatusUnprocessableEntity, "amount", err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,75 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"kc-bank/app/repository"
+	"kc-bank/domain"
+	"kc-bank/pkg/auth"
+)
+
+type IHoldQueryService interface {
+	GetHold(ctx context.Context, id string) (*domain.Hold, error)
+	GetAccountHolds(ctx context.Context, accountId string) ([]*domain.Hold, error)
+}
+
+type holdQueryService struct {
+	holdRepository    repository.IHoldRepository
+	accountRepository repository.IAccountRepository
+}
+
+func NewHoldQueryService(holdRepository repository.IHoldRepository, accountRepository repository.IAccountRepository) IHoldQueryService {
+	return &holdQueryService{
+		holdRepository:    holdRepository,
+		accountRepository: accountRepository,
+	}
+}
+
+// GetHold returns the hold to the owner of its account and to staff allowed
+// to read any account.
+func (q *holdQueryService) GetHold(ctx context.Context, id string) (*domain.Hold, error) {
+	hold, err := q.holdRepository.GetHold(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.checkAccountAccess(ctx, hold.AccountId); err != nil {
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return nil, repository.ErrHoldNotFound
+		}
+
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// GetAccountHolds returns every hold of the account, the newest first.
+func (q *holdQueryService) GetAccountHolds(ctx context.Context, accountId string) ([]*domain.Hold, error) {
+	if err := q.checkAccountAccess(ctx, accountId); err != nil {
+		return nil, err
+	}
+
+	return q.holdRepository.GetHoldsByAccount(ctx, accountId)
+}
+
+func (q *holdQueryService) checkAccountAccess(ctx context.Context, accountId string) error {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	account, err := q.accountRepository.GetAccount(ctx, accountId)
+
+	if err != nil {
+		return err
+	}
+
+	if account == nil || (account.UserId != principal.UserId && !principal.Can(auth.PermissionAccountReadAny)) {
+		return repository.ErrAccountNotFound
+	}
+
+	return nil
+}
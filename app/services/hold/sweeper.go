@@ -0,0 +1,56 @@
+package hold
+
+import (
+	"context"
+	"kc-bank/app/services/hold/command"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ISweeper interface {
+	Run(ctx context.Context)
+}
+
+type sweeper struct {
+	command   command.ICommandHandler
+	interval  time.Duration
+	batchSize int
+}
+
+func NewSweeper(command command.ICommandHandler, interval time.Duration, batchSize int) ISweeper {
+	return &sweeper{
+		command:   command,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run releases expired holds every interval until ctx is done, so the money
+// they reserved becomes available again without anyone releasing them.
+func (s *sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *sweeper) sweep(ctx context.Context) {
+	released, err := s.command.ReleaseExpiredHolds(ctx, s.batchSize)
+
+	if err != nil {
+		zap.L().Error("Failed to release expired holds", zap.Error(err))
+	}
+
+	if released > 0 {
+		zap.L().Info("Expired holds released", zap.Int("count", released))
+	}
+}
@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"kc-bank/app/repository"
+	"kc-bank/domain"
+	"kc-bank/infra/rabbitmq"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type IRelay interface {
+	Run(ctx context.Context)
+}
+
+type relay struct {
+	outboxRepository repository.IOutboxRepository
+	rmqService       rabbitmq.IRabbitMQService
+	workerId         string
+	leaseTTL         time.Duration
+	interval         time.Duration
+	batchSize        int
+}
+
+// NewRelay returns the relay publishing outbox events as workerId, which must
+// differ between replicas: it is whom an event's lease is given to. leaseTTL
+// must cover publishing one event and waiting for the broker's confirm.
+func NewRelay(
+	outboxRepository repository.IOutboxRepository,
+	rmqService rabbitmq.IRabbitMQService,
+	workerId string,
+	leaseTTL time.Duration,
+	interval time.Duration,
+	batchSize int,
+) IRelay {
+	return &relay{
+		outboxRepository: outboxRepository,
+		rmqService:       rmqService,
+		workerId:         workerId,
+		leaseTTL:         leaseTTL,
+		interval:         interval,
+		batchSize:        batchSize,
+	}
+}
+
+// Run publishes pending outbox events every interval until ctx is done. An
+// event is marked sent only after the broker confirms it, so events survive a
+// broker outage and are delivered at least once. Every replica runs it; an
+// event is published only by the relay that claimed it, so it is only
+// published again when that relay dies before marking it sent. Consumers
+// deduplicate those on the message id, or on the aggregate the event is
+// about.
+func (r *relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.publishPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *relay) publishPending(ctx context.Context) {
+	events, err := r.outboxRepository.GetPendingEvents(ctx, time.Now(), r.batchSize)
+
+	if err != nil {
+		zap.L().Error("Failed to load pending outbox events", zap.Error(err))
+		return
+	}
+
+	for _, candidate := range events {
+		event, err := r.outboxRepository.ClaimEvent(ctx, candidate.Id, r.workerId, r.leaseTTL)
+
+		if err != nil {
+			// Another relay claimed or sent the event in the meantime.
+			if !errors.Is(err, domain.ErrOutboxEventLeased) && !errors.Is(err, domain.ErrOutboxEventNotPending) && !errors.Is(err, repository.ErrConcurrentUpdate) {
+				zap.L().Warn("Failed to claim outbox event", zap.String("eventId", candidate.Id), zap.Error(err))
+			}
+
+			continue
+		}
+
+		if err := r.publish(ctx, event); err != nil {
+			// Later events are most likely to fail the same way; keep the
+			// order and try again on the next tick.
+			return
+		}
+	}
+}
+
+func (r *relay) publish(ctx context.Context, event *domain.OutboxEvent) error {
+	err := r.rmqService.PublishWithConfirm(event.Exchange, event.RoutingKey, event.Id, event.Payload)
+
+	if err != nil {
+		zap.L().Warn("Failed to publish outbox event", zap.String("eventId", event.Id), zap.String("aggregateId", event.AggregateId), zap.Error(err))
+
+		if err := r.outboxRepository.MarkFailedAttempt(ctx, event.Id, r.workerId, err); err != nil && !isLeaseLost(err) {
+			zap.L().Error("Failed to record outbox publish attempt", zap.String("eventId", event.Id), zap.Error(err))
+		}
+
+		return err
+	}
+
+	// If this fails the event is published again on the next tick; consumers
+	// already tolerate duplicates.
+	if err := r.outboxRepository.MarkSent(ctx, event.Id, r.workerId); err != nil {
+		if isLeaseLost(err) {
+			// The lease ran out while publishing and another relay claimed
+			// the event; it publishes the event again and marks it sent.
+			zap.L().Warn("Outbox event lease lost before marking it sent", zap.String("eventId", event.Id), zap.String("workerId", r.workerId))
+			return nil
+		}
+
+		return err
+	}
+
+	zap.L().Info("Outbox event published", zap.String("eventId", event.Id), zap.String("eventType", event.EventType), zap.String("aggregateId", event.AggregateId))
+
+	return nil
+}
+
+// isLeaseLost reports whether err means another relay claimed the event
+// after this one did.
+func isLeaseLost(err error) bool {
+	return errors.Is(err, domain.ErrOutboxLeaseLost) || errors.Is(err, repository.ErrConcurrentUpdate)
+}
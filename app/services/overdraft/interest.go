@@ -0,0 +1,79 @@
+package overdraft
+
+import (
+	"context"
+	"kc-bank/app/repository"
+	"kc-bank/domain"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type IInterestAccrual interface {
+	Run(ctx context.Context)
+}
+
+type interestAccrual struct {
+	accountRepository repository.IAccountRepository
+	rate              *domain.InterestRate
+	interval          time.Duration
+}
+
+func NewInterestAccrual(
+	accountRepository repository.IAccountRepository,
+	rate *domain.InterestRate,
+	interval time.Duration,
+) IInterestAccrual {
+	return &interestAccrual{
+		accountRepository: accountRepository,
+		rate:              rate,
+		interval:          interval,
+	}
+}
+
+// Run charges overdrawn accounts a day of interest every interval until ctx is
+// done. Interest is charged once per account and UTC day, so running more
+// often than daily only makes up for missed runs sooner.
+func (a *interestAccrual) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.accrue(ctx, time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *interestAccrual) accrue(ctx context.Context, day time.Time) {
+	accounts, err := a.accountRepository.GetOverdrawnAccounts(ctx)
+
+	if err != nil {
+		zap.L().Error("Failed to load overdrawn accounts", zap.Error(err))
+		return
+	}
+
+	charged := 0
+
+	for _, account := range accounts {
+		// A failed account is retried on the next run; it does not hold up
+		// the others.
+		accrued, err := a.accountRepository.AccrueOverdraftInterest(ctx, account.Id, day, a.rate)
+
+		if err != nil {
+			continue
+		}
+
+		if accrued {
+			charged++
+		}
+	}
+
+	if charged > 0 {
+		zap.L().Info("Overdraft interest accrued", zap.String("day", day.Format(time.DateOnly)), zap.Int("accounts", charged))
+	}
+}
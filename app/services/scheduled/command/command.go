@@ -0,0 +1,12 @@
+package command
+
+import "time"
+
+type ScheduleTransferCommand struct {
+	// Amount is decimal text in the source account's currency.
+	Amount                  string
+	FromIBAN                string
+	ToIBAN                  string
+	AllowCurrencyConversion bool
+	ExecuteAt               time.Time
+}
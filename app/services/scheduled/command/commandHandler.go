@@ -0,0 +1,288 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"kc-bank/app/repository"
+	accountCommand "kc-bank/app/services/account/command"
+	"kc-bank/domain"
+	"kc-bank/pkg/auth"
+	errorresponse "kc-bank/pkg/error_response"
+	"kc-bank/pkg/services"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidExecuteAt           = errorresponse.NewFieldError(http.StatusBadRequest, "executeAt", "executeAt must be in the future and within the scheduling horizon")
+	ErrCurrencyConversionNeeded   = errorresponse.NewFieldError(http.StatusUnprocessableEntity, "allowCurrencyConversion", "accounts hold different currencies; set allowCurrencyConversion to convert at the rate on the execution date")
+	errScheduledTransferLeaseLost = errors.New("scheduled transfer lease was taken over by another worker")
+	errUserNotActive              = errors.New("user is not active")
+)
+
+// SchedulePolicy bounds scheduling and execution. A transfer may be scheduled
+// up to MaxHorizon ahead. A worker holds a transfer for LeaseTTL while
+// executing it, and a transfer failing for transient reasons is given up
+// after MaxAttempts.
+type SchedulePolicy struct {
+	MaxHorizon  time.Duration
+	LeaseTTL    time.Duration
+	MaxAttempts int
+}
+
+type ICommandHandler interface {
+	ScheduleTransfer(ctx context.Context, command ScheduleTransferCommand) (*domain.ScheduledTransfer, error)
+	CancelScheduledTransfer(ctx context.Context, id string) (*domain.ScheduledTransfer, error)
+	ExecuteDueTransfers(ctx context.Context, workerId string, limit int) (int, error)
+}
+
+type commandHandler struct {
+	scheduledTransferRepository repository.IScheduledTransferRepository
+	accountRepository           repository.IAccountRepository
+	transferRepository          repository.ITransferRepository
+	userRepository              repository.IUserRepository
+	accountCommand              accountCommand.ICommandHandler
+	ibanService                 services.IIbanService
+	policy                      SchedulePolicy
+}
+
+func NewCommandHandler(
+	scheduledTransferRepository repository.IScheduledTransferRepository,
+	accountRepository repository.IAccountRepository,
+	transferRepository repository.ITransferRepository,
+	userRepository repository.IUserRepository,
+	accountCommand accountCommand.ICommandHandler,
+	ibanService services.IIbanService,
+	policy SchedulePolicy,
+) ICommandHandler {
+	return &commandHandler{
+		scheduledTransferRepository: scheduledTransferRepository,
+		accountRepository:           accountRepository,
+		transferRepository:          transferRepository,
+		userRepository:              userRepository,
+		accountCommand:              accountCommand,
+		ibanService:                 ibanService,
+		policy:                      policy,
+	}
+}
+
+// ScheduleTransfer stores a transfer to be executed at command.ExecuteAt. The
+// accounts are checked now; the balance only when the transfer runs.
+func (c *commandHandler) ScheduleTransfer(ctx context.Context, command ScheduleTransferCommand) (*domain.ScheduledTransfer, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !command.ExecuteAt.After(now) || command.ExecuteAt.Sub(now) > c.policy.MaxHorizon {
+		return nil, ErrInvalidExecuteAt
+	}
+
+	if err := c.validateIbans(ctx, command); err != nil {
+		return nil, err
+	}
+
+	fromAccount, err := c.getAccountByIban(ctx, command.FromIBAN, accountCommand.ErrFromIbanNotFound)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// An account the caller does not own is reported exactly like a missing
+	// one.
+	if fromAccount.UserId != principal.UserId {
+		return nil, accountCommand.ErrFromIbanNotFound
+	}
+
+	toAccount, err := c.getAccountByIban(ctx, command.ToIBAN, accountCommand.ErrToIbanNotFound)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if fromAccount.Id == toAccount.Id {
+		return nil, errorresponse.NewBadRequestError("cannot transfer to the same account")
+	}
+
+	if fromAccount.Currency != toAccount.Currency && !command.AllowCurrencyConversion {
+		return nil, ErrCurrencyConversionNeeded
+	}
+
+	amount, err := domain.ParseMoney(command.Amount, fromAccount.Currency)
+
+	if err != nil {
+		return nil, errorresponse.NewFieldError(http.StatusBadRequest, "amount", err.Error())
+	}
+
+	if !amount.IsPositive() {
+		return nil, errorresponse.NewFieldError(http.StatusBadRequest, "amount", "amount must be greater than zero")
+	}
+
+	scheduled := domain.NewScheduledTransfer(principal.UserId, fromAccount.Iban, toAccount.Iban, amount, command.AllowCurrencyConversion, command.ExecuteAt)
+
+	if err := c.scheduledTransferRepository.CreateScheduledTransfer(ctx, scheduled); err != nil {
+		return nil, err
+	}
+
+	zap.L().Info("Transfer scheduled", zap.String("scheduledTransferId", scheduled.Id), zap.String("userId", principal.UserId), zap.Time("executeAt", scheduled.ExecuteAt))
+
+	return scheduled, nil
+}
+
+// CancelScheduledTransfer stops a scheduled transfer of the caller that has
+// not run yet.
+func (c *commandHandler) CancelScheduledTransfer(ctx context.Context, id string) (*domain.ScheduledTransfer, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled, err := c.scheduledTransferRepository.UpdateScheduledTransfer(ctx, id, func(scheduled *domain.ScheduledTransfer) error {
+		if scheduled.UserId != principal.UserId {
+			return repository.ErrScheduledTransferNotFound
+		}
+
+		return scheduled.Cancel(time.Now())
+	})
+
+	switch {
+	case errors.Is(err, domain.ErrScheduledTransferNotScheduled), errors.Is(err, domain.ErrScheduledTransferLeased):
+		return nil, errorresponse.NewConflictError(err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	zap.L().Info("Scheduled transfer cancelled", zap.String("scheduledTransferId", scheduled.Id), zap.String("userId", principal.UserId))
+
+	return scheduled, nil
+}
+
+// ExecuteDueTransfers executes up to limit due transfers as workerId and
+// returns how many it ran to an outcome. Transfers another worker leases
+// first are left to it.
+func (c *commandHandler) ExecuteDueTransfers(ctx context.Context, workerId string, limit int) (int, error) {
+	due, err := c.scheduledTransferRepository.GetDueScheduledTransfers(ctx, time.Now(), limit)
+
+	if err != nil {
+		return 0, err
+	}
+
+	executed := 0
+
+	for _, candidate := range due {
+		scheduled, err := c.scheduledTransferRepository.UpdateScheduledTransfer(ctx, candidate.Id, func(scheduled *domain.ScheduledTransfer) error {
+			return scheduled.AcquireLease(workerId, time.Now(), c.policy.LeaseTTL)
+		})
+
+		if err != nil {
+			// Lost the race for the lease, or the transfer was cancelled in
+			// the meantime.
+			if !errors.Is(err, domain.ErrScheduledTransferLeased) && !errors.Is(err, domain.ErrScheduledTransferNotScheduled) &&
+				!errors.Is(err, domain.ErrScheduledTransferNotDue) && !errors.Is(err, repository.ErrConcurrentUpdate) {
+				zap.L().Warn("Failed to lease scheduled transfer", zap.String("scheduledTransferId", candidate.Id), zap.Error(err))
+			}
+
+			continue
+		}
+
+		if c.execute(ctx, workerId, scheduled) {
+			executed++
+		}
+	}
+
+	return executed, nil
+}
+
+// execute runs scheduled, which workerId holds the lease on, and records the
+// outcome. It reports whether the transfer reached a final status.
+func (c *commandHandler) execute(ctx context.Context, workerId string, scheduled *domain.ScheduledTransfer) bool {
+	transferErr := c.executeTransfer(ctx, scheduled)
+
+	recorded, err := c.scheduledTransferRepository.UpdateScheduledTransfer(ctx, scheduled.Id, func(scheduled *domain.ScheduledTransfer) error {
+		if !scheduled.HoldsLease(workerId) {
+			return errScheduledTransferLeaseLost
+		}
+
+		now := time.Now()
+
+		switch {
+		case transferErr == nil, errors.Is(transferErr, repository.ErrTransferAlreadyCompleted):
+			scheduled.MarkExecuted(now)
+		case repository.IsTransientError(transferErr) && scheduled.Attempts < c.policy.MaxAttempts:
+			scheduled.Retry(transferErr.Error(), now)
+		default:
+			scheduled.MarkFailed(transferErr.Error(), now)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		// The transfer id is fixed, so whoever runs it next finds it
+		// completed rather than moving the money again.
+		zap.L().Warn("Failed to record scheduled transfer outcome", zap.String("scheduledTransferId", scheduled.Id), zap.NamedError("transferError", transferErr), zap.Error(err))
+		return false
+	}
+
+	zap.L().Info("Scheduled transfer run", zap.String("scheduledTransferId", recorded.Id), zap.String("transferId", recorded.TransferId), zap.String("status", string(recorded.Status)), zap.Int("attempts", recorded.Attempts), zap.String("failureReason", recorded.FailureReason))
+
+	return recorded.Status != domain.ScheduledTransferStatusScheduled
+}
+
+func (c *commandHandler) executeTransfer(ctx context.Context, scheduled *domain.ScheduledTransfer) error {
+	// An earlier attempt may have moved the money and died before recording
+	// it; the balance check below would then fail on money already sent.
+	transfer, err := c.transferRepository.GetTransfer(ctx, scheduled.TransferId)
+
+	if err == nil && transfer.Status == domain.TransferStatusCompleted {
+		return repository.ErrTransferAlreadyCompleted
+	}
+
+	if err != nil && !errors.Is(err, repository.ErrTransferNotFound) {
+		return err
+	}
+
+	user, err := c.userRepository.GetUser(ctx, scheduled.UserId)
+
+	if err != nil {
+		return err
+	}
+
+	if !user.IsActive() {
+		return errUserNotActive
+	}
+
+	_, err = c.accountCommand.ExecuteTransfer(ctx, accountCommand.TransferMoneyCommand{
+		Amount:                  scheduled.Amount.String(),
+		FromIBAN:                scheduled.FromIban,
+		ToIBAN:                  scheduled.ToIban,
+		AllowCurrencyConversion: scheduled.AllowCurrencyConversion,
+		UserId:                  scheduled.UserId,
+		TransferId:              scheduled.TransferId,
+	})
+
+	return err
+}
+
+func (c *commandHandler) validateIbans(ctx context.Context, command ScheduleTransferCommand) error {
+	return accountCommand.ValidateTransferIbans(ctx, c.ibanService, c.accountRepository, command.FromIBAN, command.ToIBAN)
+}
+
+func (c *commandHandler) getAccountByIban(ctx context.Context, iban string, notFound error) (*domain.Account, error) {
+	accountId, err := c.accountRepository.FindByIban(ctx, iban)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if accountId == "" {
+		return nil, notFound
+	}
+
+	return c.accountRepository.GetAccount(ctx, accountId)
+}
@@ -0,0 +1,57 @@
+package query
+
+import (
+	"context"
+	"kc-bank/app/repository"
+	"kc-bank/domain"
+	"kc-bank/pkg/auth"
+)
+
+type IScheduledTransferQueryService interface {
+	GetScheduledTransfers(ctx context.Context) ([]*domain.ScheduledTransfer, error)
+	GetScheduledTransfer(ctx context.Context, id string) (*domain.ScheduledTransfer, error)
+}
+
+type scheduledTransferQueryService struct {
+	scheduledTransferRepository repository.IScheduledTransferRepository
+}
+
+func NewScheduledTransferQueryService(scheduledTransferRepository repository.IScheduledTransferRepository) IScheduledTransferQueryService {
+	return &scheduledTransferQueryService{
+		scheduledTransferRepository: scheduledTransferRepository,
+	}
+}
+
+// GetScheduledTransfers returns the caller's scheduled transfers, whatever
+// their status, the soonest first.
+func (q *scheduledTransferQueryService) GetScheduledTransfers(ctx context.Context) ([]*domain.ScheduledTransfer, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return q.scheduledTransferRepository.GetScheduledTransfersByUser(ctx, principal.UserId)
+}
+
+// GetScheduledTransfer returns the scheduled transfer only to the user who
+// scheduled it.
+func (q *scheduledTransferQueryService) GetScheduledTransfer(ctx context.Context, id string) (*domain.ScheduledTransfer, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled, err := q.scheduledTransferRepository.GetScheduledTransfer(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if scheduled.UserId != principal.UserId {
+		return nil, repository.ErrScheduledTransferNotFound
+	}
+
+	return scheduled, nil
+}
@@ -0,0 +1,61 @@
+package scheduled
+
+import (
+	"context"
+	"kc-bank/app/services/scheduled/command"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type IScheduler interface {
+	Run(ctx context.Context)
+}
+
+type scheduler struct {
+	command   command.ICommandHandler
+	workerId  string
+	interval  time.Duration
+	batchSize int
+}
+
+// NewScheduler returns the worker executing due scheduled transfers as
+// workerId, which must differ between replicas: it is whom a transfer's lease
+// is given to.
+func NewScheduler(command command.ICommandHandler, workerId string, interval time.Duration, batchSize int) IScheduler {
+	return &scheduler{
+		command:   command,
+		workerId:  workerId,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run executes due scheduled transfers every interval until ctx is done. Every
+// replica runs it; leases keep them from executing the same transfer.
+func (s *scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.executeDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *scheduler) executeDue(ctx context.Context) {
+	executed, err := s.command.ExecuteDueTransfers(ctx, s.workerId, s.batchSize)
+
+	if err != nil {
+		zap.L().Error("Failed to execute scheduled transfers", zap.Error(err))
+	}
+
+	if executed > 0 {
+		zap.L().Info("Scheduled transfers executed", zap.Int("count", executed), zap.String("workerId", s.workerId))
+	}
+}
@@ -7,4 +7,42 @@ type Command struct {
 	Email     string
 	Password  string
 	Age       int32
+	// NationalId is the TC Kimlik No.
+	NationalId string
+	BirthYear  int32
+}
+
+type ChangeRoleCommand struct {
+	UserId string
+	Role   string
+}
+
+// UpdateProfileCommand changes the fields that are set and keeps the others.
+type UpdateProfileCommand struct {
+	UserId    string
+	FirstName *string
+	LastName  *string
+	Email     *string
+}
+
+// VerifyIdentityCommand records the national id and birth year of a user
+// registered before they were captured.
+type VerifyIdentityCommand struct {
+	UserId     string
+	NationalId string
+	BirthYear  int32
+}
+
+type ChangePasswordCommand struct {
+	UserId          string
+	CurrentPassword string
+	NewPassword     string
+}
+
+type DeactivateCommand struct {
+	UserId string
+}
+
+type DeleteCommand struct {
+	UserId string
 }
@@ -2,33 +2,80 @@ package command
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"kc-bank/app/repository"
 	"kc-bank/domain"
+	"kc-bank/pkg/auth"
+	errorresponse "kc-bank/pkg/error_response"
 	"kc-bank/pkg/services"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 type ICommandHandler interface {
 	Save(ctx context.Context, command Command) error
+	ChangeRole(ctx context.Context, command ChangeRoleCommand) error
+	PromoteToAdmin(ctx context.Context, email string) error
+	UpdateProfile(ctx context.Context, command UpdateProfileCommand) error
+	VerifyIdentity(ctx context.Context, command VerifyIdentityCommand) error
+	ChangePassword(ctx context.Context, command ChangePasswordCommand) error
+	Deactivate(ctx context.Context, command DeactivateCommand) error
+	Delete(ctx context.Context, command DeleteCommand) error
 }
 
+var (
+	ErrWrongCurrentPassword = errorresponse.NewFieldError(http.StatusUnprocessableEntity, "currentPassword", "current password is incorrect")
+	ErrUserNotActive        = errorresponse.NewConflictError("user is not active")
+	ErrIdentityVerified     = errorresponse.NewConflictError("identity is already verified and can not be changed")
+	ErrNameNotVerified      = errorresponse.NewUnprocessableEntityError("name does not match the national identity registry")
+)
+
+// userEntityType is the entity type of audit records about users.
+const userEntityType = "user"
+
 type commandHandler struct {
-	userRepository  repository.IUserRepository
-	passwordService services.IPasswordService
+	userRepository         repository.IUserRepository
+	accountRepository      repository.IAccountRepository
+	refreshTokenRepository repository.IRefreshTokenRepository
+	passwordService        services.IPasswordService
+	emailNormalizer        services.EmailNormalizer
+	identityVerifier       services.IIdentityVerifier
 }
 
-func NewCommandHandler(userRepository repository.IUserRepository, passwordService services.IPasswordService) ICommandHandler {
+func NewCommandHandler(
+	userRepository repository.IUserRepository,
+	accountRepository repository.IAccountRepository,
+	refreshTokenRepository repository.IRefreshTokenRepository,
+	passwordService services.IPasswordService,
+	emailNormalizer services.EmailNormalizer,
+	identityVerifier services.IIdentityVerifier,
+) ICommandHandler {
 	return &commandHandler{
-		userRepository:  userRepository,
-		passwordService: passwordService,
+		userRepository:         userRepository,
+		accountRepository:      accountRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		passwordService:        passwordService,
+		emailNormalizer:        emailNormalizer,
+		identityVerifier:       identityVerifier,
 	}
 }
 
 func (c *commandHandler) Save(ctx context.Context, command Command) error {
-	// TODO: add national id check
+	err := c.verifyIdentity(ctx, services.IdentityClaim{
+		NationalId: command.NationalId,
+		FirstName:  command.FirstName,
+		LastName:   command.LastName,
+		BirthYear:  command.BirthYear,
+	})
+
+	if err != nil {
+		return err
+	}
 
 	hashedPassword, err := c.passwordService.HashPassword(command.Password)
 
@@ -47,15 +94,348 @@ func (c *commandHandler) Save(ctx context.Context, command Command) error {
 	return nil
 }
 
+func (c *commandHandler) ChangeRole(ctx context.Context, command ChangeRoleCommand) error {
+	role := domain.Role(command.Role)
+
+	if !role.IsValid() {
+		return errorresponse.NewBadRequestError(fmt.Sprintf("unknown role %q", command.Role))
+	}
+
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	// An admin demoting themselves could leave nobody able to manage roles.
+	if command.UserId == principal.UserId {
+		return errorresponse.NewForbiddenError("you can not change your own role")
+	}
+
+	user, err := c.userRepository.GetUser(ctx, command.UserId)
+
+	if err != nil {
+		return err
+	}
+
+	audit := domain.NewAuditRecord(userEntityType, user.Id, domain.AuditUserRoleChanged, principal.UserId)
+	audit.Record("role", string(user.EffectiveRole()), string(role))
+
+	user.Role = role
+	user.UpdatedAt = time.Now()
+
+	err = c.userRepository.UpdateUser(ctx, user, audit)
+
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("User role changed", zap.String("userId", user.Id), zap.String("role", string(role)), zap.String("changedBy", principal.UserId))
+
+	return nil
+}
+
+// PromoteToAdmin gives the user with email the admin role. It bootstraps the
+// first admin, who can then assign roles through the API.
+func (c *commandHandler) PromoteToAdmin(ctx context.Context, email string) error {
+	user, err := c.userRepository.FindByEmail(ctx, c.emailNormalizer.Canonical(email))
+
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return repository.ErrUserNotFound
+	}
+
+	if user.Role == domain.RoleAdmin {
+		return nil
+	}
+
+	audit := domain.NewAuditRecord(userEntityType, user.Id, domain.AuditUserRoleChanged, domain.AuditSystemActor)
+	audit.Record("role", string(user.EffectiveRole()), string(domain.RoleAdmin))
+
+	user.Role = domain.RoleAdmin
+	user.UpdatedAt = time.Now()
+
+	return c.userRepository.UpdateUser(ctx, user, audit)
+}
+
+// UpdateProfile changes the names and email of a user. Users edit their own
+// profile; staff allowed to manage users edit anyone's. Names of a user with a
+// verified identity only change once the registry confirms them.
+func (c *commandHandler) UpdateProfile(ctx context.Context, command UpdateProfileCommand) error {
+	principal, user, err := c.getManagedUser(ctx, command.UserId)
+
+	if err != nil {
+		return err
+	}
+
+	if !user.IsActive() {
+		return ErrUserNotActive
+	}
+
+	audit := domain.NewAuditRecord(userEntityType, user.Id, domain.AuditUserUpdated, principal.UserId)
+
+	if command.FirstName != nil {
+		audit.Record("firstName", user.FirstName, *command.FirstName)
+		user.FirstName = *command.FirstName
+	}
+
+	if command.LastName != nil {
+		audit.Record("lastName", user.LastName, *command.LastName)
+		user.LastName = *command.LastName
+	}
+
+	// Registration checked the name against the national id; a new name has
+	// to pass the same check.
+	_, firstNameChanged := audit.Changes["firstName"]
+	_, lastNameChanged := audit.Changes["lastName"]
+
+	if (firstNameChanged || lastNameChanged) && user.NationalId != "" {
+		err := c.identityVerifier.Verify(ctx, services.IdentityClaim{
+			NationalId: user.NationalId,
+			FirstName:  user.FirstName,
+			LastName:   user.LastName,
+			BirthYear:  user.BirthYear,
+		})
+
+		if errors.Is(err, services.ErrIdentityNotVerified) {
+			return ErrNameNotVerified
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if command.Email != nil {
+		email := c.emailNormalizer.Normalize(*command.Email)
+
+		audit.Record("email", user.Email, email)
+		user.Email = email
+		user.CanonicalEmail = c.emailNormalizer.Canonical(email)
+	}
+
+	if !audit.HasChanges() {
+		return nil
+	}
+
+	user.UpdatedAt = time.Now()
+
+	return c.userRepository.UpdateUser(ctx, user, audit)
+}
+
+// VerifyIdentity records the national id and birth year of a user registered
+// before they were captured, once the registry confirms them against the
+// user's name. A verified identity is not changed this way.
+func (c *commandHandler) VerifyIdentity(ctx context.Context, command VerifyIdentityCommand) error {
+	principal, user, err := c.getManagedUser(ctx, command.UserId)
+
+	if err != nil {
+		return err
+	}
+
+	if !user.IsActive() {
+		return ErrUserNotActive
+	}
+
+	if user.NationalId != "" && user.BirthYear > 0 {
+		return ErrIdentityVerified
+	}
+
+	err = c.verifyIdentity(ctx, services.IdentityClaim{
+		NationalId: command.NationalId,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		BirthYear:  command.BirthYear,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	audit := domain.NewAuditRecord(userEntityType, user.Id, domain.AuditUserIdentityVerified, principal.UserId)
+	audit.RecordRedacted("nationalId")
+	audit.Record("birthYear", strconv.Itoa(int(user.BirthYear)), strconv.Itoa(int(command.BirthYear)))
+
+	user.NationalId = command.NationalId
+	user.BirthYear = command.BirthYear
+	user.UpdatedAt = time.Now()
+
+	return c.userRepository.UpdateUser(ctx, user, audit)
+}
+
+// verifyIdentity checks the national id and confirms claim with the registry,
+// reporting a mismatch against the nationalId field.
+func (c *commandHandler) verifyIdentity(ctx context.Context, claim services.IdentityClaim) error {
+	if err := domain.ValidateNationalId(claim.NationalId); err != nil {
+		return errorresponse.NewFieldError(http.StatusBadRequest, "nationalId", err.Error())
+	}
+
+	err := c.identityVerifier.Verify(ctx, claim)
+
+	if errors.Is(err, services.ErrIdentityNotVerified) {
+		return errorresponse.NewFieldError(http.StatusUnprocessableEntity, "nationalId", err.Error())
+	}
+
+	return err
+}
+
+// ChangePassword replaces the password of the caller, who must know the
+// current one, and logs every session out.
+func (c *commandHandler) ChangePassword(ctx context.Context, command ChangePasswordCommand) error {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	// Nobody may set another user's password, whatever their role.
+	if command.UserId != principal.UserId {
+		return errorresponse.NewForbiddenError("you can only change your own password")
+	}
+
+	user, err := c.getUser(ctx, command.UserId)
+
+	if err != nil {
+		return err
+	}
+
+	if !c.passwordService.CheckPasswordHash(command.CurrentPassword, user.Password) {
+		return ErrWrongCurrentPassword
+	}
+
+	hashedPassword, err := c.passwordService.HashPassword(command.NewPassword)
+
+	if err != nil {
+		return fmt.Errorf("password could not hash: %s", err.Error())
+	}
+
+	audit := domain.NewAuditRecord(userEntityType, user.Id, domain.AuditUserPasswordChanged, principal.UserId)
+	audit.RecordRedacted("password")
+
+	user.Password = hashedPassword
+	user.UpdatedAt = time.Now()
+
+	if err := c.userRepository.UpdateUser(ctx, user, audit); err != nil {
+		return err
+	}
+
+	return c.refreshTokenRepository.RevokeUserRefreshTokens(ctx, user.Id)
+}
+
+// Deactivate stops a user from logging in and opening accounts. The user is
+// kept and can be told apart from a deleted one.
+func (c *commandHandler) Deactivate(ctx context.Context, command DeactivateCommand) error {
+	principal, user, err := c.getManagedUser(ctx, command.UserId)
+
+	if err != nil {
+		return err
+	}
+
+	if user.Status == domain.UserStatusDeactivated {
+		return ErrUserNotActive
+	}
+
+	return c.closeUser(ctx, principal, user, domain.UserStatusDeactivated, domain.AuditUserDeactivated)
+}
+
+// Delete soft-deletes a user: the document stays for the records, but the
+// user is treated as missing from then on.
+func (c *commandHandler) Delete(ctx context.Context, command DeleteCommand) error {
+	principal, user, err := c.getManagedUser(ctx, command.UserId)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.DeletedAt = &now
+
+	return c.closeUser(ctx, principal, user, domain.UserStatusDeleted, domain.AuditUserDeleted)
+}
+
+// closeUser moves user to status once none of their accounts holds money,
+// and revokes their sessions. The check and the change are one transaction.
+func (c *commandHandler) closeUser(ctx context.Context, principal *auth.Principal, user *domain.User, status domain.UserStatus, action domain.AuditAction) error {
+	accounts, err := c.accountRepository.GetAccountsByUser(ctx, user.Id)
+
+	if err != nil {
+		return err
+	}
+
+	accountIds := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		accountIds = append(accountIds, account.Id)
+	}
+
+	audit := domain.NewAuditRecord(userEntityType, user.Id, action, principal.UserId)
+	audit.Record("status", string(user.Status), string(status))
+
+	user.Status = status
+	user.UpdatedAt = time.Now()
+
+	if err := c.userRepository.CloseUser(ctx, user, accountIds, audit); err != nil {
+		return err
+	}
+
+	zap.L().Info("User closed", zap.String("userId", user.Id), zap.String("status", string(status)), zap.String("closedBy", principal.UserId))
+
+	return c.refreshTokenRepository.RevokeUserRefreshTokens(ctx, user.Id)
+}
+
+// getManagedUser returns the user the caller may change: themselves, or
+// anyone for staff allowed to manage users. Others get not found, as on reads.
+func (c *commandHandler) getManagedUser(ctx context.Context, userId string) (*auth.Principal, *domain.User, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if userId != principal.UserId && !principal.Can(auth.PermissionUserManage) {
+		return nil, nil, repository.ErrUserNotFound
+	}
+
+	user, err := c.getUser(ctx, userId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return principal, user, nil
+}
+
+// getUser treats deleted users as missing.
+func (c *commandHandler) getUser(ctx context.Context, userId string) (*domain.User, error) {
+	user, err := c.userRepository.GetUser(ctx, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status == domain.UserStatusDeleted {
+		return nil, repository.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
 func (c *commandHandler) BuildEntity(command Command, hashedPassword string) *domain.User {
 	return &domain.User{
-		Id:        uuid.New().String(),
-		FirstName: command.FirstName,
-		LastName:  command.LastName,
-		Email:     command.Email,
-		Password:  hashedPassword,
-		Age:       command.Age,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Id:             uuid.New().String(),
+		FirstName:      command.FirstName,
+		LastName:       command.LastName,
+		Email:          c.emailNormalizer.Normalize(command.Email),
+		CanonicalEmail: c.emailNormalizer.Canonical(command.Email),
+		Password:       hashedPassword,
+		Age:            command.Age,
+		BirthYear:      command.BirthYear,
+		NationalId:     command.NationalId,
+		Role:           domain.RoleCustomer,
+		Status:         domain.UserStatusActive,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 }
@@ -0,0 +1,171 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"kc-bank/app/repository"
+	"kc-bank/domain"
+	"kc-bank/pkg/auth"
+	errorresponse "kc-bank/pkg/error_response"
+	"kc-bank/pkg/services"
+	"net/http"
+	"testing"
+)
+
+// users is a user repository holding a single user in memory.
+type users struct {
+	repository.IUserRepository
+	user  *domain.User
+	audit *domain.AuditRecord
+}
+
+func (r *users) GetUser(_ context.Context, id string) (*domain.User, error) {
+	if r.user == nil || r.user.Id != id {
+		return nil, repository.ErrUserNotFound
+	}
+
+	user := *r.user
+	return &user, nil
+}
+
+func (r *users) UpdateUser(_ context.Context, user *domain.User, audit *domain.AuditRecord) error {
+	r.user = user
+	r.audit = audit
+	return nil
+}
+
+// registry confirms only the claims it holds.
+type registry map[services.IdentityClaim]bool
+
+func (r registry) Verify(_ context.Context, claim services.IdentityClaim) error {
+	if !r[claim] {
+		return services.ErrIdentityNotVerified
+	}
+
+	return nil
+}
+
+func TestVerifyIdentity(t *testing.T) {
+	confirmed := registry{
+		{NationalId: "10000000146", FirstName: "Ayse", LastName: "Yilmaz", BirthYear: 1990}: true,
+	}
+
+	tests := []struct {
+		name       string
+		user       domain.User
+		command    VerifyIdentityCommand
+		wantStatus int32
+		wantErr    error
+	}{
+		{
+			name:    "legacy user confirmed by the registry",
+			user:    domain.User{Id: "u1", FirstName: "Ayse", LastName: "Yilmaz", Age: 40},
+			command: VerifyIdentityCommand{UserId: "u1", NationalId: "10000000146", BirthYear: 1990},
+		},
+		{
+			name:       "registry disagrees",
+			user:       domain.User{Id: "u1", FirstName: "Ayse", LastName: "Yilmaz"},
+			command:    VerifyIdentityCommand{UserId: "u1", NationalId: "10000000146", BirthYear: 1991},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid national id",
+			user:       domain.User{Id: "u1", FirstName: "Ayse", LastName: "Yilmaz"},
+			command:    VerifyIdentityCommand{UserId: "u1", NationalId: "10000000147", BirthYear: 1990},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "already verified",
+			user:    domain.User{Id: "u1", FirstName: "Ayse", LastName: "Yilmaz", NationalId: "10000000146", BirthYear: 1990},
+			command: VerifyIdentityCommand{UserId: "u1", NationalId: "12345678950", BirthYear: 1990},
+			wantErr: ErrIdentityVerified,
+		},
+		{
+			name:    "inactive user",
+			user:    domain.User{Id: "u1", FirstName: "Ayse", LastName: "Yilmaz", Status: domain.UserStatusDeactivated},
+			command: VerifyIdentityCommand{UserId: "u1", NationalId: "10000000146", BirthYear: 1990},
+			wantErr: ErrUserNotActive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := tt.user
+			userRepository := &users{user: &stored}
+			handler := &commandHandler{userRepository: userRepository, identityVerifier: confirmed}
+			ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserId: "u1", Role: domain.RoleCustomer})
+
+			err := handler.VerifyIdentity(ctx, tt.command)
+
+			if tt.wantStatus != 0 {
+				var response *errorresponse.ErrorResponse
+				if !errors.As(err, &response) || response.Status != tt.wantStatus {
+					t.Fatalf("VerifyIdentity() error = %v, want status %d", err, tt.wantStatus)
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyIdentity() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				if userRepository.audit != nil {
+					t.Errorf("refused VerifyIdentity() stored the user")
+				}
+				return
+			}
+
+			if userRepository.user.NationalId != tt.command.NationalId || userRepository.user.BirthYear != tt.command.BirthYear {
+				t.Errorf("VerifyIdentity() stored %+v, want the verified national id and birth year", userRepository.user)
+			}
+
+			if userRepository.audit == nil || userRepository.audit.Action != domain.AuditUserIdentityVerified {
+				t.Errorf("VerifyIdentity() audit = %+v, want %s", userRepository.audit, domain.AuditUserIdentityVerified)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileVerifiesNewNames(t *testing.T) {
+	confirmed := registry{
+		{NationalId: "10000000146", FirstName: "Ayse", LastName: "Kaya", BirthYear: 1990}: true,
+	}
+
+	verified := domain.User{Id: "u1", FirstName: "Ayse", LastName: "Yilmaz", NationalId: "10000000146", BirthYear: 1990}
+	name := func(value string) *string { return &value }
+
+	tests := []struct {
+		name         string
+		user         domain.User
+		command      UpdateProfileCommand
+		wantErr      error
+		wantLastName string
+	}{
+		{name: "name confirmed by the registry", user: verified, command: UpdateProfileCommand{UserId: "u1", LastName: name("Kaya")}, wantLastName: "Kaya"},
+		{name: "name the registry does not confirm", user: verified, command: UpdateProfileCommand{UserId: "u1", LastName: name("Demir")}, wantErr: ErrNameNotVerified, wantLastName: "Yilmaz"},
+		{name: "unchanged name is not checked", user: verified, command: UpdateProfileCommand{UserId: "u1", LastName: name("Yilmaz")}, wantLastName: "Yilmaz"},
+		{
+			name:         "user without a verified identity",
+			user:         domain.User{Id: "u1", FirstName: "Ayse", LastName: "Yilmaz"},
+			command:      UpdateProfileCommand{UserId: "u1", LastName: name("Demir")},
+			wantLastName: "Demir",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := tt.user
+			userRepository := &users{user: &stored}
+			handler := &commandHandler{userRepository: userRepository, identityVerifier: confirmed}
+			ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserId: "u1", Role: domain.RoleCustomer})
+
+			err := handler.UpdateProfile(ctx, tt.command)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateProfile() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if userRepository.user.LastName != tt.wantLastName {
+				t.Errorf("stored last name = %q, want %q", userRepository.user.LastName, tt.wantLastName)
+			}
+		})
+	}
+}
@@ -2,9 +2,9 @@ package query
 
 import (
 	"context"
-	"errors"
 	"kc-bank/app/repository"
 	"kc-bank/domain"
+	"kc-bank/pkg/auth"
 )
 
 type IUserQueryService interface {
@@ -22,15 +22,27 @@ func NewUserQueryService(userRepository repository.IUserRepository) IUserQuerySe
 	}
 }
 
+// GetUser returns the caller's own user, or any user to staff allowed to read
+// them. Others get the same not found error as for a missing user.
 func (u *userQueryService) GetUser(ctx context.Context, Id string) (*domain.User, error) {
+	principal, err := auth.RequirePrincipal(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if Id != principal.UserId && !principal.Can(auth.PermissionUserReadAny) {
+		return nil, repository.ErrUserNotFound
+	}
+
 	user, err := u.userRepository.GetUser(ctx, Id)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if user == nil {
-		return nil, errors.New("user not found")
+	if user == nil || user.Status == domain.UserStatusDeleted {
+		return nil, repository.ErrUserNotFound
 	}
 
 	return user, nil
@@ -1,15 +1,131 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"time"
 )
 
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "ACTIVE"
+	// AccountStatusFrozenDebit lets money in but not out.
+	AccountStatusFrozenDebit AccountStatus = "FROZEN_DEBIT"
+	// AccountStatusFrozenAll stops every movement.
+	AccountStatusFrozenAll AccountStatus = "FROZEN_ALL"
+	// AccountStatusDormant marks an account unused for a long time. It still
+	// receives money, but must be reactivated before money can leave it.
+	AccountStatusDormant AccountStatus = "DORMANT"
+	AccountStatusClosed  AccountStatus = "CLOSED"
+)
+
+var (
+	ErrInvalidAccountStatus           = errors.New("unknown account status")
+	ErrInvalidAccountStatusTransition = errors.New("account status transition is not allowed")
+	ErrAccountHasBalance              = errors.New("account balance must be zero to close it")
+)
+
+// accountStatusTransitions lists the statuses each status may move to. Frozen
+// accounts must be unfrozen before they can be closed, so their money can not
+// be swept out while the freeze is in place; closed is final.
+var accountStatusTransitions = map[AccountStatus][]AccountStatus{
+	AccountStatusActive:      {AccountStatusFrozenDebit, AccountStatusFrozenAll, AccountStatusDormant, AccountStatusClosed},
+	AccountStatusFrozenDebit: {AccountStatusActive, AccountStatusFrozenAll},
+	AccountStatusFrozenAll:   {AccountStatusActive, AccountStatusFrozenDebit},
+	AccountStatusDormant:     {AccountStatusActive, AccountStatusFrozenDebit, AccountStatusFrozenAll, AccountStatusClosed},
+	AccountStatusClosed:      {},
+}
+
+func (s AccountStatus) IsValid() bool {
+	_, ok := accountStatusTransitions[s]
+	return ok
+}
+
+// Account is a customer account. StatusReason explains the last status
+// change, e.g. why the account was frozen. OverdraftLimit is how far below
+// zero the balance may go, none when nil; OverdraftBreachedAt is set while the
+// balance is below even that. HeldAmount is the sum of the account's active
+// holds, none when nil.
 type Account struct {
-	Id        string    `bson:"_id"`
-	Currency  string    `bson:"currency" validate:"required"`
-	Iban      string    `bson:"iban" validate:"required"`
-	Balance   float64   `bson:"balance" validate:"required"`
-	CreatedAt time.Time `bson:"createdAt"`
-	UpdatedAt time.Time `bson:"updatedAt"`
-	UserId    string    `bson:"userId"`
+	Id                  string        `bson:"_id"`
+	Currency            string        `bson:"currency" validate:"required"`
+	Iban                string        `bson:"iban" validate:"required"`
+	Balance             Money         `bson:"balance" validate:"required"`
+	CreatedAt           time.Time     `bson:"createdAt"`
+	UpdatedAt           time.Time     `bson:"updatedAt"`
+	UserId              string        `bson:"userId"`
+	Status              AccountStatus `bson:"status"`
+	StatusReason        string        `bson:"statusReason"`
+	StatusChangedAt     *time.Time    `bson:"statusChangedAt"`
+	OverdraftLimit      *Money        `bson:"overdraftLimit"`
+	OverdraftBreachedAt *time.Time    `bson:"overdraftBreachedAt"`
+	HeldAmount          *Money        `bson:"heldAmount"`
+}
+
+// EffectiveStatus treats accounts stored before statuses existed as active.
+func (a *Account) EffectiveStatus() AccountStatus {
+	if a.Status == "" {
+		return AccountStatusActive
+	}
+
+	return a.Status
+}
+
+// CanDebit reports whether money may leave the account.
+func (a *Account) CanDebit() bool {
+	return a.EffectiveStatus() == AccountStatusActive
+}
+
+// CanCredit reports whether money may enter the account.
+func (a *Account) CanCredit() bool {
+	switch a.EffectiveStatus() {
+	case AccountStatusActive, AccountStatusFrozenDebit, AccountStatusDormant:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanChangeStatus reports, as an error, why the account can not move to
+// status; it does not look at the balance.
+func (a *Account) CanChangeStatus(status AccountStatus) error {
+	if !status.IsValid() {
+		return fmt.Errorf("%w: %q", ErrInvalidAccountStatus, status)
+	}
+
+	current := a.EffectiveStatus()
+
+	for _, next := range accountStatusTransitions[current] {
+		if next == status {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s to %s", ErrInvalidAccountStatusTransition, current, status)
+}
+
+// ChangeStatus moves the account to status if the transition is allowed. An
+// account can only be closed once its balance is zero and nothing is on hold.
+func (a *Account) ChangeStatus(status AccountStatus, reason string) error {
+	if err := a.CanChangeStatus(status); err != nil {
+		return err
+	}
+
+	if status == AccountStatusClosed && !a.Balance.IsZero() {
+		return ErrAccountHasBalance
+	}
+
+	if status == AccountStatusClosed && a.HasHolds() {
+		return ErrAccountHasHolds
+	}
+
+	now := time.Now()
+
+	a.Status = status
+	a.StatusReason = reason
+	a.StatusChangedAt = &now
+	a.UpdatedAt = now
+
+	return nil
 }
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAccountCanChangeStatus(t *testing.T) {
+	allowed := map[AccountStatus][]AccountStatus{
+		AccountStatusActive:      {AccountStatusFrozenDebit, AccountStatusFrozenAll, AccountStatusDormant, AccountStatusClosed},
+		AccountStatusFrozenDebit: {AccountStatusActive, AccountStatusFrozenAll},
+		AccountStatusFrozenAll:   {AccountStatusActive, AccountStatusFrozenDebit},
+		AccountStatusDormant:     {AccountStatusActive, AccountStatusFrozenDebit, AccountStatusFrozenAll, AccountStatusClosed},
+		AccountStatusClosed:      {},
+	}
+
+	statuses := []AccountStatus{AccountStatusActive, AccountStatusFrozenDebit, AccountStatusFrozenAll, AccountStatusDormant, AccountStatusClosed}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			wantAllowed := false
+			for _, status := range allowed[from] {
+				wantAllowed = wantAllowed || status == to
+			}
+
+			t.Run(string(from)+" to "+string(to), func(t *testing.T) {
+				account := &Account{Status: from}
+				err := account.CanChangeStatus(to)
+
+				if wantAllowed && err != nil {
+					t.Errorf("CanChangeStatus() error = %v, want allowed", err)
+				}
+
+				if !wantAllowed && !errors.Is(err, ErrInvalidAccountStatusTransition) {
+					t.Errorf("CanChangeStatus() error = %v, want %v", err, ErrInvalidAccountStatusTransition)
+				}
+			})
+		}
+	}
+}
+
+func TestAccountCanChangeStatusRejectsUnknownStatus(t *testing.T) {
+	account := &Account{Status: AccountStatusActive}
+
+	for _, status := range []AccountStatus{"", "SUSPENDED", "active"} {
+		if err := account.CanChangeStatus(status); !errors.Is(err, ErrInvalidAccountStatus) {
+			t.Errorf("CanChangeStatus(%q) error = %v, want %v", status, err, ErrInvalidAccountStatus)
+		}
+	}
+}
+
+func TestAccountChangeStatus(t *testing.T) {
+	held := NewMoney(100, "TRY")
+
+	tests := []struct {
+		name    string
+		account Account
+		status  AccountStatus
+		wantErr error
+	}{
+		{name: "freeze", account: Account{Status: AccountStatusActive, Balance: NewMoney(500, "TRY")}, status: AccountStatusFrozenAll},
+		{name: "legacy account counts as active", account: Account{Balance: NewMoney(500, "TRY")}, status: AccountStatusDormant},
+		{name: "close empty account", account: Account{Status: AccountStatusActive, Balance: ZeroMoney("TRY")}, status: AccountStatusClosed},
+		{name: "close dormant empty account", account: Account{Status: AccountStatusDormant, Balance: ZeroMoney("TRY")}, status: AccountStatusClosed},
+		{name: "close account with money", account: Account{Status: AccountStatusActive, Balance: NewMoney(1, "TRY")}, status: AccountStatusClosed, wantErr: ErrAccountHasBalance},
+		{name: "close overdrawn account", account: Account{Status: AccountStatusActive, Balance: NewMoney(-1, "TRY")}, status: AccountStatusClosed, wantErr: ErrAccountHasBalance},
+		{name: "close account with holds", account: Account{Status: AccountStatusActive, Balance: ZeroMoney("TRY"), HeldAmount: &held}, status: AccountStatusClosed, wantErr: ErrAccountHasHolds},
+		{name: "close frozen account", account: Account{Status: AccountStatusFrozenDebit, Balance: ZeroMoney("TRY")}, status: AccountStatusClosed, wantErr: ErrInvalidAccountStatusTransition},
+		{name: "reopen closed account", account: Account{Status: AccountStatusClosed, Balance: ZeroMoney("TRY")}, status: AccountStatusActive, wantErr: ErrInvalidAccountStatusTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := tt.account
+			previous := account.EffectiveStatus()
+
+			err := account.ChangeStatus(tt.status, "reason")
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChangeStatus() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				if account.EffectiveStatus() != previous || account.StatusChangedAt != nil {
+					t.Errorf("refused ChangeStatus() changed the account to %s", account.Status)
+				}
+
+				return
+			}
+
+			if account.Status != tt.status || account.StatusReason != "reason" || account.StatusChangedAt == nil {
+				t.Errorf("ChangeStatus() left %+v, want status %s with its reason", account, tt.status)
+			}
+		})
+	}
+}
+
+func TestAccountMovementsByStatus(t *testing.T) {
+	tests := []struct {
+		status     AccountStatus
+		wantDebit  bool
+		wantCredit bool
+	}{
+		{status: "", wantDebit: true, wantCredit: true},
+		{status: AccountStatusActive, wantDebit: true, wantCredit: true},
+		{status: AccountStatusFrozenDebit, wantDebit: false, wantCredit: true},
+		{status: AccountStatusFrozenAll, wantDebit: false, wantCredit: false},
+		{status: AccountStatusDormant, wantDebit: false, wantCredit: true},
+		{status: AccountStatusClosed, wantDebit: false, wantCredit: false},
+	}
+
+	for _, tt := range tests {
+		account := &Account{Status: tt.status}
+
+		if got := account.CanDebit(); got != tt.wantDebit {
+			t.Errorf("%q CanDebit() = %v, want %v", tt.status, got, tt.wantDebit)
+		}
+
+		if got := account.CanCredit(); got != tt.wantCredit {
+			t.Errorf("%q CanCredit() = %v, want %v", tt.status, got, tt.wantCredit)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditAction string
+
+const (
+	AuditUserUpdated          AuditAction = "USER_UPDATED"
+	AuditUserPasswordChanged  AuditAction = "USER_PASSWORD_CHANGED"
+	AuditUserRoleChanged      AuditAction = "USER_ROLE_CHANGED"
+	AuditUserDeactivated      AuditAction = "USER_DEACTIVATED"
+	AuditUserDeleted          AuditAction = "USER_DELETED"
+	AuditUserIdentityVerified AuditAction = "USER_IDENTITY_VERIFIED"
+
+	AuditAccountStatusChanged    AuditAction = "ACCOUNT_STATUS_CHANGED"
+	AuditAccountOverdraftChanged AuditAction = "ACCOUNT_OVERDRAFT_CHANGED"
+	AuditAccountIbanReissued     AuditAction = "ACCOUNT_IBAN_REISSUED"
+)
+
+// AuditSystemActor is the actor of changes made by the application itself
+// rather than by an authenticated user.
+const AuditSystemActor = "system"
+
+// redactedValue replaces values that must not be kept in the audit trail.
+const redactedValue = "[redacted]"
+
+type FieldChange struct {
+	Old string `bson:"old"`
+	New string `bson:"new"`
+}
+
+// AuditRecord records who changed what on an entity and when.
+type AuditRecord struct {
+	Id         string                 `bson:"_id"`
+	EntityType string                 `bson:"entityType"`
+	EntityId   string                 `bson:"entityId"`
+	Action     AuditAction            `bson:"action"`
+	ActorId    string                 `bson:"actorId"`
+	Changes    map[string]FieldChange `bson:"changes"`
+	Reason     string                 `bson:"reason"`
+	CreatedAt  time.Time              `bson:"createdAt"`
+}
+
+func NewAuditRecord(entityType, entityId string, action AuditAction, actorId string) *AuditRecord {
+	return &AuditRecord{
+		Id:         uuid.New().String(),
+		EntityType: entityType,
+		EntityId:   entityId,
+		Action:     action,
+		ActorId:    actorId,
+		Changes:    make(map[string]FieldChange),
+		CreatedAt:  time.Now(),
+	}
+}
+
+// Record notes a field change; unchanged values are ignored.
+func (a *AuditRecord) Record(field, oldValue, newValue string) {
+	if oldValue == newValue {
+		return
+	}
+
+	a.Changes[field] = FieldChange{Old: oldValue, New: newValue}
+}
+
+// RecordRedacted notes that a secret field changed without keeping its values.
+func (a *AuditRecord) RecordRedacted(field string) {
+	a.Changes[field] = FieldChange{Old: redactedValue, New: redactedValue}
+}
+
+func (a *AuditRecord) HasChanges() bool {
+	return len(a.Changes) > 0
+}
@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidExchangeRate = errors.New("exchange rate must be a positive decimal number")
+
+// ExchangeRate converts amounts of From into To: one unit of From is worth
+// Rate units of To. Rate is kept as decimal text so it is stored exactly.
+type ExchangeRate struct {
+	From string `bson:"from"`
+	To   string `bson:"to"`
+	Rate string `bson:"rate"`
+}
+
+func NewExchangeRate(from, to, rate string) (*ExchangeRate, error) {
+	value, ok := new(big.Rat).SetString(rate)
+	if !ok || value.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidExchangeRate, rate)
+	}
+
+	return &ExchangeRate{From: from, To: to, Rate: rate}, nil
+}
+
+// Convert returns amount in the To currency, rounded half away from zero to
+// the currency's scale.
+func (r *ExchangeRate) Convert(amount Money) (Money, error) {
+	if amount.Currency != r.From {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, amount.Currency, r.From)
+	}
+
+	rate, ok := new(big.Rat).SetString(r.Rate)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidExchangeRate, r.Rate)
+	}
+
+	fromScale, err := CurrencyScale(r.From)
+	if err != nil {
+		return Money{}, err
+	}
+
+	toScale, err := CurrencyScale(r.To)
+	if err != nil {
+		return Money{}, err
+	}
+
+	// Minor units of From, to major units, through the rate, to minor units
+	// of To.
+	value := new(big.Rat).SetInt64(amount.Minor)
+	value.Mul(value, rate)
+	value.Mul(value, new(big.Rat).SetFrac(pow10(toScale), pow10(fromScale)))
+
+	minor, err := roundHalfAwayFromZero(value)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return NewMoney(minor, r.To), nil
+}
+
+// WithSpread returns the rate a customer gets after the bank's spread of
+// spreadBps basis points, which always lowers it. The result is exact: it has
+// at most four more decimal places than r.
+func (r *ExchangeRate) WithSpread(spreadBps int) (*ExchangeRate, error) {
+	if spreadBps < 0 || spreadBps >= 10000 {
+		return nil, fmt.Errorf("spread of %d basis points is out of range", spreadBps)
+	}
+
+	rate, ok := new(big.Rat).SetString(r.Rate)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidExchangeRate, r.Rate)
+	}
+
+	rate.Mul(rate, big.NewRat(int64(10000-spreadBps), 10000))
+
+	_, decimals, _ := strings.Cut(r.Rate, ".")
+
+	return NewExchangeRate(r.From, r.To, formatRate(rate, len(decimals)+4))
+}
+
+// InverseExchangeRate returns the rate from r.To to r.From, rounded to
+// decimals places.
+func InverseExchangeRate(r *ExchangeRate, decimals int) (*ExchangeRate, error) {
+	rate, ok := new(big.Rat).SetString(r.Rate)
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidExchangeRate, r.Rate)
+	}
+
+	return NewExchangeRate(r.To, r.From, formatRate(rate.Inv(rate), decimals))
+}
+
+// formatRate prints rate with at most decimals places and no trailing zeros.
+func formatRate(rate *big.Rat, decimals int) string {
+	text := rate.FloatString(decimals)
+
+	if strings.Contains(text, ".") {
+		text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	}
+
+	return text
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
+
+func roundHalfAwayFromZero(value *big.Rat) (int64, error) {
+	numerator := new(big.Int).Abs(value.Num())
+	denominator := value.Denom()
+
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if remainder.Lsh(remainder, 1).Cmp(denominator) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+
+	if value.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+
+	if !quotient.IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+
+	return quotient.Int64(), nil
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExchangeRateConvert(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      ExchangeRate
+		amount    Money
+		wantMinor int64
+		wantErr   error
+	}{
+		{name: "exact", rate: ExchangeRate{From: "USD", To: "TRY", Rate: "34.5678"}, amount: NewMoney(10000, "USD"), wantMinor: 345678},
+		{name: "half rounds up", rate: ExchangeRate{From: "USD", To: "TRY", Rate: "34.565"}, amount: NewMoney(1, "USD"), wantMinor: 35},
+		{name: "below half rounds down", rate: ExchangeRate{From: "USD", To: "TRY", Rate: "34.5649"}, amount: NewMoney(1, "USD"), wantMinor: 35},
+		{name: "just below half rounds down", rate: ExchangeRate{From: "USD", To: "TRY", Rate: "34.4999"}, amount: NewMoney(1, "USD"), wantMinor: 34},
+		{name: "negative half rounds away from zero", rate: ExchangeRate{From: "USD", To: "TRY", Rate: "34.565"}, amount: NewMoney(-1, "USD"), wantMinor: -35},
+		{name: "into a currency without minor units", rate: ExchangeRate{From: "USD", To: "JPY", Rate: "151.5"}, amount: NewMoney(100, "USD"), wantMinor: 152},
+		{name: "from a currency without minor units", rate: ExchangeRate{From: "JPY", To: "USD", Rate: "0.0066"}, amount: NewMoney(1000, "JPY"), wantMinor: 660},
+		{name: "into a three-decimal currency", rate: ExchangeRate{From: "USD", To: "KWD", Rate: "0.3075"}, amount: NewMoney(100, "USD"), wantMinor: 308},
+		{name: "from a three-decimal currency", rate: ExchangeRate{From: "KWD", To: "TRY", Rate: "112.4"}, amount: NewMoney(1500, "KWD"), wantMinor: 16860},
+		{name: "wrong source currency", rate: ExchangeRate{From: "USD", To: "TRY", Rate: "34.5"}, amount: NewMoney(100, "EUR"), wantErr: ErrCurrencyMismatch},
+		{name: "unsupported target currency", rate: ExchangeRate{From: "USD", To: "XXX", Rate: "34.5"}, amount: NewMoney(100, "USD"), wantErr: ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := tt.rate.Convert(tt.amount)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Convert() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && converted != NewMoney(tt.wantMinor, tt.rate.To) {
+				t.Errorf("Convert() = %+v, want %d %s minor units", converted, tt.wantMinor, tt.rate.To)
+			}
+		})
+	}
+}
+
+func TestNewExchangeRate(t *testing.T) {
+	for _, rate := range []string{"0", "-1.5", "", "abc"} {
+		if _, err := NewExchangeRate("USD", "TRY", rate); !errors.Is(err, ErrInvalidExchangeRate) {
+			t.Errorf("NewExchangeRate(%q) error = %v, want %v", rate, err, ErrInvalidExchangeRate)
+		}
+	}
+}
+
+func TestExchangeRateWithSpread(t *testing.T) {
+	tests := []struct {
+		rate      string
+		spreadBps int
+		want      string
+		wantErr   bool
+	}{
+		{rate: "34.5678", spreadBps: 0, want: "34.5678"},
+		{rate: "34.5678", spreadBps: 50, want: "34.394961"},
+		{rate: "1.0825", spreadBps: 25, want: "1.07979375"},
+		{rate: "2", spreadBps: 9999, want: "0.0002"},
+		{rate: "34.5678", spreadBps: 10000, wantErr: true},
+		{rate: "34.5678", spreadBps: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		rate := &ExchangeRate{From: "USD", To: "TRY", Rate: tt.rate}
+		got, err := rate.WithSpread(tt.spreadBps)
+
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("WithSpread(%s, %d) error = %v, want error %v", tt.rate, tt.spreadBps, err, tt.wantErr)
+		}
+
+		if err == nil && got.Rate != tt.want {
+			t.Errorf("WithSpread(%s, %d) = %s, want %s", tt.rate, tt.spreadBps, got.Rate, tt.want)
+		}
+	}
+}
+
+func TestInverseExchangeRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     string
+		decimals int
+		want     string
+		wantErr  error
+	}{
+		{name: "rounded to eight places", rate: "34.5678", decimals: 8, want: "0.02892866"},
+		{name: "rounded to four places", rate: "34.5678", decimals: 4, want: "0.0289"},
+		{name: "half rounds up", rate: "1.0825", decimals: 6, want: "0.923788"},
+		{name: "exact inverse drops trailing zeros", rate: "0.5", decimals: 8, want: "2"},
+		{name: "repeating inverse", rate: "3", decimals: 4, want: "0.3333"},
+		{name: "too small to represent", rate: "1000000", decimals: 4, wantErr: ErrInvalidExchangeRate},
+		{name: "zero rate", rate: "0", decimals: 4, wantErr: ErrInvalidExchangeRate},
+		{name: "malformed rate", rate: "1/0", decimals: 4, wantErr: ErrInvalidExchangeRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inverse, err := InverseExchangeRate(&ExchangeRate{From: "USD", To: "TRY", Rate: tt.rate}, tt.decimals)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("InverseExchangeRate(%s) error = %v, want %v", tt.rate, err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if inverse.Rate != tt.want || inverse.From != "TRY" || inverse.To != "USD" {
+				t.Errorf("InverseExchangeRate(%s) = %+v, want TRY to USD at %s", tt.rate, inverse, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FxQuote is the rate offered to a user for converting From into To. Rate is
+// MidRate after the bank's spread; a quote that was stored locks Rate until
+// ExpiresAt. Spot quotes, priced for a single transfer, have no Id.
+type FxQuote struct {
+	Id        string       `bson:"_id"`
+	UserId    string       `bson:"userId"`
+	From      string       `bson:"from"`
+	To        string       `bson:"to"`
+	MidRate   ExchangeRate `bson:"midRate"`
+	Rate      ExchangeRate `bson:"rate"`
+	SpreadBps int          `bson:"spreadBps"`
+	CreatedAt time.Time    `bson:"createdAt"`
+	ExpiresAt time.Time    `bson:"expiresAt"`
+}
+
+// NewFxQuote prices midRate for userId and locks the price for ttl.
+func NewFxQuote(userId string, midRate *ExchangeRate, spreadBps int, ttl time.Duration) (*FxQuote, error) {
+	quote, err := NewSpotFxQuote(userId, midRate, spreadBps)
+	if err != nil {
+		return nil, err
+	}
+
+	quote.Id = uuid.New().String()
+	quote.ExpiresAt = quote.CreatedAt.Add(ttl)
+
+	return quote, nil
+}
+
+// NewSpotFxQuote prices midRate for one conversion made right away.
+func NewSpotFxQuote(userId string, midRate *ExchangeRate, spreadBps int) (*FxQuote, error) {
+	rate, err := midRate.WithSpread(spreadBps)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &FxQuote{
+		UserId:    userId,
+		From:      midRate.From,
+		To:        midRate.To,
+		MidRate:   *midRate,
+		Rate:      *rate,
+		SpreadBps: spreadBps,
+		CreatedAt: now,
+		ExpiresAt: now,
+	}, nil
+}
+
+func (q *FxQuote) IsExpired() bool {
+	return !time.Now().Before(q.ExpiresAt)
+}
+
+// Convert prices amount at the quote. converted is what the customer receives
+// and spread what the bank keeps, both in To: together they are amount at the
+// mid rate.
+func (q *FxQuote) Convert(amount Money) (converted, spread Money, err error) {
+	if amount.Currency != q.From {
+		return Money{}, Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, amount.Currency, q.From)
+	}
+
+	converted, err = q.Rate.Convert(amount)
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+
+	atMidRate, err := q.MidRate.Convert(amount)
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+
+	spread, err = atMidRate.Sub(converted)
+	if err != nil {
+		return Money{}, Money{}, err
+	}
+
+	return converted, spread, nil
+}
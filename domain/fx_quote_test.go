@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFxQuoteConvert(t *testing.T) {
+	tests := []struct {
+		name          string
+		midRate       string
+		spreadBps     int
+		amount        Money
+		wantConverted int64
+		wantSpread    int64
+	}{
+		{name: "no spread", midRate: "34.5678", spreadBps: 0, amount: NewMoney(10000, "USD"), wantConverted: 345678, wantSpread: 0},
+		{name: "spread", midRate: "34.5678", spreadBps: 50, amount: NewMoney(10000, "USD"), wantConverted: 343950, wantSpread: 1728},
+		// Each side is rounded on its own: 34.39 kuruş to the customer and
+		// 34.57 at the mid rate leave the bank a whole kuruş.
+		{name: "rounding on a cent", midRate: "34.5678", spreadBps: 50, amount: NewMoney(1, "USD"), wantConverted: 34, wantSpread: 1},
+		{name: "spread too small to keep", midRate: "34.5678", spreadBps: 1, amount: NewMoney(1, "USD"), wantConverted: 35, wantSpread: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := NewSpotFxQuote("user", &ExchangeRate{From: "USD", To: "TRY", Rate: tt.midRate}, tt.spreadBps)
+			if err != nil {
+				t.Fatalf("NewSpotFxQuote() error = %v", err)
+			}
+
+			converted, spread, err := quote.Convert(tt.amount)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+
+			if converted != NewMoney(tt.wantConverted, "TRY") || spread != NewMoney(tt.wantSpread, "TRY") {
+				t.Errorf("Convert() = %+v, %+v, want %d and %d TRY minor units", converted, spread, tt.wantConverted, tt.wantSpread)
+			}
+		})
+	}
+}
+
+// The customer never gets more than the mid rate would give, and what the
+// customer gets and the bank keeps add up to exactly the mid-rate amount.
+func TestFxQuoteConvertSpreadIsNeverNegative(t *testing.T) {
+	quote, err := NewSpotFxQuote("user", &ExchangeRate{From: "USD", To: "TRY", Rate: "34.5678"}, 35)
+	if err != nil {
+		t.Fatalf("NewSpotFxQuote() error = %v", err)
+	}
+
+	for minor := int64(1); minor <= 5000; minor++ {
+		amount := NewMoney(minor, "USD")
+
+		converted, spread, err := quote.Convert(amount)
+		if err != nil {
+			t.Fatalf("Convert(%d) error = %v", minor, err)
+		}
+
+		atMidRate, err := quote.MidRate.Convert(amount)
+		if err != nil {
+			t.Fatalf("MidRate.Convert(%d) error = %v", minor, err)
+		}
+
+		if spread.IsNegative() {
+			t.Fatalf("Convert(%d) spread = %d, want at least zero", minor, spread.Minor)
+		}
+
+		if converted.Minor+spread.Minor != atMidRate.Minor {
+			t.Fatalf("Convert(%d) = %d + %d, want them to add up to %d", minor, converted.Minor, spread.Minor, atMidRate.Minor)
+		}
+	}
+}
+
+func TestFxQuoteConvertRejectsOtherCurrencies(t *testing.T) {
+	quote, err := NewSpotFxQuote("user", &ExchangeRate{From: "USD", To: "TRY", Rate: "34.5678"}, 50)
+	if err != nil {
+		t.Fatalf("NewSpotFxQuote() error = %v", err)
+	}
+
+	if _, _, err := quote.Convert(NewMoney(100, "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Convert(EUR) error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+}
@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type HoldStatus string
+
+const (
+	HoldStatusActive HoldStatus = "ACTIVE"
+	// HoldStatusCaptured means the hold was turned into a transfer; a partial
+	// capture releases the rest of it.
+	HoldStatusCaptured HoldStatus = "CAPTURED"
+	HoldStatusReleased HoldStatus = "RELEASED"
+	HoldStatusExpired  HoldStatus = "EXPIRED"
+)
+
+var (
+	ErrHoldNotActive       = errors.New("hold is no longer active")
+	ErrHoldExpired         = errors.New("hold has expired")
+	ErrHoldCaptureExceeded = errors.New("capture amount exceeds the held amount")
+	ErrAccountHasHolds     = errors.New("account has funds on hold")
+)
+
+// Hold reserves Amount of an account's money without moving it, e.g. for a
+// card authorization. While active it is counted in the account's HeldAmount
+// and so reduces the available balance; it ends when it is captured into a
+// transfer, released, or expires at ExpiresAt. CapturedAmount and TransferId
+// describe the capture.
+type Hold struct {
+	Id             string     `bson:"_id"`
+	AccountId      string     `bson:"accountId"`
+	Amount         Money      `bson:"amount"`
+	CapturedAmount *Money     `bson:"capturedAmount"`
+	TransferId     string     `bson:"transferId"`
+	Status         HoldStatus `bson:"status"`
+	Reference      string     `bson:"reference"`
+	CreatedBy      string     `bson:"createdBy"`
+	ExpiresAt      time.Time  `bson:"expiresAt"`
+	CreatedAt      time.Time  `bson:"createdAt"`
+	UpdatedAt      time.Time  `bson:"updatedAt"`
+	EndedAt        *time.Time `bson:"endedAt"`
+}
+
+func NewHold(accountId string, amount Money, reference, createdBy string, expiresAt time.Time) *Hold {
+	now := time.Now()
+
+	return &Hold{
+		Id:        uuid.New().String(),
+		AccountId: accountId,
+		Amount:    amount,
+		Status:    HoldStatusActive,
+		Reference: reference,
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func (h *Hold) IsExpired(now time.Time) bool {
+	return !now.Before(h.ExpiresAt)
+}
+
+// Capture ends the hold by taking amount of it, which may be less than the
+// held amount but not more.
+func (h *Hold) Capture(amount Money, transferId string, now time.Time) error {
+	if h.Status != HoldStatusActive {
+		return fmt.Errorf("%w: %s", ErrHoldNotActive, h.Status)
+	}
+
+	if h.IsExpired(now) {
+		return ErrHoldExpired
+	}
+
+	cmp, err := amount.Cmp(h.Amount)
+	if err != nil {
+		return err
+	}
+
+	if cmp > 0 {
+		return ErrHoldCaptureExceeded
+	}
+
+	h.Status = HoldStatusCaptured
+	h.CapturedAmount = &amount
+	h.TransferId = transferId
+	h.UpdatedAt = now
+	h.EndedAt = &now
+
+	return nil
+}
+
+// End releases the hold without capturing it, with status RELEASED or
+// EXPIRED.
+func (h *Hold) End(status HoldStatus, now time.Time) error {
+	if h.Status != HoldStatusActive {
+		return fmt.Errorf("%w: %s", ErrHoldNotActive, h.Status)
+	}
+
+	h.Status = status
+	h.UpdatedAt = now
+	h.EndedAt = &now
+
+	return nil
+}
+
+// EffectiveHeldAmount is the money on hold, zero for accounts without holds.
+func (a *Account) EffectiveHeldAmount() Money {
+	if a.HeldAmount == nil {
+		return ZeroMoney(a.Balance.Currency)
+	}
+
+	return *a.HeldAmount
+}
+
+// AddHeld changes the money on hold by amount, which is negative when a hold
+// ends.
+func (a *Account) AddHeld(amount Money) error {
+	held, err := a.EffectiveHeldAmount().Add(amount)
+	if err != nil {
+		return err
+	}
+
+	if held.IsNegative() {
+		return fmt.Errorf("held amount of account %s would become negative", a.Id)
+	}
+
+	a.HeldAmount = &held
+	if held.IsZero() {
+		a.HeldAmount = nil
+	}
+
+	a.UpdatedAt = time.Now()
+
+	return nil
+}
+
+func (a *Account) HasHolds() bool {
+	return a.EffectiveHeldAmount().IsPositive()
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord remembers the outcome of a request sent with an
+// Idempotency-Key, so a retry of the same request can be answered without
+// running it again. Ambiguous marks a request that failed in a way that does
+// not tell whether it took effect, e.g. a timeout after a transaction
+// committed; retrying it could apply it twice.
+type IdempotencyRecord struct {
+	Key         string    `bson:"_id"`
+	RequestHash string    `bson:"requestHash"`
+	Completed   bool      `bson:"completed"`
+	Ambiguous   bool      `bson:"ambiguous"`
+	StatusCode  int       `bson:"statusCode"`
+	Body        []byte    `bson:"body"`
+	CreatedAt   time.Time `bson:"createdAt"`
+}
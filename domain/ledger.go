@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PostingDirection string
+
+const (
+	PostingDebit  PostingDirection = "DEBIT"
+	PostingCredit PostingDirection = "CREDIT"
+)
+
+type JournalEntryType string
+
+const (
+	JournalEntryAccountOpening JournalEntryType = "ACCOUNT_OPENING"
+	JournalEntryLegacyBalance  JournalEntryType = "LEGACY_BALANCE"
+	JournalEntryTransfer       JournalEntryType = "TRANSFER"
+	JournalEntryFee            JournalEntryType = "FEE"
+	JournalEntryInterest       JournalEntryType = "INTEREST"
+	// JournalEntryAccountClosing sweeps the remaining balance of an account
+	// being closed to another account of the same owner.
+	JournalEntryAccountClosing JournalEntryType = "ACCOUNT_CLOSING"
+	JournalEntryDeposit        JournalEntryType = "DEPOSIT"
+	JournalEntryWithdrawal     JournalEntryType = "WITHDRAWAL"
+)
+
+// internalLedgerAccountPrefix marks ledger accounts that belong to the bank
+// itself (equity, cash, income). They have no document in the accounts bucket.
+const internalLedgerAccountPrefix = "gl::"
+
+var ErrUnbalancedJournalEntry = errors.New("journal entry is not balanced")
+
+type Posting struct {
+	AccountId string           `bson:"accountId"`
+	Direction PostingDirection `bson:"direction"`
+	Amount    Money            `bson:"amount"`
+}
+
+// JournalEntry is an immutable record of a money movement. Its postings must
+// balance: for every currency, the sum of debits equals the sum of credits.
+type JournalEntry struct {
+	Id        string           `bson:"_id"`
+	Type      JournalEntryType `bson:"type"`
+	Reference string           `bson:"reference"`
+	Postings  []Posting        `bson:"postings"`
+	CreatedAt time.Time        `bson:"createdAt"`
+}
+
+func InternalLedgerAccount(name, currency string) string {
+	return internalLedgerAccountPrefix + name + "::" + currency
+}
+
+func IsInternalLedgerAccount(accountId string) bool {
+	return strings.HasPrefix(accountId, internalLedgerAccountPrefix)
+}
+
+func Debit(accountId string, amount Money) Posting {
+	return Posting{AccountId: accountId, Direction: PostingDebit, Amount: amount}
+}
+
+func Credit(accountId string, amount Money) Posting {
+	return Posting{AccountId: accountId, Direction: PostingCredit, Amount: amount}
+}
+
+func NewJournalEntry(entryType JournalEntryType, reference string, postings ...Posting) (*JournalEntry, error) {
+	entry := &JournalEntry{
+		Id:        uuid.New().String(),
+		Type:      entryType,
+		Reference: reference,
+		Postings:  postings,
+		CreatedAt: time.Now(),
+	}
+
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func NewTransferJournalEntry(transferId, fromAccountId, toAccountId string, amount Money) (*JournalEntry, error) {
+	return NewJournalEntry(JournalEntryTransfer, transferId,
+		Debit(fromAccountId, amount),
+		Credit(toAccountId, amount),
+	)
+}
+
+// NewCrossCurrencyTransferJournalEntry books a transfer between accounts in
+// different currencies through the bank's FX position accounts, so each
+// currency balances on its own: the source leg in amount's currency and the
+// destination leg in converted's. The spread the bank kept on the conversion
+// is booked as FX income.
+func NewCrossCurrencyTransferJournalEntry(transferId, fromAccountId, toAccountId string, amount, converted, spread Money) (*JournalEntry, error) {
+	atMidRate, err := converted.Add(spread)
+	if err != nil {
+		return nil, err
+	}
+
+	postings := []Posting{
+		Debit(fromAccountId, amount),
+		Credit(InternalLedgerAccount("fx-position", amount.Currency), amount),
+		Debit(InternalLedgerAccount("fx-position", converted.Currency), atMidRate),
+		Credit(toAccountId, converted),
+	}
+
+	if spread.IsPositive() {
+		postings = append(postings, Credit(InternalLedgerAccount("fx-income", spread.Currency), spread))
+	}
+
+	return NewJournalEntry(JournalEntryTransfer, transferId, postings...)
+}
+
+// NewDepositJournalEntry books cash paid into an account against the bank's
+// cash account.
+func NewDepositJournalEntry(transferId, accountId string, amount Money) (*JournalEntry, error) {
+	return NewJournalEntry(JournalEntryDeposit, transferId,
+		Debit(InternalLedgerAccount("cash", amount.Currency), amount),
+		Credit(accountId, amount),
+	)
+}
+
+// NewWithdrawalJournalEntry books cash paid out of an account against the
+// bank's cash account.
+func NewWithdrawalJournalEntry(transferId, accountId string, amount Money) (*JournalEntry, error) {
+	return NewJournalEntry(JournalEntryWithdrawal, transferId,
+		Debit(accountId, amount),
+		Credit(InternalLedgerAccount("cash", amount.Currency), amount),
+	)
+}
+
+// NewAccountOpeningJournalEntry records the opening of an account against the
+// bank's opening-balance equity account, so every customer account has a
+// ledger history from the moment it exists.
+func NewAccountOpeningJournalEntry(accountId string, openingBalance Money) (*JournalEntry, error) {
+	return NewJournalEntry(JournalEntryAccountOpening, accountId,
+		Debit(InternalLedgerAccount("opening-equity", openingBalance.Currency), openingBalance),
+		Credit(accountId, openingBalance),
+	)
+}
+
+func (e *JournalEntry) Validate() error {
+	if len(e.Postings) < 2 {
+		return fmt.Errorf("%w: at least two postings are required", ErrUnbalancedJournalEntry)
+	}
+
+	totals := make(map[string]int64)
+
+	for _, posting := range e.Postings {
+		if posting.AccountId == "" {
+			return fmt.Errorf("%w: posting without account", ErrUnbalancedJournalEntry)
+		}
+
+		if posting.Amount.IsNegative() {
+			return fmt.Errorf("%w: negative posting amount", ErrUnbalancedJournalEntry)
+		}
+
+		switch posting.Direction {
+		case PostingDebit:
+			totals[posting.Amount.Currency] += posting.Amount.Minor
+		case PostingCredit:
+			totals[posting.Amount.Currency] -= posting.Amount.Minor
+		default:
+			return fmt.Errorf("%w: unknown posting direction %q", ErrUnbalancedJournalEntry, posting.Direction)
+		}
+	}
+
+	for currency, total := range totals {
+		if total != 0 {
+			return fmt.Errorf("%w: debits and credits differ in %s", ErrUnbalancedJournalEntry, currency)
+		}
+	}
+
+	return nil
+}
+
+// BalanceEffect is the change a posting makes to a customer account balance.
+// Customer accounts are liabilities of the bank, so credits increase them and
+// debits decrease them.
+func (p Posting) BalanceEffect() Money {
+	if p.Direction == PostingDebit {
+		return p.Amount.Negate()
+	}
+
+	return p.Amount
+}
@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewJournalEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		postings []Posting
+		wantErr  error
+	}{
+		{
+			name:     "balanced transfer",
+			postings: []Posting{Debit("a", NewMoney(1000, "TRY")), Credit("b", NewMoney(1000, "TRY"))},
+		},
+		{
+			name: "balanced with several postings per side",
+			postings: []Posting{
+				Debit("a", NewMoney(700, "TRY")),
+				Debit("b", NewMoney(300, "TRY")),
+				Credit("c", NewMoney(1000, "TRY")),
+			},
+		},
+		{
+			name:     "zero amounts balance",
+			postings: []Posting{Debit("a", ZeroMoney("TRY")), Credit("b", ZeroMoney("TRY"))},
+		},
+		{
+			name:     "debits exceed credits",
+			postings: []Posting{Debit("a", NewMoney(1000, "TRY")), Credit("b", NewMoney(999, "TRY"))},
+			wantErr:  ErrUnbalancedJournalEntry,
+		},
+		{
+			name:     "zero against non-zero",
+			postings: []Posting{Debit("a", ZeroMoney("TRY")), Credit("b", NewMoney(1, "TRY"))},
+			wantErr:  ErrUnbalancedJournalEntry,
+		},
+		{
+			name:     "single posting",
+			postings: []Posting{Debit("a", NewMoney(1000, "TRY"))},
+			wantErr:  ErrUnbalancedJournalEntry,
+		},
+		{
+			name:    "no postings",
+			wantErr: ErrUnbalancedJournalEntry,
+		},
+		{
+			name:     "negative amount",
+			postings: []Posting{Debit("a", NewMoney(-1000, "TRY")), Credit("b", NewMoney(-1000, "TRY"))},
+			wantErr:  ErrUnbalancedJournalEntry,
+		},
+		{
+			name:     "posting without account",
+			postings: []Posting{Debit("", NewMoney(1000, "TRY")), Credit("b", NewMoney(1000, "TRY"))},
+			wantErr:  ErrUnbalancedJournalEntry,
+		},
+		{
+			name:     "unknown direction",
+			postings: []Posting{{AccountId: "a", Direction: "SIDEWAYS", Amount: NewMoney(1000, "TRY")}, Credit("b", NewMoney(1000, "TRY"))},
+			wantErr:  ErrUnbalancedJournalEntry,
+		},
+		{
+			name:     "mixed currencies with equal minor units",
+			postings: []Posting{Debit("a", NewMoney(1000, "TRY")), Credit("b", NewMoney(1000, "USD"))},
+			wantErr:  ErrUnbalancedJournalEntry,
+		},
+		{
+			name: "mixed currencies balanced per currency",
+			postings: []Posting{
+				Debit("a", NewMoney(1000, "USD")),
+				Credit(InternalLedgerAccount("fx-position", "USD"), NewMoney(1000, "USD")),
+				Debit(InternalLedgerAccount("fx-position", "TRY"), NewMoney(34000, "TRY")),
+				Credit("b", NewMoney(34000, "TRY")),
+			},
+		},
+		{
+			name: "mixed currencies with one currency unbalanced",
+			postings: []Posting{
+				Debit("a", NewMoney(1000, "USD")),
+				Credit(InternalLedgerAccount("fx-position", "USD"), NewMoney(1000, "USD")),
+				Debit(InternalLedgerAccount("fx-position", "TRY"), NewMoney(34000, "TRY")),
+				Credit("b", NewMoney(33999, "TRY")),
+			},
+			wantErr: ErrUnbalancedJournalEntry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := NewJournalEntry(JournalEntryTransfer, "ref", tt.postings...)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewJournalEntry() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil && entry != nil {
+				t.Fatalf("NewJournalEntry() returned an entry along with error %v", err)
+			}
+
+			if tt.wantErr == nil && (entry == nil || entry.Id == "") {
+				t.Fatalf("NewJournalEntry() = %v, want an entry with an id", entry)
+			}
+		})
+	}
+}
+
+func TestNewCrossCurrencyTransferJournalEntryBalancesPerCurrency(t *testing.T) {
+	entry, err := NewCrossCurrencyTransferJournalEntry("transfer", "from", "to", NewMoney(10000, "USD"), NewMoney(339150, "TRY"), NewMoney(1700, "TRY"))
+	if err != nil {
+		t.Fatalf("NewCrossCurrencyTransferJournalEntry() error = %v", err)
+	}
+
+	totals := map[string]int64{}
+	for _, posting := range entry.Postings {
+		effect := posting.Amount.Minor
+		if posting.Direction == PostingCredit {
+			effect = -effect
+		}
+
+		totals[posting.Amount.Currency] += effect
+	}
+
+	for currency, total := range totals {
+		if total != 0 {
+			t.Errorf("%s debits and credits differ by %d", currency, total)
+		}
+	}
+}
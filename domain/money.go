@@ -0,0 +1,197 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrInvalidAmount       = errors.New("amount must be a plain decimal number")
+	ErrAmountPrecision     = errors.New("amount has more decimal places than the currency allows")
+	ErrAmountOverflow      = errors.New("amount is out of range")
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
+)
+
+// currencyScales holds the number of minor-unit digits for each supported
+// ISO 4217 currency.
+var currencyScales = map[string]int{
+	"TRY": 2,
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KWD": 3,
+}
+
+// Money is an exact amount of a currency, held as an integer number of minor
+// units (kuruş for TRY, cents for USD, fils for KWD).
+type Money struct {
+	Minor    int64  `bson:"minor"`
+	Currency string `bson:"currency"`
+}
+
+func CurrencyScale(currency string) (int, error) {
+	scale, ok := currencyScales[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+
+	return scale, nil
+}
+
+func NewMoney(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+func ZeroMoney(currency string) Money {
+	return NewMoney(0, currency)
+}
+
+// ParseMoney parses a decimal string such as "12.30" into Money. Amounts with
+// more fractional digits than the currency supports are rejected rather than
+// rounded.
+func ParseMoney(amount, currency string) (Money, error) {
+	scale, err := CurrencyScale(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	text := strings.TrimSpace(amount)
+
+	negative := strings.HasPrefix(text, "-")
+	if negative {
+		text = text[1:]
+	}
+
+	integerPart, fractionPart, hasFraction := strings.Cut(text, ".")
+	if integerPart == "" || !isDigits(integerPart) || (hasFraction && (fractionPart == "" || !isDigits(fractionPart))) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	// Trailing zeros do not add precision: "10.500" is a valid TRY amount.
+	fractionPart = strings.TrimRight(fractionPart, "0")
+	if len(fractionPart) > scale {
+		return Money{}, fmt.Errorf("%w: %s supports %d", ErrAmountPrecision, currency, scale)
+	}
+
+	digits := integerPart + fractionPart + strings.Repeat("0", scale-len(fractionPart))
+
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, ErrAmountOverflow
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return NewMoney(minor, currency), nil
+}
+
+// MoneyFromFloat converts a legacy float amount, rounding half away from zero
+// to the currency's scale. It exists only to migrate data written before
+// amounts were stored in minor units.
+func MoneyFromFloat(amount float64, currency string) (Money, error) {
+	scale, err := CurrencyScale(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	minor := math.Round(amount * math.Pow10(scale))
+	if math.IsNaN(minor) || minor > math.MaxInt64 || minor < math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+
+	return NewMoney(int64(minor), currency), nil
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+
+	sum := m.Minor + other.Minor
+	if (other.Minor > 0 && sum < m.Minor) || (other.Minor < 0 && sum > m.Minor) {
+		return Money{}, ErrAmountOverflow
+	}
+
+	return NewMoney(sum, m.Currency), nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Minor == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+
+	return m.Add(other.Negate())
+}
+
+func (m Money) Negate() Money {
+	return NewMoney(-m.Minor, m.Currency)
+}
+
+// Cmp returns -1, 0 or +1 depending on whether m is less than, equal to or
+// greater than other.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+
+	switch {
+	case m.Minor < other.Minor:
+		return -1, nil
+	case m.Minor > other.Minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+// String formats the amount as a plain decimal at the currency's scale,
+// e.g. "1250.00" for TRY or "1250" for JPY.
+func (m Money) String() string {
+	scale, ok := currencyScales[m.Currency]
+	if !ok || scale == 0 {
+		return strconv.FormatInt(m.Minor, 10)
+	}
+
+	sign := ""
+	magnitude := uint64(m.Minor)
+	if m.Minor < 0 {
+		sign = "-"
+		magnitude = uint64(-(m.Minor + 1)) + 1
+	}
+
+	digits := strconv.FormatUint(magnitude, 10)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
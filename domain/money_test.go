@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount    string
+		currency  string
+		wantMinor int64
+		wantErr   error
+	}{
+		{amount: "12.30", currency: "TRY", wantMinor: 1230},
+		{amount: "12.3", currency: "TRY", wantMinor: 1230},
+		{amount: "12", currency: "TRY", wantMinor: 1200},
+		{amount: "0.01", currency: "USD", wantMinor: 1},
+		{amount: " 5.00 ", currency: "EUR", wantMinor: 500},
+		{amount: "-7.25", currency: "GBP", wantMinor: -725},
+		{amount: "10.500", currency: "TRY", wantMinor: 1050},
+		{amount: "1250", currency: "JPY", wantMinor: 1250},
+		{amount: "1250.0", currency: "JPY", wantMinor: 1250},
+		{amount: "1.234", currency: "KWD", wantMinor: 1234},
+		{amount: "0.001", currency: "KWD", wantMinor: 1},
+		{amount: "12.345", currency: "TRY", wantErr: ErrAmountPrecision},
+		{amount: "1250.5", currency: "JPY", wantErr: ErrAmountPrecision},
+		{amount: "1.2345", currency: "KWD", wantErr: ErrAmountPrecision},
+		{amount: "", currency: "TRY", wantErr: ErrInvalidAmount},
+		{amount: ".5", currency: "TRY", wantErr: ErrInvalidAmount},
+		{amount: "5.", currency: "TRY", wantErr: ErrInvalidAmount},
+		{amount: "1e3", currency: "TRY", wantErr: ErrInvalidAmount},
+		{amount: "1,000.00", currency: "TRY", wantErr: ErrInvalidAmount},
+		{amount: "+5", currency: "TRY", wantErr: ErrInvalidAmount},
+		{amount: "--5", currency: "TRY", wantErr: ErrInvalidAmount},
+		{amount: "92233720368547758.08", currency: "TRY", wantErr: ErrAmountOverflow},
+		{amount: "10", currency: "XXX", wantErr: ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.currency+" "+tt.amount, func(t *testing.T) {
+			money, err := ParseMoney(tt.amount, tt.currency)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseMoney(%q, %s) error = %v, want %v", tt.amount, tt.currency, err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && money != NewMoney(tt.wantMinor, tt.currency) {
+				t.Errorf("ParseMoney(%q, %s) = %+v, want %d minor units", tt.amount, tt.currency, money, tt.wantMinor)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{money: NewMoney(1230, "TRY"), want: "12.30"},
+		{money: NewMoney(5, "USD"), want: "0.05"},
+		{money: NewMoney(0, "EUR"), want: "0.00"},
+		{money: NewMoney(-725, "GBP"), want: "-7.25"},
+		{money: NewMoney(-5, "TRY"), want: "-0.05"},
+		{money: NewMoney(1250, "JPY"), want: "1250"},
+		{money: NewMoney(1, "KWD"), want: "0.001"},
+		{money: NewMoney(math.MinInt64, "TRY"), want: "-92233720368547758.08"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.money.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMoneyRoundTrips(t *testing.T) {
+	for _, amount := range []string{"0.00", "0.01", "-0.01", "123456.78", "-92233720368547758.07"} {
+		money, err := ParseMoney(amount, "TRY")
+		if err != nil {
+			t.Fatalf("ParseMoney(%q) error = %v", amount, err)
+		}
+
+		if got := money.String(); got != amount {
+			t.Errorf("ParseMoney(%q).String() = %q", amount, got)
+		}
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	tests := []struct {
+		amount    float64
+		currency  string
+		wantMinor int64
+	}{
+		{amount: 12.3, currency: "TRY", wantMinor: 1230},
+		{amount: 0.125, currency: "TRY", wantMinor: 13},
+		{amount: -0.125, currency: "TRY", wantMinor: -13},
+		{amount: 1250.5, currency: "JPY", wantMinor: 1251},
+		{amount: 1.2345, currency: "KWD", wantMinor: 1235},
+	}
+
+	for _, tt := range tests {
+		money, err := MoneyFromFloat(tt.amount, tt.currency)
+		if err != nil {
+			t.Fatalf("MoneyFromFloat(%v, %s) error = %v", tt.amount, tt.currency, err)
+		}
+
+		if money.Minor != tt.wantMinor {
+			t.Errorf("MoneyFromFloat(%v, %s) = %d minor units, want %d", tt.amount, tt.currency, money.Minor, tt.wantMinor)
+		}
+	}
+
+	if _, err := MoneyFromFloat(math.Inf(1), "TRY"); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("MoneyFromFloat(+Inf) error = %v, want %v", err, ErrAmountOverflow)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	sum, err := NewMoney(100, "TRY").Add(NewMoney(-250, "TRY"))
+	if err != nil || sum != NewMoney(-150, "TRY") {
+		t.Errorf("Add() = %+v, %v, want -150 TRY", sum, err)
+	}
+
+	if _, err := NewMoney(100, "TRY").Add(NewMoney(100, "USD")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add() across currencies error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+
+	if _, err := NewMoney(math.MaxInt64, "TRY").Add(NewMoney(1, "TRY")); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("Add() past MaxInt64 error = %v, want %v", err, ErrAmountOverflow)
+	}
+
+	if _, err := NewMoney(0, "TRY").Sub(NewMoney(math.MinInt64, "TRY")); !errors.Is(err, ErrAmountOverflow) {
+		t.Errorf("Sub(MinInt64) error = %v, want %v", err, ErrAmountOverflow)
+	}
+
+	if cmp, err := NewMoney(100, "TRY").Cmp(NewMoney(99, "TRY")); err != nil || cmp != 1 {
+		t.Errorf("Cmp() = %d, %v, want 1", cmp, err)
+	}
+
+	if _, err := NewMoney(100, "TRY").Cmp(NewMoney(100, "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Cmp() across currencies error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidNationalId = errors.New("national id is not a valid TC Kimlik No")
+
+// ValidateNationalId checks a TC Kimlik No: eleven digits, not starting with
+// zero, whose last two digits are the checksums of the official algorithm.
+func ValidateNationalId(nationalId string) error {
+	if len(nationalId) != 11 || nationalId[0] == '0' || !isDigits(nationalId) {
+		return ErrInvalidNationalId
+	}
+
+	var d [11]int
+	for i, r := range nationalId {
+		d[i] = int(r - '0')
+	}
+
+	odd := d[0] + d[2] + d[4] + d[6] + d[8]
+	even := d[1] + d[3] + d[5] + d[7]
+
+	// Go's % keeps the sign of the dividend, so shift the result into 0-9.
+	if ((odd*7-even)%10+10)%10 != d[9] {
+		return ErrInvalidNationalId
+	}
+
+	sum := 0
+	for _, digit := range d[:10] {
+		sum += digit
+	}
+
+	if sum%10 != d[10] {
+		return ErrInvalidNationalId
+	}
+
+	return nil
+}
+
+// MaskNationalId hides all but the last two digits.
+func MaskNationalId(nationalId string) string {
+	if len(nationalId) <= 2 {
+		return nationalId
+	}
+
+	return strings.Repeat("*", len(nationalId)-2) + nationalId[len(nationalId)-2:]
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateNationalId(t *testing.T) {
+	tests := []struct {
+		name       string
+		nationalId string
+		wantErr    error
+	}{
+		{name: "valid", nationalId: "10000000146"},
+		{name: "valid with every digit", nationalId: "12345678950"},
+		// The first checksum comes out negative before it is shifted.
+		{name: "valid with a negative first checksum", nationalId: "19090909018"},
+		{name: "wrong tenth digit", nationalId: "10000000156", wantErr: ErrInvalidNationalId},
+		{name: "wrong eleventh digit", nationalId: "10000000147", wantErr: ErrInvalidNationalId},
+		{name: "transposed digits", nationalId: "12345678590", wantErr: ErrInvalidNationalId},
+		{name: "leading zero", nationalId: "02345678950", wantErr: ErrInvalidNationalId},
+		{name: "too short", nationalId: "1000000014", wantErr: ErrInvalidNationalId},
+		{name: "too long", nationalId: "100000001460", wantErr: ErrInvalidNationalId},
+		{name: "letters", nationalId: "1000000014A", wantErr: ErrInvalidNationalId},
+		{name: "spaces", nationalId: "100 0000014", wantErr: ErrInvalidNationalId},
+		{name: "empty", nationalId: "", wantErr: ErrInvalidNationalId},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateNationalId(tt.nationalId); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateNationalId(%q) error = %v, want %v", tt.nationalId, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaskNationalId(t *testing.T) {
+	tests := []struct {
+		nationalId string
+		want       string
+	}{
+		{nationalId: "10000000146", want: "*********46"},
+		{nationalId: "12", want: "12"},
+		{nationalId: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := MaskNationalId(tt.nationalId); got != tt.want {
+			t.Errorf("MaskNationalId(%q) = %q, want %q", tt.nationalId, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "PENDING"
+	OutboxStatusSent    OutboxStatus = "SENT"
+)
+
+const OutboxEventTransferRequested = "TransferRequested"
+
+var (
+	ErrOutboxEventNotPending = errors.New("outbox event is no longer pending")
+	ErrOutboxEventLeased     = errors.New("outbox event is being published")
+	ErrOutboxLeaseLost       = errors.New("outbox event lease is held by another relay")
+)
+
+// OutboxEvent is a message waiting to be published. It is written in the same
+// transaction as the state change it announces and published afterwards by
+// the outbox relay, so the two can never disagree.
+//
+// A relay publishes an event only after claiming it: LeaseOwner is the relay
+// and LeaseExpiresAt when another relay may claim it, should the first die
+// before marking it sent. The event is published with its Id as message id.
+type OutboxEvent struct {
+	Id             string          `bson:"_id"`
+	EventType      string          `bson:"eventType"`
+	AggregateId    string          `bson:"aggregateId"`
+	Exchange       string          `bson:"exchange"`
+	RoutingKey     string          `bson:"routingKey"`
+	Payload        json.RawMessage `bson:"payload"`
+	Status         OutboxStatus    `bson:"status"`
+	Attempts       int             `bson:"attempts"`
+	LastError      string          `bson:"lastError"`
+	LeaseOwner     string          `bson:"leaseOwner"`
+	LeaseExpiresAt *time.Time      `bson:"leaseExpiresAt"`
+	CreatedAt      time.Time       `bson:"createdAt"`
+	SentAt         *time.Time      `bson:"sentAt"`
+}
+
+func NewOutboxEvent(eventType, aggregateId, exchange, routingKey string, payload json.RawMessage) *OutboxEvent {
+	return &OutboxEvent{
+		Id:          uuid.New().String(),
+		EventType:   eventType,
+		AggregateId: aggregateId,
+		Exchange:    exchange,
+		RoutingKey:  routingKey,
+		Payload:     payload,
+		Status:      OutboxStatusPending,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func (e *OutboxEvent) IsLeased(now time.Time) bool {
+	return e.LeaseExpiresAt != nil && now.Before(*e.LeaseExpiresAt)
+}
+
+// Claim gives owner the right to publish the event for ttl.
+func (e *OutboxEvent) Claim(owner string, now time.Time, ttl time.Duration) error {
+	if e.Status != OutboxStatusPending {
+		return fmt.Errorf("%w: %s", ErrOutboxEventNotPending, e.Status)
+	}
+
+	if e.IsLeased(now) {
+		return ErrOutboxEventLeased
+	}
+
+	expiresAt := now.Add(ttl)
+
+	e.LeaseOwner = owner
+	e.LeaseExpiresAt = &expiresAt
+
+	return nil
+}
+
+// HoldsLease reports whether owner may still record the outcome of the
+// publish it started: no other relay has claimed the event since.
+func (e *OutboxEvent) HoldsLease(owner string) bool {
+	return e.LeaseOwner == owner
+}
+
+// MarkSent records that the event was published and gives the lease up.
+func (e *OutboxEvent) MarkSent(now time.Time) {
+	e.Status = OutboxStatusSent
+	e.SentAt = &now
+	e.releaseLease()
+}
+
+// RecordFailedAttempt notes a failed publish and gives the lease up, so the
+// event is retried on the next tick rather than when the lease runs out.
+func (e *OutboxEvent) RecordFailedAttempt(cause error) {
+	e.Attempts++
+	e.LastError = cause.Error()
+	e.releaseLease()
+}
+
+func (e *OutboxEvent) releaseLease() {
+	e.LeaseOwner = ""
+	e.LeaseExpiresAt = nil
+}
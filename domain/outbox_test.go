@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOutboxEventLeaseTakeover(t *testing.T) {
+	now := time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)
+	event := NewOutboxEvent(OutboxEventTransferRequested, "t1", "transfers", "transfer.requested", nil)
+
+	if err := event.Claim("relay-a", now, time.Minute); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	if err := event.Claim("relay-b", now.Add(30*time.Second), time.Minute); !errors.Is(err, ErrOutboxEventLeased) {
+		t.Fatalf("Claim() while leased error = %v, want %v", err, ErrOutboxEventLeased)
+	}
+
+	if err := event.Claim("relay-b", now.Add(2*time.Minute), time.Minute); err != nil {
+		t.Fatalf("Claim() after the lease ran out error = %v", err)
+	}
+
+	if event.HoldsLease("relay-a") {
+		t.Errorf("HoldsLease(relay-a) = true after relay-b claimed the event")
+	}
+
+	event.MarkSent(now.Add(2 * time.Minute))
+
+	if event.Status != OutboxStatusSent || event.LeaseOwner != "" || event.LeaseExpiresAt != nil {
+		t.Errorf("MarkSent() left %+v, want a sent event without a lease", event)
+	}
+}
@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var (
+	ErrInvalidOverdraftLimit      = errors.New("overdraft limit must not be negative")
+	ErrOverdraftLimitBelowBalance = errors.New("overdraft limit must cover the current overdrawn balance")
+	ErrInvalidInterestRate        = errors.New("interest rate must be a non-negative decimal number")
+)
+
+// daysPerYear turns a yearly rate into a daily one (actual/365).
+const daysPerYear = 365
+
+// EffectiveOverdraftLimit is the overdraft limit, zero for accounts without
+// one.
+func (a *Account) EffectiveOverdraftLimit() Money {
+	if a.OverdraftLimit == nil {
+		return ZeroMoney(a.Balance.Currency)
+	}
+
+	return *a.OverdraftLimit
+}
+
+// AvailableBalance is what may still leave the account: the balance plus what
+// remains of the overdraft limit, less the money on hold.
+func (a *Account) AvailableBalance() (Money, error) {
+	available, err := a.Balance.Add(a.EffectiveOverdraftLimit())
+	if err != nil {
+		return Money{}, err
+	}
+
+	return available.Sub(a.EffectiveHeldAmount())
+}
+
+// CanCover reports whether amount may leave the account without going past
+// its overdraft limit.
+func (a *Account) CanCover(amount Money) (bool, error) {
+	available, err := a.AvailableBalance()
+	if err != nil {
+		return false, err
+	}
+
+	cmp, err := available.Cmp(amount)
+	if err != nil {
+		return false, err
+	}
+
+	return cmp >= 0, nil
+}
+
+// SetOverdraftLimit sets the overdraft limit; zero removes it. It can not be
+// lowered below what the account already owes.
+func (a *Account) SetOverdraftLimit(limit Money) error {
+	if limit.IsNegative() {
+		return ErrInvalidOverdraftLimit
+	}
+
+	if limit.Currency != a.Balance.Currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, limit.Currency, a.Balance.Currency)
+	}
+
+	if a.Balance.IsNegative() && limit.Minor < -a.Balance.Minor {
+		return ErrOverdraftLimitBelowBalance
+	}
+
+	a.OverdraftLimit = &limit
+	if limit.IsZero() {
+		a.OverdraftLimit = nil
+	}
+
+	a.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// TrackOverdraftBreach records whether the balance is past the overdraft
+// limit, and reports whether it has just gone past it. Holds do not count.
+func (a *Account) TrackOverdraftBreach(now time.Time) (bool, error) {
+	withinLimit, err := a.Balance.Add(a.EffectiveOverdraftLimit())
+	if err != nil {
+		return false, err
+	}
+
+	if !withinLimit.IsNegative() {
+		a.OverdraftBreachedAt = nil
+		return false, nil
+	}
+
+	if a.OverdraftBreachedAt != nil {
+		return false, nil
+	}
+
+	a.OverdraftBreachedAt = &now
+
+	return true, nil
+}
+
+// InterestRate is a yearly interest rate, e.g. "0.24" for 24%.
+type InterestRate struct {
+	yearly *big.Rat
+}
+
+func NewInterestRate(yearly string) (*InterestRate, error) {
+	value, ok := new(big.Rat).SetString(yearly)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidInterestRate, yearly)
+	}
+
+	return &InterestRate{yearly: value}, nil
+}
+
+func (r *InterestRate) IsZero() bool {
+	return r.yearly.Sign() == 0
+}
+
+// DailyInterest is one day of interest on amount, rounded half away from zero
+// to the currency's scale. The sign of amount is ignored.
+func (r *InterestRate) DailyInterest(amount Money) (Money, error) {
+	value := new(big.Rat).SetInt64(amount.Minor)
+	value.Abs(value)
+	value.Mul(value, r.yearly)
+	value.Quo(value, big.NewRat(daysPerYear, 1))
+
+	minor, err := roundHalfAwayFromZero(value)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return NewMoney(minor, amount.Currency), nil
+}
+
+// OverdraftInterestEntryId identifies the interest entry of accountId for
+// day, so interest is charged at most once a day however often accrual runs.
+func OverdraftInterestEntryId(accountId string, day time.Time) string {
+	return "interest::" + accountId + "::" + day.UTC().Format(time.DateOnly)
+}
+
+// NewOverdraftInterestJournalEntry charges a day of overdraft interest to the
+// account as income of the bank.
+func NewOverdraftInterestJournalEntry(accountId string, day time.Time, interest Money) (*JournalEntry, error) {
+	entry, err := NewJournalEntry(JournalEntryInterest, accountId,
+		Debit(accountId, interest),
+		Credit(InternalLedgerAccount("overdraft-interest", interest.Currency), interest),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Id = OverdraftInterestEntryId(accountId, day)
+
+	return entry, nil
+}
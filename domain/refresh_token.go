@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is the stored state of an issued refresh token. Only a hash of
+// the token is kept. Tokens issued by rotating one another share a FamilyId,
+// so presenting an already rotated token can revoke the whole chain.
+type RefreshToken struct {
+	Id         string     `bson:"_id"`
+	UserId     string     `bson:"userId"`
+	FamilyId   string     `bson:"familyId"`
+	ExpiresAt  time.Time  `bson:"expiresAt"`
+	CreatedAt  time.Time  `bson:"createdAt"`
+	RevokedAt  *time.Time `bson:"revokedAt"`
+	ReplacedBy string     `bson:"replacedBy"`
+}
+
+// NewRefreshToken starts a new token family when familyId is empty.
+func NewRefreshToken(tokenHash, userId, familyId string, ttl time.Duration) *RefreshToken {
+	now := time.Now()
+
+	if familyId == "" {
+		familyId = uuid.New().String()
+	}
+
+	return &RefreshToken{
+		Id:        tokenHash,
+		UserId:    userId,
+		FamilyId:  familyId,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+}
+
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
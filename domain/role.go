@@ -0,0 +1,18 @@
+package domain
+
+type Role string
+
+const (
+	RoleCustomer Role = "CUSTOMER"
+	RoleTeller   Role = "TELLER"
+	RoleAdmin    Role = "ADMIN"
+)
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleCustomer, RoleTeller, RoleAdmin:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ScheduledTransferStatus string
+
+const (
+	ScheduledTransferStatusScheduled ScheduledTransferStatus = "SCHEDULED"
+	ScheduledTransferStatusExecuted  ScheduledTransferStatus = "EXECUTED"
+	ScheduledTransferStatusFailed    ScheduledTransferStatus = "FAILED"
+	ScheduledTransferStatusCancelled ScheduledTransferStatus = "CANCELLED"
+)
+
+var (
+	ErrScheduledTransferNotScheduled = errors.New("scheduled transfer is no longer scheduled")
+	ErrScheduledTransferNotDue       = errors.New("scheduled transfer is not due yet")
+	ErrScheduledTransferLeased       = errors.New("scheduled transfer is being executed")
+)
+
+// ScheduledTransfer is a transfer to be executed at ExecuteAt on behalf of
+// UserId. TransferId is fixed when it is scheduled and becomes the id of the
+// executed transfer, so however often execution is attempted the money moves
+// at most once.
+//
+// A worker executes it only while holding its lease: LeaseOwner is the worker
+// and LeaseExpiresAt when another worker may take over, should the first die
+// mid-way. Attempts counts the leases taken; FailureReason is the last error.
+type ScheduledTransfer struct {
+	Id                      string                  `bson:"_id"`
+	UserId                  string                  `bson:"userId"`
+	FromIban                string                  `bson:"fromIban"`
+	ToIban                  string                  `bson:"toIban"`
+	Amount                  Money                   `bson:"amount"`
+	AllowCurrencyConversion bool                    `bson:"allowCurrencyConversion"`
+	ExecuteAt               time.Time               `bson:"executeAt"`
+	Status                  ScheduledTransferStatus `bson:"status"`
+	TransferId              string                  `bson:"transferId"`
+	Attempts                int                     `bson:"attempts"`
+	FailureReason           string                  `bson:"failureReason"`
+	LeaseOwner              string                  `bson:"leaseOwner"`
+	LeaseExpiresAt          *time.Time              `bson:"leaseExpiresAt"`
+	CreatedAt               time.Time               `bson:"createdAt"`
+	UpdatedAt               time.Time               `bson:"updatedAt"`
+	ExecutedAt              *time.Time              `bson:"executedAt"`
+	CancelledAt             *time.Time              `bson:"cancelledAt"`
+}
+
+func NewScheduledTransfer(userId, fromIban, toIban string, amount Money, allowCurrencyConversion bool, executeAt time.Time) *ScheduledTransfer {
+	now := time.Now()
+
+	return &ScheduledTransfer{
+		Id:                      uuid.New().String(),
+		UserId:                  userId,
+		FromIban:                fromIban,
+		ToIban:                  toIban,
+		Amount:                  amount,
+		AllowCurrencyConversion: allowCurrencyConversion,
+		ExecuteAt:               executeAt,
+		Status:                  ScheduledTransferStatusScheduled,
+		TransferId:              uuid.New().String(),
+		CreatedAt:               now,
+		UpdatedAt:               now,
+	}
+}
+
+func (s *ScheduledTransfer) IsLeased(now time.Time) bool {
+	return s.LeaseExpiresAt != nil && now.Before(*s.LeaseExpiresAt)
+}
+
+// AcquireLease gives owner the right to execute the transfer for ttl.
+func (s *ScheduledTransfer) AcquireLease(owner string, now time.Time, ttl time.Duration) error {
+	if s.Status != ScheduledTransferStatusScheduled {
+		return fmt.Errorf("%w: %s", ErrScheduledTransferNotScheduled, s.Status)
+	}
+
+	if now.Before(s.ExecuteAt) {
+		return ErrScheduledTransferNotDue
+	}
+
+	if s.IsLeased(now) {
+		return ErrScheduledTransferLeased
+	}
+
+	expiresAt := now.Add(ttl)
+
+	s.LeaseOwner = owner
+	s.LeaseExpiresAt = &expiresAt
+	s.Attempts++
+	s.UpdatedAt = now
+
+	return nil
+}
+
+// HoldsLease reports whether owner may still record the outcome of the
+// execution it started: no other worker has taken the lease over since.
+func (s *ScheduledTransfer) HoldsLease(owner string) bool {
+	return s.LeaseOwner == owner
+}
+
+func (s *ScheduledTransfer) MarkExecuted(now time.Time) {
+	s.Status = ScheduledTransferStatusExecuted
+	s.FailureReason = ""
+	s.ExecutedAt = &now
+	s.releaseLease(now)
+}
+
+func (s *ScheduledTransfer) MarkFailed(reason string, now time.Time) {
+	s.Status = ScheduledTransferStatusFailed
+	s.FailureReason = reason
+	s.releaseLease(now)
+}
+
+// Retry gives up the lease after a failure that may not happen again, so the
+// transfer is attempted again on a later run.
+func (s *ScheduledTransfer) Retry(reason string, now time.Time) {
+	s.FailureReason = reason
+	s.releaseLease(now)
+}
+
+// Cancel stops the transfer from being executed. It is refused while a
+// worker is executing it.
+func (s *ScheduledTransfer) Cancel(now time.Time) error {
+	if s.Status != ScheduledTransferStatusScheduled {
+		return fmt.Errorf("%w: %s", ErrScheduledTransferNotScheduled, s.Status)
+	}
+
+	if s.IsLeased(now) {
+		return ErrScheduledTransferLeased
+	}
+
+	s.Status = ScheduledTransferStatusCancelled
+	s.CancelledAt = &now
+	s.UpdatedAt = now
+
+	return nil
+}
+
+func (s *ScheduledTransfer) releaseLease(now time.Time) {
+	s.LeaseOwner = ""
+	s.LeaseExpiresAt = nil
+	s.UpdatedAt = now
+}
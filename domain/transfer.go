@@ -0,0 +1,192 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TransferStatus string
+
+const (
+	TransferStatusPending   TransferStatus = "PENDING"
+	TransferStatusCompleted TransferStatus = "COMPLETED"
+	TransferStatusFailed    TransferStatus = "FAILED"
+)
+
+// TransferType tells transfers between accounts apart from cash deposits and
+// withdrawals, whose other side is the bank's cash account.
+type TransferType string
+
+const (
+	TransferTypeTransfer   TransferType = "TRANSFER"
+	TransferTypeDeposit    TransferType = "DEPOSIT"
+	TransferTypeWithdrawal TransferType = "WITHDRAWAL"
+)
+
+type CashChannelType string
+
+const (
+	CashChannelBranch CashChannelType = "BRANCH"
+	CashChannelAtm    CashChannelType = "ATM"
+)
+
+// CashChannel records where cash was paid in or out: the branch and the
+// teller who handled it, or the ATM.
+type CashChannel struct {
+	Type     CashChannelType `bson:"type"`
+	BranchId string          `bson:"branchId"`
+	AtmId    string          `bson:"atmId"`
+	TellerId string          `bson:"tellerId"`
+}
+
+type TransferDirection string
+
+const (
+	TransferDirectionIn  TransferDirection = "IN"
+	TransferDirectionOut TransferDirection = "OUT"
+)
+
+// Transfer is the persisted record of a money movement between two accounts.
+// The balances after the movement are captured when it completes, so history
+// can be shown without replaying the ledger.
+//
+// Amount is what leaves the source account, in its currency. When the
+// destination account holds another currency, ConvertedAmount is what reaches
+// it, converted at ExchangeRate, the rate offered after the spread; FxSpread
+// is what the bank kept and QuoteId the quote the rate came from, if any.
+// They are all empty for same-currency transfers.
+//
+// Deposits and withdrawals are transfers with the bank's cash account on the
+// other side, which has no IBAN; Channel records where the cash changed hands.
+// HoldId is the hold a transfer captured, if any.
+type Transfer struct {
+	Id               string         `bson:"_id"`
+	Type             TransferType   `bson:"type"`
+	FromAccountId    string         `bson:"fromAccountId"`
+	FromIban         string         `bson:"fromIban"`
+	ToAccountId      string         `bson:"toAccountId"`
+	ToIban           string         `bson:"toIban"`
+	Amount           Money          `bson:"amount"`
+	ConvertedAmount  *Money         `bson:"convertedAmount"`
+	ExchangeRate     *ExchangeRate  `bson:"exchangeRate"`
+	FxSpread         *Money         `bson:"fxSpread"`
+	QuoteId          string         `bson:"quoteId"`
+	Channel          *CashChannel   `bson:"channel"`
+	Reference        string         `bson:"reference"`
+	HoldId           string         `bson:"holdId"`
+	FromBalanceAfter *Money         `bson:"fromBalanceAfter"`
+	ToBalanceAfter   *Money         `bson:"toBalanceAfter"`
+	Status           TransferStatus `bson:"status"`
+	FailureReason    string         `bson:"failureReason"`
+	JournalEntryId   string         `bson:"journalEntryId"`
+	CreatedAt        time.Time      `bson:"createdAt"`
+	UpdatedAt        time.Time      `bson:"updatedAt"`
+	CompletedAt      *time.Time     `bson:"completedAt"`
+}
+
+func NewTransfer(fromAccountId, fromIban, toAccountId, toIban string, amount Money) *Transfer {
+	now := time.Now()
+
+	return &Transfer{
+		Id:            uuid.New().String(),
+		Type:          TransferTypeTransfer,
+		FromAccountId: fromAccountId,
+		FromIban:      fromIban,
+		ToAccountId:   toAccountId,
+		ToIban:        toIban,
+		Amount:        amount,
+		Status:        TransferStatusPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// NewDeposit pays amount into the account in cash.
+func NewDeposit(accountId, iban string, amount Money, channel CashChannel, reference string) *Transfer {
+	transfer := NewTransfer(InternalLedgerAccount("cash", amount.Currency), "", accountId, iban, amount)
+	transfer.Type = TransferTypeDeposit
+	transfer.Channel = &channel
+	transfer.Reference = reference
+
+	return transfer
+}
+
+// NewWithdrawal pays amount out of the account in cash.
+func NewWithdrawal(accountId, iban string, amount Money, channel CashChannel, reference string) *Transfer {
+	transfer := NewTransfer(accountId, iban, InternalLedgerAccount("cash", amount.Currency), "", amount)
+	transfer.Type = TransferTypeWithdrawal
+	transfer.Channel = &channel
+	transfer.Reference = reference
+
+	return transfer
+}
+
+// EffectiveType treats transfers stored before types existed as transfers
+// between accounts.
+func (t *Transfer) EffectiveType() TransferType {
+	if t.Type == "" {
+		return TransferTypeTransfer
+	}
+
+	return t.Type
+}
+
+// Convert makes t a cross-currency transfer priced at quote.
+func (t *Transfer) Convert(quote *FxQuote) error {
+	converted, spread, err := quote.Convert(t.Amount)
+	if err != nil {
+		return err
+	}
+
+	rate := quote.Rate
+
+	t.ExchangeRate = &rate
+	t.ConvertedAmount = &converted
+	t.FxSpread = &spread
+	t.QuoteId = quote.Id
+
+	return nil
+}
+
+// CreditAmount is the amount that reaches the destination account.
+func (t *Transfer) CreditAmount() Money {
+	if t.ConvertedAmount != nil {
+		return *t.ConvertedAmount
+	}
+
+	return t.Amount
+}
+
+// Complete marks t completed. A balance is nil for the bank's own accounts,
+// which do not keep one.
+func (t *Transfer) Complete(journalEntryId string, fromBalanceAfter, toBalanceAfter *Money) {
+	now := time.Now()
+
+	t.Status = TransferStatusCompleted
+	t.JournalEntryId = journalEntryId
+	t.FromBalanceAfter = fromBalanceAfter
+	t.ToBalanceAfter = toBalanceAfter
+	t.FailureReason = ""
+	t.UpdatedAt = now
+	t.CompletedAt = &now
+}
+
+func (t *Transfer) Fail(reason string) {
+	now := time.Now()
+
+	t.Status = TransferStatusFailed
+	t.FailureReason = reason
+	t.UpdatedAt = now
+	t.CompletedAt = &now
+}
+
+// DirectionFor reports whether the transfer moved money into or out of the
+// given account.
+func (t *Transfer) DirectionFor(accountId string) TransferDirection {
+	if t.FromAccountId == accountId {
+		return TransferDirectionOut
+	}
+
+	return TransferDirectionIn
+}
@@ -4,13 +4,63 @@ import (
 	"time"
 )
 
+type UserStatus string
+
+const (
+	UserStatusActive      UserStatus = "ACTIVE"
+	UserStatusBlocked     UserStatus = "BLOCKED"
+	UserStatusDeactivated UserStatus = "DEACTIVATED"
+	UserStatusDeleted     UserStatus = "DELETED"
+)
+
 type User struct {
-	Id        string    `bson:"_id"`
-	FirstName string    `bson:"firstName" validate:"required"`
-	LastName  string    `bson:"lastName" validate:"required"`
-	Email     string    `bson:"email" validate:"required,email"`
-	Password  string    `bson:"password" validate:"required,min=6"`
-	Age       int32     `bson:"age" validate:"gte=0,lte=130"`
-	CreatedAt time.Time `bson:"createdAt"`
-	UpdatedAt time.Time `bson:"updatedAt"`
+	Id        string `bson:"_id"`
+	FirstName string `bson:"firstName" validate:"required"`
+	LastName  string `bson:"lastName" validate:"required"`
+	Email     string `bson:"email" validate:"required,email"`
+	// CanonicalEmail is the normalized email uniqueness is enforced on.
+	CanonicalEmail string `bson:"canonicalEmail"`
+	Password       string `bson:"password" validate:"required,min=6"`
+	Age            int32  `bson:"age" validate:"gte=0,lte=130"`
+	BirthYear      int32  `bson:"birthYear"`
+	// NationalId is the plain TC Kimlik No. It is never written to the
+	// database; the repository stores EncryptedNationalId instead.
+	NationalId          string     `json:"-" bson:"-"`
+	EncryptedNationalId string     `bson:"encryptedNationalId"`
+	Role                Role       `bson:"role"`
+	Status              UserStatus `bson:"status"`
+	CreatedAt           time.Time  `bson:"createdAt"`
+	UpdatedAt           time.Time  `bson:"updatedAt"`
+	DeletedAt           *time.Time `bson:"deletedAt"`
+}
+
+// EffectiveRole treats users stored before roles existed as customers.
+func (u *User) EffectiveRole() Role {
+	if u.Role == "" {
+		return RoleCustomer
+	}
+
+	return u.Role
+}
+
+// IsActive reports whether the user may log in and open accounts; users
+// stored before statuses existed are active.
+func (u *User) IsActive() bool {
+	return u.Status == "" || u.Status == UserStatusActive
+}
+
+// AgeAt returns the age the user has surely reached at now, from the birth
+// year verified at registration. Only the year is known, so the birthday is
+// taken as not yet passed. ok is false for users stored without a birth year.
+func (u *User) AgeAt(now time.Time) (age int32, ok bool) {
+	if u.BirthYear <= 0 {
+		return 0, false
+	}
+
+	age = int32(now.Year()) - u.BirthYear - 1
+	if age < 0 {
+		age = 0
+	}
+
+	return age, true
 }
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserAgeAt(t *testing.T) {
+	now := time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		birthYear int32
+		age       int32
+		wantAge   int32
+		wantOk    bool
+	}{
+		{name: "birthday may not have passed", birthYear: 2008, wantAge: 17, wantOk: true},
+		{name: "self-declared age is ignored", birthYear: 2010, age: 30, wantAge: 15, wantOk: true},
+		{name: "born this year", birthYear: 2026, wantAge: 0, wantOk: true},
+		{name: "birth year in the future", birthYear: 2030, wantAge: 0, wantOk: true},
+		{name: "no birth year", birthYear: 0, age: 40, wantAge: 0, wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &User{BirthYear: tt.birthYear, Age: tt.age}
+			age, ok := user.AgeAt(now)
+
+			if age != tt.wantAge || ok != tt.wantOk {
+				t.Errorf("AgeAt() = %d, %v, want %d, %v", age, ok, tt.wantAge, tt.wantOk)
+			}
+		})
+	}
+}
@@ -1,105 +1,667 @@
 package rabbitmq
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
 	"go.uber.org/zap"
 )
 
+const (
+	retryCountHeader = "x-retry-count"
+	lastErrorHeader  = "x-last-error"
+
+	confirmTimeout    = 5 * time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+var ErrPublishNotConfirmed = errors.New("message was not confirmed by the broker")
+
 type IRabbitMQService interface {
 	Publish(exchange, routingKey string, body []byte) error
+	PublishWithConfirm(exchange, routingKey, messageId string, body []byte) error
 	Consume() (<-chan amqp.Delivery, error)
+	Retry(delivery amqp.Delivery, cause error) (bool, error)
+	DeadLetter(delivery amqp.Delivery, cause error) error
+	PeekDeadLetters(limit int) ([]DeadLetter, error)
+	ReplayDeadLetters(limit int) (int, error)
 	Close()
 }
 
+// DeadLetter is a message that exhausted its retries or could not be
+// processed at all.
+type DeadLetter struct {
+	MessageId  string
+	Body       []byte
+	RetryCount int
+	LastError  string
+	Timestamp  time.Time
+}
+
 type RabbitMQ struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	queue   amqp.Queue
+	url            string
+	conn           *amqp.Connection
+	connMu         sync.Mutex
+	closed         bool
+	confirmCh      *amqp.Channel
+	confirms       chan amqp.Confirmation
+	confirmTag     uint64
+	confirmMu      sync.Mutex
+	channel        *amqp.Channel
+	adminChannel   *amqp.Channel
+	adminMu        sync.Mutex
+	queueName      string
+	exchangeName   string
+	exchangeType   string
+	retryQueues    []string
+	retryBaseDelay time.Duration
+	deadLetters    string
+	dlxName        string
 }
 
-func NewRabbitMQ(url, queueName, exchangeName, exchangeType string) (*RabbitMQ, error) {
-	conn, err := amqp.Dial(url)
-	if err != nil {
+// NewRabbitMQ declares the work queue together with its retry and dead-letter
+// topology:
+//
+//   - <queue>.retry.<n>: one queue per attempt, holding a message for
+//     retryBaseDelay*2^(n-1) before dead-lettering it back to the exchange.
+//     A queue per attempt keeps a long delay from blocking shorter ones.
+//   - <exchange>.dlx / <queue>.dlq: where messages go after maxRetries.
+func NewRabbitMQ(url, queueName, exchangeName, exchangeType string, maxRetries int, retryBaseDelay time.Duration) (*RabbitMQ, error) {
+	retryQueues := make([]string, 0, maxRetries)
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		retryQueues = append(retryQueues, fmt.Sprintf("%s.retry.%d", queueName, attempt))
+	}
+
+	r := &RabbitMQ{
+		url:            url,
+		queueName:      queueName,
+		exchangeName:   exchangeName,
+		exchangeType:   exchangeType,
+		retryQueues:    retryQueues,
+		retryBaseDelay: retryBaseDelay,
+		deadLetters:    queueName + ".dlq",
+		dlxName:        exchangeName + ".dlx",
+	}
+
+	if err := r.connect(); err != nil {
 		return nil, err
 	}
 
-	ch, err := conn.Channel()
+	zap.L().Info("RabbitMQ connection established")
+
+	return r, nil
+}
+
+// connect opens the consumer and admin channels and declares the topology on
+// them, dialing a new connection when there is none or it has been closed.
+func (r *RabbitMQ) connect() error {
+	if r.conn == nil || r.conn.IsClosed() {
+		conn, err := amqp.Dial(r.url)
+		if err != nil {
+			return err
+		}
+
+		r.conn = conn
+	}
+
+	ch, err := r.conn.Channel()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	q, err := ch.QueueDeclare(
-		queueName, // name
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
+	if err := r.declareTopology(ch); err != nil {
+		ch.Close()
+		return err
+	}
+
+	// Inspecting and replaying the DLQ uses its own channel, so unacked
+	// admin deliveries never mix with the consumer's.
+	adminCh, err := r.conn.Channel()
+	if err != nil {
+		ch.Close()
+		return err
+	}
+
+	r.channel = ch
+	r.adminChannel = adminCh
+
+	return nil
+}
+
+func (r *RabbitMQ) declareTopology(ch *amqp.Channel) error {
+	_, err := ch.QueueDeclare(
+		r.queueName, // name
+		true,        // durable
+		false,       // delete when unused
+		false,       // exclusive
+		false,       // no-wait
+		nil,         // arguments
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	err = ch.ExchangeDeclare(
-		exchangeName, // name
-		exchangeType, // type
-		true,         // durable
-		false,        // auto-deleted
-		false,        // internal
-		false,        // no-wait
-		nil,          // arguments
+		r.exchangeName, // name
+		r.exchangeType, // type
+		true,           // durable
+		false,          // auto-deleted
+		false,          // internal
+		false,          // no-wait
+		nil,            // arguments
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	err = ch.QueueBind(
-		queueName,    // queue name
-		"",           // routing key
-		exchangeName, // exchange
+		r.queueName,    // queue name
+		"",             // routing key
+		r.exchangeName, // exchange
 		false,
 		nil,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	zap.L().Info("RabbitMQ connection established")
+	for i, retryQueueName := range r.retryQueues {
+		delay := r.retryBaseDelay * time.Duration(1<<i)
+
+		_, err = ch.QueueDeclare(
+			retryQueueName, // name
+			true,           // durable
+			false,          // delete when unused
+			false,          // exclusive
+			false,          // no-wait
+			amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    r.exchangeName,
+				"x-dead-letter-routing-key": "",
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ch.ExchangeDeclare(
+		r.dlxName, // name
+		"fanout",  // type
+		true,      // durable
+		false,     // auto-deleted
+		false,     // internal
+		false,     // no-wait
+		nil,       // arguments
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = ch.QueueDeclare(
+		r.deadLetters, // name
+		true,          // durable
+		false,         // delete when unused
+		false,         // exclusive
+		false,         // no-wait
+		nil,           // arguments
+	)
+	if err != nil {
+		return err
+	}
+
+	return ch.QueueBind(
+		r.deadLetters, // queue name
+		"",            // routing key
+		r.dlxName,     // exchange
+		false,
+		nil,
+	)
+}
+
+// reconnect replaces the consumer and admin channels after the connection or
+// one of them was closed.
+func (r *RabbitMQ) reconnect() error {
+	r.adminMu.Lock()
+	defer r.adminMu.Unlock()
+
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+
+	if r.closed {
+		return errors.New("rabbitmq connection closed")
+	}
+
+	if r.channel != nil {
+		r.channel.Close()
+	}
 
-	return &RabbitMQ{
-		conn:    conn,
-		channel: ch,
-		queue:   q,
-	}, nil
+	if r.adminChannel != nil {
+		r.adminChannel.Close()
+	}
+
+	return r.connect()
+}
+
+func (r *RabbitMQ) isClosed() bool {
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+
+	return r.closed
 }
 
 func (r *RabbitMQ) Publish(exchange, routingKey string, body []byte) error {
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+
 	err := r.channel.Publish(
 		exchange,   // exchange
 		routingKey, // routing key
 		false,      // mandatory
 		false,      // immediate
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    uuid.New().String(),
+			Timestamp:    time.Now(),
+			Body:         body,
 		})
 	return err
 }
 
+// PublishWithConfirm publishes on a channel in confirm mode and waits until
+// the broker has taken responsibility for the message. A lost connection is
+// re-established on the next call, so callers can simply retry after an
+// outage.
+func (r *RabbitMQ) PublishWithConfirm(exchange, routingKey, messageId string, body []byte) error {
+	return r.publishConfirmed(exchange, routingKey, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageId,
+		Timestamp:    time.Now(),
+		Body:         body,
+	})
+}
+
+func (r *RabbitMQ) publishConfirmed(exchange, routingKey string, publishing amqp.Publishing) error {
+	r.confirmMu.Lock()
+	defer r.confirmMu.Unlock()
+
+	if err := r.openConfirmChannel(); err != nil {
+		return err
+	}
+
+	err := r.confirmCh.Publish(
+		exchange,   // exchange
+		routingKey, // routing key
+		false,      // mandatory
+		false,      // immediate
+		publishing,
+	)
+	if err != nil {
+		r.resetConfirmChannel()
+		return err
+	}
+
+	r.confirmTag++
+	timeout := time.After(confirmTimeout)
+
+	for {
+		select {
+		case confirm, ok := <-r.confirms:
+			if !ok {
+				r.resetConfirmChannel()
+				return ErrPublishNotConfirmed
+			}
+
+			if confirm.DeliveryTag < r.confirmTag {
+				continue
+			}
+
+			if !confirm.Ack {
+				return ErrPublishNotConfirmed
+			}
+
+			return nil
+
+		case <-timeout:
+			// The confirmation may still arrive; dropping the channel keeps it
+			// from being mistaken for the next publish's.
+			r.resetConfirmChannel()
+			return fmt.Errorf("%w: timed out after %s", ErrPublishNotConfirmed, confirmTimeout)
+		}
+	}
+}
+
+// openConfirmChannel opens the confirm-mode channel if there is none, dialing
+// a new connection when the old one has been closed.
+func (r *RabbitMQ) openConfirmChannel() error {
+	if r.confirmCh != nil {
+		return nil
+	}
+
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+
+	if r.conn.IsClosed() {
+		conn, err := amqp.Dial(r.url)
+		if err != nil {
+			return err
+		}
+
+		r.conn = conn
+		zap.L().Info("RabbitMQ connection re-established")
+	}
+
+	ch, err := r.conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return err
+	}
+
+	r.confirmCh = ch
+	r.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	r.confirmTag = 0
+
+	return nil
+}
+
+func (r *RabbitMQ) resetConfirmChannel() {
+	if r.confirmCh != nil {
+		r.confirmCh.Close()
+	}
+
+	r.confirmCh = nil
+	r.confirms = nil
+}
+
+// Consume delivers messages that must be acknowledged by the caller, so a
+// crash mid-processing leaves the message on the queue. The returned channel
+// outlives the connection: when the broker connection or the consumer channel
+// is closed, the consumer is rebuilt on a new one, and the channel is only
+// closed by Close.
 func (r *RabbitMQ) Consume() (<-chan amqp.Delivery, error) {
+	msgs, closed, err := r.startConsumer()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make(chan amqp.Delivery)
+	go r.forwardDeliveries(msgs, closed, deliveries)
+
+	return deliveries, nil
+}
+
+func (r *RabbitMQ) startConsumer() (<-chan amqp.Delivery, chan *amqp.Error, error) {
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+
+	if err := r.channel.Qos(10, 0, false); err != nil {
+		return nil, nil, err
+	}
+
+	closed := r.channel.NotifyClose(make(chan *amqp.Error, 1))
+
 	msgs, err := r.channel.Consume(
-		r.queue.Name, // queue
-		"",           // consumer
-		true,         // auto-ack
-		false,        // exclusive
-		false,        // no-local
-		false,        // no-wait
-		nil,          // args
+		r.queueName, // queue
+		"",          // consumer
+		false,       // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // args
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return msgs, closed, nil
+}
+
+func (r *RabbitMQ) forwardDeliveries(msgs <-chan amqp.Delivery, closed chan *amqp.Error, deliveries chan<- amqp.Delivery) {
+	defer close(deliveries)
+
+	for {
+		for msg := range msgs {
+			deliveries <- msg
+		}
+
+		if r.isClosed() {
+			return
+		}
+
+		var cause error
+		select {
+		case amqpErr := <-closed:
+			if amqpErr != nil {
+				cause = amqpErr
+			}
+		default:
+		}
+
+		zap.L().Warn("RabbitMQ consumer stopped, reconnecting", zap.Error(cause))
+
+		var ok bool
+		if msgs, closed, ok = r.resumeConsuming(); !ok {
+			return
+		}
+	}
+}
+
+// resumeConsuming rebuilds the connection, channels and consumer, backing off
+// between attempts, until it succeeds or Close is called.
+func (r *RabbitMQ) resumeConsuming() (<-chan amqp.Delivery, chan *amqp.Error, bool) {
+	delay := time.Second
+
+	for {
+		if r.isClosed() {
+			return nil, nil, false
+		}
+
+		err := r.reconnect()
+		if err == nil {
+			msgs, closed, err := r.startConsumer()
+			if err == nil {
+				zap.L().Info("RabbitMQ consumer re-established")
+				return msgs, closed, true
+			}
+		}
+
+		zap.L().Warn("Failed to re-establish RabbitMQ consumer", zap.Duration("retryIn", delay), zap.Error(err))
+
+		time.Sleep(delay)
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// Retry schedules delivery for another attempt after its backoff delay and
+// acknowledges the original once the broker has confirmed the copy. Once the
+// retries are exhausted the message is dead-lettered instead and false is
+// returned.
+func (r *RabbitMQ) Retry(delivery amqp.Delivery, cause error) (bool, error) {
+	retryCount := retryCountOf(delivery.Headers)
+
+	if retryCount >= len(r.retryQueues) {
+		return false, r.DeadLetter(delivery, cause)
+	}
+
+	err := r.publishConfirmed(
+		"",                        // default exchange routes by queue name
+		r.retryQueues[retryCount], // routing key
+		republishing(delivery, retryCount+1, cause),
+	)
+	if err != nil {
+		return true, requeue(delivery, err)
+	}
+
+	return true, delivery.Ack(false)
+}
+
+// DeadLetter moves delivery to the dead-letter queue and acknowledges the
+// original once the broker has confirmed the copy.
+func (r *RabbitMQ) DeadLetter(delivery amqp.Delivery, cause error) error {
+	err := r.publishConfirmed(
+		r.dlxName, // exchange
+		"",        // routing key
+		republishing(delivery, retryCountOf(delivery.Headers), cause),
 	)
-	return msgs, err
+	if err != nil {
+		return requeue(delivery, err)
+	}
+
+	return delivery.Ack(false)
+}
+
+// requeue returns delivery to its queue after its copy could not be published,
+// so the message is not lost with it.
+func requeue(delivery amqp.Delivery, cause error) error {
+	if err := delivery.Nack(false, true); err != nil {
+		// The channel is gone, which puts the message back on the queue too.
+		zap.L().Warn("Failed to requeue message", zap.String("messageId", delivery.MessageId), zap.Error(err))
+	}
+
+	return cause
+}
+
+// PeekDeadLetters returns up to limit messages from the dead-letter queue
+// without removing them.
+func (r *RabbitMQ) PeekDeadLetters(limit int) ([]DeadLetter, error) {
+	r.adminMu.Lock()
+	defer r.adminMu.Unlock()
+
+	deadLetters := make([]DeadLetter, 0, limit)
+	var lastTag uint64
+
+	for len(deadLetters) < limit {
+		delivery, ok, err := r.adminChannel.Get(r.deadLetters, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			break
+		}
+
+		lastTag = delivery.DeliveryTag
+		deadLetters = append(deadLetters, toDeadLetter(delivery))
+	}
+
+	if lastTag != 0 {
+		if err := r.adminChannel.Nack(lastTag, true, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return deadLetters, nil
+}
+
+// ReplayDeadLetters publishes up to limit dead-lettered messages back to the
+// work exchange with a fresh retry budget.
+func (r *RabbitMQ) ReplayDeadLetters(limit int) (int, error) {
+	r.adminMu.Lock()
+	defer r.adminMu.Unlock()
+
+	replayed := 0
+
+	for replayed < limit {
+		delivery, ok, err := r.adminChannel.Get(r.deadLetters, false)
+		if err != nil {
+			return replayed, err
+		}
+
+		if !ok {
+			break
+		}
+
+		publishing := republishing(delivery, 0, nil)
+
+		if err := r.publishConfirmed(r.exchangeName, "", publishing); err != nil {
+			_ = delivery.Nack(false, true)
+			return replayed, err
+		}
+
+		if err := delivery.Ack(false); err != nil {
+			return replayed, err
+		}
+
+		replayed++
+	}
+
+	return replayed, nil
 }
 
 func (r *RabbitMQ) Close() {
+	r.confirmMu.Lock()
+	r.resetConfirmChannel()
+	r.confirmMu.Unlock()
+
+	r.adminMu.Lock()
+	defer r.adminMu.Unlock()
+
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
+
+	r.closed = true
+	r.adminChannel.Close()
 	r.channel.Close()
 	r.conn.Close()
 }
+
+func republishing(delivery amqp.Delivery, retryCount int, cause error) amqp.Publishing {
+	headers := amqp.Table{}
+	for key, value := range delivery.Headers {
+		headers[key] = value
+	}
+
+	headers[retryCountHeader] = int32(retryCount)
+
+	if cause != nil {
+		headers[lastErrorHeader] = cause.Error()
+	} else {
+		delete(headers, lastErrorHeader)
+	}
+
+	return amqp.Publishing{
+		Headers:      headers,
+		ContentType:  delivery.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    delivery.MessageId,
+		Timestamp:    delivery.Timestamp,
+		Body:         delivery.Body,
+	}
+}
+
+func retryCountOf(headers amqp.Table) int {
+	switch count := headers[retryCountHeader].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case string:
+		parsed, _ := strconv.Atoi(count)
+		return parsed
+	default:
+		return 0
+	}
+}
+
+func toDeadLetter(delivery amqp.Delivery) DeadLetter {
+	lastError, _ := delivery.Headers[lastErrorHeader].(string)
+
+	return DeadLetter{
+		MessageId:  delivery.MessageId,
+		Body:       delivery.Body,
+		RetryCount: retryCountOf(delivery.Headers),
+		LastError:  lastError,
+		Timestamp:  delivery.Timestamp,
+	}
+}
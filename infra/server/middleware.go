@@ -1,11 +1,72 @@
 package server
 
 import (
+	"kc-bank/pkg/auth"
+	"kc-bank/pkg/services"
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
-func InitMiddlewares(app *fiber.App) {
+// publicRoutes under /api/v1 that can be called without an access token.
+var publicRoutes = map[string]bool{
+	"POST /api/v1/auth/login":   true,
+	"POST /api/v1/auth/refresh": true,
+	"POST /api/v1/auth/logout":  true,
+	"POST /api/v1/user":         true,
+}
+
+func InitMiddlewares(app *fiber.App, tokenService services.ITokenService) {
 	// Recover Middleware
 	app.Use(recover.New())
+
+	// Authentication Middleware
+	app.Use("/api/v1", Authenticate(tokenService))
+}
+
+// Authenticate requires a valid bearer access token and makes its principal
+// available through auth.PrincipalFromContext.
+func Authenticate(tokenService services.ITokenService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if publicRoutes[c.Method()+" "+strings.TrimSuffix(c.Path(), "/")] {
+			return c.Next()
+		}
+
+		scheme, token, found := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "missing bearer token"})
+		}
+
+		principal, err := tokenService.ParseAccessToken(token)
+		if err != nil {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or expired access token"})
+		}
+
+		c.SetUserContext(auth.WithPrincipal(c.UserContext(), principal))
+
+		return c.Next()
+	}
+}
+
+// RequirePermission lets a request through only if its principal has every
+// one of permissions. It is declared per route in InitRouters, after
+// Authenticate has run.
+func RequirePermission(permissions ...auth.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := auth.PrincipalFromContext(c.UserContext())
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "authentication required"})
+		}
+
+		for _, permission := range permissions {
+			if !principal.Can(permission) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "you do not have permission to perform this action"})
+			}
+		}
+
+		return c.Next()
+	}
 }
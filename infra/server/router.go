@@ -2,8 +2,14 @@ package server
 
 import (
 	"kc-bank/app/controllers/account"
+	"kc-bank/app/controllers/admin"
+	"kc-bank/app/controllers/auth"
+	"kc-bank/app/controllers/fx"
 	"kc-bank/app/controllers/healthcheck"
+	"kc-bank/app/controllers/hold"
+	"kc-bank/app/controllers/transfer"
 	"kc-bank/app/controllers/user"
+	pkgauth "kc-bank/pkg/auth"
 	"kc-bank/pkg/handler"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,30 +18,111 @@ import (
 func InitRouters(app *fiber.App,
 	getUserHandler *user.GetUserHandler,
 	createUserHandler *user.CreateUserHandler,
+	updateUserHandler *user.UpdateUserHandler,
+	changePasswordHandler *user.ChangePasswordHandler,
+	verifyIdentityHandler *user.VerifyIdentityHandler,
+	deactivateUserHandler *user.DeactivateUserHandler,
+	deleteUserHandler *user.DeleteUserHandler,
 	getUserAllHandler *user.GetUserAllHandler,
 	healthcheckHandler *healthcheck.HealthCheckHandler,
 	getAccountHandler *account.GetAccountHandler,
 	getAccountAllHandler *account.GetAccountAllHandler,
+	getAccountLedgerHandler *account.GetAccountLedgerHandler,
+	getAccountTransactionsHandler *account.GetAccountTransactionsHandler,
 	createAccountHandler *account.CreateAccountHandler,
 	transferMoneyHandler *account.TransferMoneyHandler,
 	transferMoneyWithRabbitMQHandler *account.TransferMoneyWithRabbitMQHandler,
+	depositHandler *account.DepositHandler,
+	withdrawHandler *account.WithdrawHandler,
+	getTransferHandler *transfer.GetTransferHandler,
+	scheduleTransferHandler *transfer.ScheduleTransferHandler,
+	getScheduledTransfersHandler *transfer.GetScheduledTransfersHandler,
+	getScheduledTransferHandler *transfer.GetScheduledTransferHandler,
+	cancelScheduledTransferHandler *transfer.CancelScheduledTransferHandler,
+	createHoldHandler *hold.CreateHoldHandler,
+	getAccountHoldsHandler *hold.GetAccountHoldsHandler,
+	getHoldHandler *hold.GetHoldHandler,
+	captureHoldHandler *hold.CaptureHoldHandler,
+	releaseHoldHandler *hold.ReleaseHoldHandler,
+	createQuoteHandler *fx.CreateQuoteHandler,
+	getDeadLettersHandler *admin.GetDeadLettersHandler,
+	replayDeadLettersHandler *admin.ReplayDeadLettersHandler,
+	loginHandler *auth.LoginHandler,
+	refreshTokenHandler *auth.RefreshTokenHandler,
+	logoutHandler *auth.LogoutHandler,
+	changeUserRoleHandler *admin.ChangeUserRoleHandler,
+	changeAccountStatusHandler *admin.ChangeAccountStatusHandler,
+	setOverdraftLimitHandler *admin.SetOverdraftLimitHandler,
+	getOverdraftBreachesHandler *admin.GetOverdraftBreachesHandler,
+	idempotencyStore handler.IdempotencyStore,
 ) {
+	idempotent := handler.WithIdempotency(idempotencyStore)
 
 	app.Get("/healthcheck", handler.Handle[healthcheck.HealthCheckRequest, healthcheck.HealthCheckResponse](healthcheckHandler))
 
+	// Auth
+	authGroup := app.Group("/api/v1/auth")
+
+	authGroup.Post("/login", handler.Handle[auth.LoginRequest, auth.LoginResponse](loginHandler))
+	authGroup.Post("/refresh", handler.Handle[auth.RefreshTokenRequest, auth.RefreshTokenResponse](refreshTokenHandler))
+	authGroup.Post("/logout", handler.Handle[auth.LogoutRequest, auth.LogoutResponse](logoutHandler))
+
 	// User
 	userGroup := app.Group("/api/v1/user")
 
-	userGroup.Get("/", handler.Handle[user.GetUserAllRequest, user.GetUserAllResponse](getUserAllHandler))
+	userGroup.Get("/", RequirePermission(pkgauth.PermissionUserList), handler.Handle[user.GetUserAllRequest, user.GetUserAllResponse](getUserAllHandler))
 	userGroup.Get("/:id", handler.Handle[user.GetUserRequest, user.GetUserResponse](getUserHandler))
-	userGroup.Post("/", handler.Handle[user.CreateUserRequest, user.CreateUserResponse](createUserHandler))
+	userGroup.Post("/", handler.Handle[user.CreateUserRequest, user.CreateUserResponse](createUserHandler, idempotent))
+	userGroup.Patch("/:id", handler.Handle[user.UpdateUserRequest, user.UpdateUserResponse](updateUserHandler))
+	userGroup.Post("/:id/password", handler.Handle[user.ChangePasswordRequest, user.ChangePasswordResponse](changePasswordHandler))
+	userGroup.Post("/:id/identity", handler.Handle[user.VerifyIdentityRequest, user.VerifyIdentityResponse](verifyIdentityHandler))
+	userGroup.Post("/:id/deactivate", handler.Handle[user.DeactivateUserRequest, user.DeactivateUserResponse](deactivateUserHandler))
+	userGroup.Delete("/:id", handler.Handle[user.DeleteUserRequest, user.DeleteUserResponse](deleteUserHandler))
 
 	// Account
 	accountGroup := app.Group("/api/v1/account")
 
 	accountGroup.Get("/", handler.Handle[account.GetAccountAllRequest, account.GetAccountAllResponse](getAccountAllHandler))
 	accountGroup.Get("/:id", handler.Handle[account.GetAccountRequest, account.GetAccountResponse](getAccountHandler))
-	accountGroup.Post("/", handler.Handle[account.CreateAccountRequest, account.CreateAccountResponse](createAccountHandler))
-	accountGroup.Post("/transfer-money", handler.Handle[account.TransferMoneyRequest, account.TransferMoneyResponse](transferMoneyHandler))
-	accountGroup.Post("/transfer-money-with-rmq", handler.Handle[account.TransferMoneyWithRabbitMQRequest, account.TransferMoneyWithRabbitMQResponse](transferMoneyWithRabbitMQHandler))
+	accountGroup.Get("/:id/ledger", handler.Handle[account.GetAccountLedgerRequest, account.GetAccountLedgerResponse](getAccountLedgerHandler))
+	accountGroup.Get("/:id/transactions", handler.Handle[account.GetAccountTransactionsRequest, account.GetAccountTransactionsResponse](getAccountTransactionsHandler))
+	accountGroup.Post("/", handler.Handle[account.CreateAccountRequest, account.CreateAccountResponse](createAccountHandler, idempotent))
+	accountGroup.Post("/transfer-money", handler.Handle[account.TransferMoneyRequest, account.TransferMoneyResponse](transferMoneyHandler, idempotent))
+	accountGroup.Post("/transfer-money-with-rmq", handler.Handle[account.TransferMoneyWithRabbitMQRequest, account.TransferMoneyWithRabbitMQResponse](transferMoneyWithRabbitMQHandler, idempotent))
+	accountGroup.Post("/:id/deposit", RequirePermission(pkgauth.PermissionDepositCreate), handler.Handle[account.DepositRequest, account.DepositResponse](depositHandler, idempotent))
+	accountGroup.Post("/:id/withdraw", RequirePermission(pkgauth.PermissionWithdrawalCreate), handler.Handle[account.WithdrawRequest, account.WithdrawResponse](withdrawHandler, idempotent))
+
+	// Transfer
+	transferGroup := app.Group("/api/v1/transfers")
+
+	transferGroup.Post("/scheduled", handler.Handle[transfer.ScheduleTransferRequest, transfer.ScheduleTransferResponse](scheduleTransferHandler, idempotent))
+	transferGroup.Get("/scheduled", handler.Handle[transfer.GetScheduledTransfersRequest, transfer.GetScheduledTransfersResponse](getScheduledTransfersHandler))
+	transferGroup.Get("/scheduled/:id", handler.Handle[transfer.GetScheduledTransferRequest, transfer.GetScheduledTransferResponse](getScheduledTransferHandler))
+	transferGroup.Post("/scheduled/:id/cancel", handler.Handle[transfer.CancelScheduledTransferRequest, transfer.CancelScheduledTransferResponse](cancelScheduledTransferHandler))
+	transferGroup.Get("/:id", handler.Handle[transfer.GetTransferRequest, transfer.GetTransferResponse](getTransferHandler))
+
+	// Hold
+	accountGroup.Post("/:id/holds", RequirePermission(pkgauth.PermissionHoldManage), handler.Handle[hold.CreateHoldRequest, hold.CreateHoldResponse](createHoldHandler, idempotent))
+	accountGroup.Get("/:id/holds", handler.Handle[hold.GetAccountHoldsRequest, hold.GetAccountHoldsResponse](getAccountHoldsHandler))
+
+	holdGroup := app.Group("/api/v1/holds")
+
+	holdGroup.Get("/:id", handler.Handle[hold.GetHoldRequest, hold.GetHoldResponse](getHoldHandler))
+	holdGroup.Post("/:id/capture", RequirePermission(pkgauth.PermissionHoldManage), handler.Handle[hold.CaptureHoldRequest, hold.CaptureHoldResponse](captureHoldHandler, idempotent))
+	holdGroup.Post("/:id/release", RequirePermission(pkgauth.PermissionHoldManage), handler.Handle[hold.ReleaseHoldRequest, hold.ReleaseHoldResponse](releaseHoldHandler))
+
+	// FX
+	fxGroup := app.Group("/api/v1/fx")
+
+	fxGroup.Post("/quotes", handler.Handle[fx.CreateQuoteRequest, fx.CreateQuoteResponse](createQuoteHandler))
+
+	// Admin
+	adminGroup := app.Group("/api/v1/admin")
+
+	adminGroup.Get("/dlq", RequirePermission(pkgauth.PermissionDeadLetterManage), handler.Handle[admin.GetDeadLettersRequest, admin.GetDeadLettersResponse](getDeadLettersHandler))
+	adminGroup.Post("/dlq/replay", RequirePermission(pkgauth.PermissionDeadLetterManage), handler.Handle[admin.ReplayDeadLettersRequest, admin.ReplayDeadLettersResponse](replayDeadLettersHandler))
+	adminGroup.Patch("/users/:id/role", RequirePermission(pkgauth.PermissionUserManageRoles), handler.Handle[admin.ChangeUserRoleRequest, admin.ChangeUserRoleResponse](changeUserRoleHandler))
+	adminGroup.Patch("/accounts/:id/status", RequirePermission(pkgauth.PermissionAccountFreeze), handler.Handle[admin.ChangeAccountStatusRequest, admin.ChangeAccountStatusResponse](changeAccountStatusHandler))
+	adminGroup.Put("/accounts/:id/overdraft", RequirePermission(pkgauth.PermissionAccountOverdraft), handler.Handle[admin.SetOverdraftLimitRequest, admin.SetOverdraftLimitResponse](setOverdraftLimitHandler))
+	adminGroup.Get("/overdraft-breaches", RequirePermission(pkgauth.PermissionAccountOverdraft), handler.Handle[admin.GetOverdraftBreachesRequest, admin.GetOverdraftBreachesResponse](getOverdraftBreachesHandler))
 }
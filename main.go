@@ -1,16 +1,36 @@
 package main
 
 import (
+	"context"
+	"os"
+
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
 	accountController "kc-bank/app/controllers/account"
+	adminController "kc-bank/app/controllers/admin"
+	authController "kc-bank/app/controllers/auth"
+	fxController "kc-bank/app/controllers/fx"
 	"kc-bank/app/controllers/healthcheck"
+	holdController "kc-bank/app/controllers/hold"
+	transferController "kc-bank/app/controllers/transfer"
 	userController "kc-bank/app/controllers/user"
 	"kc-bank/app/repository"
 	accountCommand "kc-bank/app/services/account/command"
 	accountQuery "kc-bank/app/services/account/query"
+	authCommand "kc-bank/app/services/auth/command"
+	fxCommand "kc-bank/app/services/fx/command"
+	"kc-bank/app/services/hold"
+	holdCommand "kc-bank/app/services/hold/command"
+	holdQuery "kc-bank/app/services/hold/query"
+	"kc-bank/app/services/outbox"
+	"kc-bank/app/services/overdraft"
+	"kc-bank/app/services/scheduled"
+	scheduledCommand "kc-bank/app/services/scheduled/command"
+	scheduledQuery "kc-bank/app/services/scheduled/query"
 	userCommand "kc-bank/app/services/user/command"
 	userQuery "kc-bank/app/services/user/query"
+	"kc-bank/domain"
 	"kc-bank/infra/couchbase"
 	"kc-bank/infra/rabbitmq"
 	"kc-bank/infra/server"
@@ -30,6 +50,8 @@ func main() {
 		appConfig.RabbitMQTransferMoneyQueueName,
 		appConfig.RabbitMQTransferMoneyExchangeName,
 		appConfig.RabbitMQTransferMoneyExchangeType,
+		appConfig.RabbitMQTransferMoneyMaxRetries,
+		appConfig.RabbitMQTransferMoneyRetryDelay,
 	)
 
 	if err != nil {
@@ -51,32 +73,208 @@ func main() {
 	// Initialize user bucket
 	userBucket := cb.InitializeBucket("users")
 
-	// Initialize user bucket
+	// Initialize account bucket
 	accountBucket := cb.InitializeBucket("accounts")
 
+	// Initialize ledger bucket
+	ledgerBucket := cb.InitializeBucket("ledger")
+
+	// Initialize transfer bucket
+	transferBucket := cb.InitializeBucket("transfers")
+
+	// Initialize lookup bucket, which enforces unique values such as IBANs
+	lookupBucket := cb.InitializeBucket("lookups")
+
+	// Initialize outbox bucket
+	outboxBucket := cb.InitializeBucket("outbox")
+
+	// Initialize refresh token bucket
+	refreshTokenBucket := cb.InitializeBucket("refresh_tokens")
+
+	// Initialize audit bucket
+	auditBucket := cb.InitializeBucket("audit")
+
+	// Initialize hold bucket
+	holdBucket := cb.InitializeBucket("holds")
+
+	// Initialize scheduled transfer bucket
+	scheduledTransferBucket := cb.InitializeBucket("scheduled_transfers")
+
+	// Initialize fx quote bucket
+	fxQuoteBucket := cb.InitializeBucket("fx_quotes")
+
+	// Initialize idempotency bucket
+	idempotencyBucket := cb.InitializeBucket("idempotency")
+
+	idempotencyRepository := repository.NewIdempotencyRepository(idempotencyBucket, appConfig.IdempotencyKeyTTL, appConfig.IdempotencyLeaseTTL)
+
 	// Dependency Injection for User
-	userRepository := repository.NewUserRepository(cluster, userBucket)
+	fieldCipher, err := services.NewFieldCipher(appConfig.FieldEncryptionKey, appConfig.FieldIndexKey)
+
+	if err != nil {
+		zap.L().Fatal("failed to initialize field encryption", zap.Error(err))
+	}
+
+	identityVerifier, err := services.NewIdentityVerifier(appConfig.IdentityVerifier)
+
+	if err != nil {
+		zap.L().Fatal("failed to initialize identity verifier", zap.Error(err))
+	}
+
+	userRepository := repository.NewUserRepository(cluster, userBucket, accountBucket, lookupBucket, auditBucket, fieldCipher)
+	accountRepository := repository.NewAccountRepository(cluster, accountBucket, userBucket, ledgerBucket, transferBucket, lookupBucket, auditBucket, scheduledTransferBucket)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(cluster, refreshTokenBucket)
 	passwordService := services.NewPasswordService()
-	userCommand := userCommand.NewCommandHandler(userRepository, passwordService)
+	emailNormalizer := services.EmailNormalizer{StripPlusAddressing: appConfig.EmailStripPlusAddressing}
+	userCommand := userCommand.NewCommandHandler(userRepository, accountRepository, refreshTokenRepository, passwordService, emailNormalizer, identityVerifier)
 	userQuery := userQuery.NewUserQueryService(userRepository)
 
+	if backfilled, err := userRepository.BackfillEmailLookups(context.Background(), emailNormalizer.Canonical); err != nil {
+		zap.L().Error("Failed to backfill email lookups", zap.Error(err))
+	} else if backfilled > 0 {
+		zap.L().Info("Backfilled email lookups", zap.Int("count", backfilled))
+	}
+
+	if appConfig.BootstrapAdminEmail != "" {
+		if err := userCommand.PromoteToAdmin(context.Background(), appConfig.BootstrapAdminEmail); err != nil {
+			zap.L().Error("Failed to promote bootstrap admin", zap.String("email", appConfig.BootstrapAdminEmail), zap.Error(err))
+		}
+	}
+
+	// Dependency Injection for Auth
+	tokenService, err := services.NewTokenService(services.TokenConfig{
+		Algorithm:      appConfig.JwtAlgorithm,
+		Secret:         appConfig.JwtSecret,
+		PrivateKeyPath: appConfig.JwtPrivateKeyPath,
+		PublicKeyPath:  appConfig.JwtPublicKeyPath,
+		Issuer:         appConfig.JwtIssuer,
+		AccessTokenTTL: appConfig.AccessTokenTTL,
+	})
+
+	if err != nil {
+		zap.L().Fatal("failed to initialize token service", zap.Error(err))
+	}
+
+	authCommand := authCommand.NewCommandHandler(userRepository, refreshTokenRepository, passwordService, tokenService, emailNormalizer, appConfig.RefreshTokenTTL)
+
 	// Dependency Injection for Account
-	accountRepository := repository.NewAccountRepository(cluster, accountBucket)
+	ledgerRepository := repository.NewLedgerRepository(cluster, ledgerBucket)
+	transferRepository := repository.NewTransferRepository(cluster, transferBucket, outboxBucket)
+	outboxRepository := repository.NewOutboxRepository(cluster, outboxBucket)
+	workerId := newWorkerId()
+
+	if migrated, err := accountRepository.MigrateLegacyBalances(context.Background()); err != nil {
+		zap.L().Error("Failed to migrate legacy account balances", zap.Error(err))
+	} else if migrated > 0 {
+		zap.L().Info("Migrated legacy account balances", zap.Int("count", migrated))
+	}
+
 	ibanService := services.NewIbanService()
-	accountCommand := accountCommand.NewCommandHandler(accountRepository, ibanService, rmq, appConfig.RabbitMQTransferMoneyExchangeName)
-	accountQuery := accountQuery.NewAccountQueryService(accountRepository)
+
+	// Accounts opened before IBANs carried check digits all hold TR IBANs.
+	generateIban := func() (string, error) { return ibanService.GenerateIBAN("TR") }
+
+	if backfilled, err := accountRepository.BackfillIbanLookups(context.Background(), ibanService.ValidateIBAN, generateIban); err != nil {
+		zap.L().Error("Failed to backfill IBAN lookups", zap.Error(err))
+	} else if backfilled > 0 {
+		zap.L().Info("Backfilled IBAN lookups", zap.Int("count", backfilled))
+	}
+
+	exchangeRateProvider, err := services.NewExchangeRateProvider(appConfig.ExchangeRateProvider, appConfig.ExchangeRateFile)
+
+	if err != nil {
+		zap.L().Fatal("failed to initialize exchange rate provider", zap.Error(err))
+	}
+
+	fxPricer := services.NewFxPricer(exchangeRateProvider, appConfig.FxSpreadBps, appConfig.FxQuoteTTL)
+	fxQuoteRepository := repository.NewFxQuoteRepository(fxQuoteBucket)
+
+	accountPolicy := accountCommand.AccountPolicy{
+		MinimumOwnerAge:        appConfig.AccountMinimumOwnerAge,
+		MaxAccountsPerCurrency: appConfig.MaxAccountsPerCurrency,
+	}
+	accountCommand := accountCommand.NewCommandHandler(accountRepository, transferRepository, userRepository, accountPolicy, ibanService, fxQuoteRepository, fxPricer, rmq, appConfig.RabbitMQTransferMoneyExchangeName)
+	accountQuery := accountQuery.NewAccountQueryService(accountRepository, ledgerRepository, transferRepository)
+	fxCommand := fxCommand.NewCommandHandler(fxQuoteRepository, fxPricer)
+	outboxRelay := outbox.NewRelay(outboxRepository, rmq, workerId, appConfig.OutboxRelayLeaseTTL, appConfig.OutboxRelayInterval, appConfig.OutboxRelayBatchSize)
+
+	holdRepository := repository.NewHoldRepository(cluster, holdBucket, accountBucket, ledgerBucket, transferBucket)
+	holdPolicy := holdCommand.HoldPolicy{
+		DefaultTTL: appConfig.HoldDefaultTTL,
+		MaxTTL:     appConfig.HoldMaxTTL,
+	}
+	holdCommand := holdCommand.NewCommandHandler(holdRepository, accountRepository, holdPolicy)
+	holdQuery := holdQuery.NewHoldQueryService(holdRepository, accountRepository)
+	holdSweeper := hold.NewSweeper(holdCommand, appConfig.HoldSweepInterval, appConfig.HoldSweepBatchSize)
+
+	scheduledTransferRepository := repository.NewScheduledTransferRepository(cluster, scheduledTransferBucket)
+	schedulePolicy := scheduledCommand.SchedulePolicy{
+		MaxHorizon:  appConfig.ScheduledTransferMaxHorizon,
+		LeaseTTL:    appConfig.ScheduledTransferLeaseTTL,
+		MaxAttempts: appConfig.ScheduledTransferMaxAttempts,
+	}
+	scheduledCommand := scheduledCommand.NewCommandHandler(scheduledTransferRepository, accountRepository, transferRepository, userRepository, accountCommand, ibanService, schedulePolicy)
+	scheduledQuery := scheduledQuery.NewScheduledTransferQueryService(scheduledTransferRepository)
+	transferScheduler := scheduled.NewScheduler(scheduledCommand, workerId, appConfig.ScheduledTransferInterval, appConfig.ScheduledTransferBatchSize)
+
+	overdraftInterestRate, err := domain.NewInterestRate(appConfig.OverdraftInterestRate)
+
+	if err != nil {
+		zap.L().Fatal("invalid overdraft interest rate", zap.Error(err))
+	}
+
+	overdraftInterest := overdraft.NewInterestAccrual(accountRepository, overdraftInterestRate, appConfig.OverdraftInterestInterval)
 
 	// Initialize controllers for User
 	getUserHandler := userController.NewGetUserHandler(userQuery)
 	getUserAllHandler := userController.NewGetUserAllHandler(userQuery)
 	createUserHandler := userController.NewCreateUserHandler(userCommand)
+	updateUserHandler := userController.NewUpdateUserHandler(userCommand)
+	changePasswordHandler := userController.NewChangePasswordHandler(userCommand)
+	verifyIdentityHandler := userController.NewVerifyIdentityHandler(userCommand)
+	deactivateUserHandler := userController.NewDeactivateUserHandler(userCommand)
+	deleteUserHandler := userController.NewDeleteUserHandler(userCommand)
 
 	// Initialize controllers for Account
 	getAccountHandler := accountController.NewGetAccountHandler(accountQuery)
 	getAccountAllHandler := accountController.NewGetAccountAllHandler(accountQuery)
+	getAccountLedgerHandler := accountController.NewGetAccountLedgerHandler(accountQuery)
+	getAccountTransactionsHandler := accountController.NewGetAccountTransactionsHandler(accountQuery)
 	createAccountHandler := accountController.NewCreateAccountHandler(accountCommand)
 	transferMoneyHandler := accountController.NewTransferMoneyHandler(accountCommand)
 	transferMoneyWithRabbitMQHandler := accountController.NewTransferMoneyWithRabbitMQHandler(accountCommand)
+	depositHandler := accountController.NewDepositHandler(accountCommand)
+	withdrawHandler := accountController.NewWithdrawHandler(accountCommand)
+
+	// Initialize controllers for Transfer
+	getTransferHandler := transferController.NewGetTransferHandler(accountQuery)
+	scheduleTransferHandler := transferController.NewScheduleTransferHandler(scheduledCommand)
+	getScheduledTransfersHandler := transferController.NewGetScheduledTransfersHandler(scheduledQuery)
+	getScheduledTransferHandler := transferController.NewGetScheduledTransferHandler(scheduledQuery)
+	cancelScheduledTransferHandler := transferController.NewCancelScheduledTransferHandler(scheduledCommand)
+
+	// Initialize controllers for Hold
+	createHoldHandler := holdController.NewCreateHoldHandler(holdCommand)
+	getAccountHoldsHandler := holdController.NewGetAccountHoldsHandler(holdQuery)
+	getHoldHandler := holdController.NewGetHoldHandler(holdQuery)
+	captureHoldHandler := holdController.NewCaptureHoldHandler(holdCommand)
+	releaseHoldHandler := holdController.NewReleaseHoldHandler(holdCommand)
+
+	// Initialize controllers for FX
+	createQuoteHandler := fxController.NewCreateQuoteHandler(fxCommand)
+
+	// Initialize controllers for Admin
+	getDeadLettersHandler := adminController.NewGetDeadLettersHandler(accountCommand)
+	replayDeadLettersHandler := adminController.NewReplayDeadLettersHandler(accountCommand)
+	changeUserRoleHandler := adminController.NewChangeUserRoleHandler(userCommand)
+	changeAccountStatusHandler := adminController.NewChangeAccountStatusHandler(accountCommand)
+	setOverdraftLimitHandler := adminController.NewSetOverdraftLimitHandler(accountCommand)
+	getOverdraftBreachesHandler := adminController.NewGetOverdraftBreachesHandler(accountQuery)
+
+	// Initialize controllers for Auth
+	loginHandler := authController.NewLoginHandler(authCommand)
+	refreshTokenHandler := authController.NewRefreshTokenHandler(authCommand)
+	logoutHandler := authController.NewLogoutHandler(authCommand)
 
 	// Initialize healthcheck handler
 	healthcheckHandler := healthcheck.NewHealthCheckHandler()
@@ -85,20 +283,50 @@ func main() {
 	app := server.Init()
 
 	// Init middlewares
-	server.InitMiddlewares(app)
+	server.InitMiddlewares(app, tokenService)
 
 	// Init routers
 	server.InitRouters(
 		app,
 		getUserHandler,
 		createUserHandler,
+		updateUserHandler,
+		changePasswordHandler,
+		verifyIdentityHandler,
+		deactivateUserHandler,
+		deleteUserHandler,
 		getUserAllHandler,
 		healthcheckHandler,
 		getAccountHandler,
 		getAccountAllHandler,
+		getAccountLedgerHandler,
+		getAccountTransactionsHandler,
 		createAccountHandler,
 		transferMoneyHandler,
 		transferMoneyWithRabbitMQHandler,
+		depositHandler,
+		withdrawHandler,
+		getTransferHandler,
+		scheduleTransferHandler,
+		getScheduledTransfersHandler,
+		getScheduledTransferHandler,
+		cancelScheduledTransferHandler,
+		createHoldHandler,
+		getAccountHoldsHandler,
+		getHoldHandler,
+		captureHoldHandler,
+		releaseHoldHandler,
+		createQuoteHandler,
+		getDeadLettersHandler,
+		replayDeadLettersHandler,
+		loginHandler,
+		refreshTokenHandler,
+		logoutHandler,
+		changeUserRoleHandler,
+		changeAccountStatusHandler,
+		setOverdraftLimitHandler,
+		getOverdraftBreachesHandler,
+		idempotencyRepository,
 	)
 
 	// Start server
@@ -106,6 +334,29 @@ func main() {
 
 	go accountCommand.TransferMoneyWithRabbitMQConsumer()
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	go outboxRelay.Run(workerCtx)
+	go holdSweeper.Run(workerCtx)
+	go transferScheduler.Run(workerCtx)
+
+	if !overdraftInterestRate.IsZero() {
+		go overdraftInterest.Run(workerCtx)
+	}
+
 	// Graceful shutdown
 	server.GracefulShutdown(app)
 }
+
+// newWorkerId names this replica when it leases outbox events and scheduled
+// transfers. The random suffix keeps it unique when replicas share a hostname.
+func newWorkerId() string {
+	hostname, err := os.Hostname()
+
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return hostname + "-" + uuid.New().String()
+}
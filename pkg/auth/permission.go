@@ -0,0 +1,55 @@
+package auth
+
+import "kc-bank/domain"
+
+// Permission is an action beyond acting on one's own data, which every
+// authenticated user may do.
+type Permission string
+
+const (
+	PermissionAccountReadAny   Permission = "account:read:any"
+	PermissionAccountFreeze    Permission = "account:freeze"
+	PermissionAccountOverdraft Permission = "account:overdraft"
+	PermissionDepositCreate    Permission = "deposit:create"
+	PermissionWithdrawalCreate Permission = "withdrawal:create"
+	PermissionHoldManage       Permission = "hold:manage"
+	PermissionUserReadAny      Permission = "user:read:any"
+	PermissionUserList         Permission = "user:list"
+	PermissionUserManage       Permission = "user:manage"
+	PermissionUserManageRoles  Permission = "user:manage-roles"
+	PermissionDeadLetterManage Permission = "dead-letter:manage"
+)
+
+var rolePermissions = map[domain.Role][]Permission{
+	domain.RoleCustomer: {},
+	domain.RoleTeller: {
+		PermissionAccountReadAny,
+		PermissionDepositCreate,
+		PermissionWithdrawalCreate,
+		PermissionHoldManage,
+		PermissionUserReadAny,
+	},
+	domain.RoleAdmin: {
+		PermissionAccountReadAny,
+		PermissionAccountFreeze,
+		PermissionAccountOverdraft,
+		PermissionDepositCreate,
+		PermissionWithdrawalCreate,
+		PermissionHoldManage,
+		PermissionUserReadAny,
+		PermissionUserList,
+		PermissionUserManage,
+		PermissionUserManageRoles,
+		PermissionDeadLetterManage,
+	},
+}
+
+func (p *Principal) Can(permission Permission) bool {
+	for _, granted := range rolePermissions[p.Role] {
+		if granted == permission {
+			return true
+		}
+	}
+
+	return false
+}
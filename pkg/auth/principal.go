@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"kc-bank/domain"
+	errorresponse "kc-bank/pkg/error_response"
+)
+
+var ErrUnauthenticated = errorresponse.NewUnauthorizedError("authentication required")
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserId string
+	Role   domain.Role
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller set by the authentication
+// middleware, or false for unauthenticated requests.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// RequirePrincipal is PrincipalFromContext for code that must not run on
+// behalf of an unknown caller.
+func RequirePrincipal(ctx context.Context) (*Principal, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	return principal, nil
+}
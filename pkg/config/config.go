@@ -1,20 +1,59 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type AppConfig struct {
-	Port                              string `yaml:"port" mapstructure:"port"`
-	RabbitMQURL                       string `yaml:"rabbitmq_url" mapstructure:"rabbitmq_url"`
-	RabbitMQTransferMoneyQueueName    string `yaml:"rabbitmq_transfer_money_queue_name" mapstructure:"rabbitmq_transfer_money_queue_name"`
-	RabbitMQTransferMoneyExchangeName string `yaml:"rabbitmq_transfer_money_exchange_name" mapstructure:"rabbitmq_transfer_money_exchange_name"`
-	RabbitMQTransferMoneyExchangeType string `yaml:"rabbitmq_transfer_money_exchange_type" mapstructure:"rabbitmq_transfer_money_exchange_type"`
-	CouchbaseUrl                      string `yaml:"couchbase_url" mapstructure:"couchbase_url"`
-	CouchbaseUsername                 string `yaml:"couchbase_username" mapstructure:"couchbase_username"`
-	CouchbasePassword                 string `yaml:"couchbase_password" mapstructure:"couchbase_password"`
+	Port                              string        `yaml:"port" mapstructure:"port"`
+	RabbitMQURL                       string        `yaml:"rabbitmq_url" mapstructure:"rabbitmq_url"`
+	RabbitMQTransferMoneyQueueName    string        `yaml:"rabbitmq_transfer_money_queue_name" mapstructure:"rabbitmq_transfer_money_queue_name"`
+	RabbitMQTransferMoneyExchangeName string        `yaml:"rabbitmq_transfer_money_exchange_name" mapstructure:"rabbitmq_transfer_money_exchange_name"`
+	RabbitMQTransferMoneyExchangeType string        `yaml:"rabbitmq_transfer_money_exchange_type" mapstructure:"rabbitmq_transfer_money_exchange_type"`
+	RabbitMQTransferMoneyMaxRetries   int           `yaml:"rabbitmq_transfer_money_max_retries" mapstructure:"rabbitmq_transfer_money_max_retries"`
+	RabbitMQTransferMoneyRetryDelay   time.Duration `yaml:"rabbitmq_transfer_money_retry_delay" mapstructure:"rabbitmq_transfer_money_retry_delay"`
+	CouchbaseUrl                      string        `yaml:"couchbase_url" mapstructure:"couchbase_url"`
+	CouchbaseUsername                 string        `yaml:"couchbase_username" mapstructure:"couchbase_username"`
+	CouchbasePassword                 string        `yaml:"couchbase_password" mapstructure:"couchbase_password"`
+	IdempotencyKeyTTL                 time.Duration `yaml:"idempotency_key_ttl" mapstructure:"idempotency_key_ttl"`
+	IdempotencyLeaseTTL               time.Duration `yaml:"idempotency_lease_ttl" mapstructure:"idempotency_lease_ttl"`
+	OutboxRelayInterval               time.Duration `yaml:"outbox_relay_interval" mapstructure:"outbox_relay_interval"`
+	OutboxRelayBatchSize              int           `yaml:"outbox_relay_batch_size" mapstructure:"outbox_relay_batch_size"`
+	OutboxRelayLeaseTTL               time.Duration `yaml:"outbox_relay_lease_ttl" mapstructure:"outbox_relay_lease_ttl"`
+	JwtAlgorithm                      string        `yaml:"jwt_algorithm" mapstructure:"jwt_algorithm"`
+	JwtSecret                         string        `yaml:"jwt_secret" mapstructure:"jwt_secret"`
+	JwtPrivateKeyPath                 string        `yaml:"jwt_private_key_path" mapstructure:"jwt_private_key_path"`
+	JwtPublicKeyPath                  string        `yaml:"jwt_public_key_path" mapstructure:"jwt_public_key_path"`
+	JwtIssuer                         string        `yaml:"jwt_issuer" mapstructure:"jwt_issuer"`
+	AccessTokenTTL                    time.Duration `yaml:"access_token_ttl" mapstructure:"access_token_ttl"`
+	RefreshTokenTTL                   time.Duration `yaml:"refresh_token_ttl" mapstructure:"refresh_token_ttl"`
+	AccountMinimumOwnerAge            int32         `yaml:"account_minimum_owner_age" mapstructure:"account_minimum_owner_age"`
+	MaxAccountsPerCurrency            int           `yaml:"max_accounts_per_currency" mapstructure:"max_accounts_per_currency"`
+	EmailStripPlusAddressing          bool          `yaml:"email_strip_plus_addressing" mapstructure:"email_strip_plus_addressing"`
+	FieldEncryptionKey                string        `yaml:"field_encryption_key" mapstructure:"field_encryption_key"`
+	FieldIndexKey                     string        `yaml:"field_index_key" mapstructure:"field_index_key"`
+	IdentityVerifier                  string        `yaml:"identity_verifier" mapstructure:"identity_verifier"`
+	BootstrapAdminEmail               string        `yaml:"bootstrap_admin_email" mapstructure:"bootstrap_admin_email"`
+	ExchangeRateProvider              string        `yaml:"exchange_rate_provider" mapstructure:"exchange_rate_provider"`
+	ExchangeRateFile                  string        `yaml:"exchange_rate_file" mapstructure:"exchange_rate_file"`
+	FxSpreadBps                       int           `yaml:"fx_spread_bps" mapstructure:"fx_spread_bps"`
+	FxQuoteTTL                        time.Duration `yaml:"fx_quote_ttl" mapstructure:"fx_quote_ttl"`
+	OverdraftInterestRate             string        `yaml:"overdraft_interest_rate" mapstructure:"overdraft_interest_rate"`
+	OverdraftInterestInterval         time.Duration `yaml:"overdraft_interest_interval" mapstructure:"overdraft_interest_interval"`
+	HoldDefaultTTL                    time.Duration `yaml:"hold_default_ttl" mapstructure:"hold_default_ttl"`
+	HoldMaxTTL                        time.Duration `yaml:"hold_max_ttl" mapstructure:"hold_max_ttl"`
+	HoldSweepInterval                 time.Duration `yaml:"hold_sweep_interval" mapstructure:"hold_sweep_interval"`
+	HoldSweepBatchSize                int           `yaml:"hold_sweep_batch_size" mapstructure:"hold_sweep_batch_size"`
+	ScheduledTransferMaxHorizon       time.Duration `yaml:"scheduled_transfer_max_horizon" mapstructure:"scheduled_transfer_max_horizon"`
+	ScheduledTransferInterval         time.Duration `yaml:"scheduled_transfer_interval" mapstructure:"scheduled_transfer_interval"`
+	ScheduledTransferBatchSize        int           `yaml:"scheduled_transfer_batch_size" mapstructure:"scheduled_transfer_batch_size"`
+	ScheduledTransferLeaseTTL         time.Duration `yaml:"scheduled_transfer_lease_ttl" mapstructure:"scheduled_transfer_lease_ttl"`
+	ScheduledTransferMaxAttempts      int           `yaml:"scheduled_transfer_max_attempts" mapstructure:"scheduled_transfer_max_attempts"`
 }
 
 func Read() *AppConfig {
@@ -29,11 +68,71 @@ func Read() *AppConfig {
 		panic(fmt.Errorf("fatal error config file: %w", err))
 	}
 
+	for key, env := range secretEnvVars {
+		if err := viper.BindEnv(key, env); err != nil {
+			panic(fmt.Errorf("fatal error binding %s: %w", env, err))
+		}
+	}
+
 	var appConfig AppConfig
 	err = viper.Unmarshal(&appConfig)
 	if err != nil {
 		panic(fmt.Errorf("fatal error unmarshalling config: %w", err))
 	}
 
+	for _, key := range []string{"field_encryption_key", "field_index_key"} {
+		if err := checkSecret(key, true); err != nil {
+			panic(fmt.Errorf("fatal error in config: %w", err))
+		}
+	}
+
+	// The secret only signs tokens with HS256; RS256 uses key files.
+	if err := checkSecret("jwt_secret", appConfig.JwtAlgorithm == "HS256"); err != nil {
+		panic(fmt.Errorf("fatal error in config: %w", err))
+	}
+
 	return &appConfig
 }
+
+// secretEnvVars lists the settings that are only read from the environment,
+// so they never end up in the repository, and the variable each is read from.
+var secretEnvVars = map[string]string{
+	"field_encryption_key": "KC_BANK_FIELD_ENCRYPTION_KEY",
+	"field_index_key":      "KC_BANK_FIELD_INDEX_KEY",
+	"jwt_secret":           "KC_BANK_JWT_SECRET",
+}
+
+// publishedSecrets holds the SHA-256 of secrets that were once committed to
+// the repository. Anyone with access to it has them, so they are refused.
+var publishedSecrets = map[string]bool{
+	"658509f67b95bab88d0b9abd1f423bb6b9000c05d39f0f9ce0c632103650a127": true,
+	"d26ebbc3e7467c13c432ffcd5a3c0a86db7c4fe817948154aa34368e7f83f262": true,
+	"5f03ffdf0ecb300f4206153d23cd196aec142cbca3c7f88082ba4b628b577878": true,
+}
+
+// checkSecret refuses the secret setting key when it is kept in the config
+// file or was published, and when it is missing if it is required.
+func checkSecret(key string, required bool) error {
+	env := secretEnvVars[key]
+
+	if viper.InConfig(key) {
+		return fmt.Errorf("%s must not be kept in the config file; set %s instead", key, env)
+	}
+
+	value := viper.GetString(key)
+
+	if value == "" {
+		if required {
+			return fmt.Errorf("%s is not set", env)
+		}
+
+		return nil
+	}
+
+	hash := sha256.Sum256([]byte(value))
+	if publishedSecrets[hex.EncodeToString(hash[:])] {
+		return fmt.Errorf("%s is a published development value; generate a new one", env)
+	}
+
+	return nil
+}
@@ -1,5 +1,10 @@
 package errorresponse
 
+import (
+	"net/http"
+	"strings"
+)
+
 type ErrorResponse struct {
 	Status      int32         `json:"status"`
 	ErrorDetail []ErrorDetail `json:"errorDetail"`
@@ -10,7 +15,68 @@ type ErrorDetail struct {
 	FieldName   string `json:"fieldName"`
 }
 
+// Error lists the field errors so an ErrorResponse can be returned wherever an
+// error is expected.
+func (e *ErrorResponse) Error() string {
+	messages := make([]string, 0, len(e.ErrorDetail))
+	for _, detail := range e.ErrorDetail {
+		messages = append(messages, detail.FieldName+": "+detail.Description)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func NewValidationError(details ...ErrorDetail) *ErrorResponse {
+	return &ErrorResponse{
+		Status:      http.StatusBadRequest,
+		ErrorDetail: details,
+	}
+}
+
+// NewFieldError reports a problem with a single request field.
+func NewFieldError(status int, fieldName, description string) *ErrorResponse {
+	return &ErrorResponse{
+		Status:      int32(status),
+		ErrorDetail: []ErrorDetail{{Description: description, FieldName: fieldName}},
+	}
+}
+
 type CustomError struct {
 	StatusCode int    `json:"statusCode"`
 	Message    string `json:"message"`
 }
+
+func (e *CustomError) Error() string {
+	return e.Message
+}
+
+func NewCustomError(statusCode int, message string) *CustomError {
+	return &CustomError{
+		StatusCode: statusCode,
+		Message:    message,
+	}
+}
+
+func NewBadRequestError(message string) *CustomError {
+	return NewCustomError(http.StatusBadRequest, message)
+}
+
+func NewConflictError(message string) *CustomError {
+	return NewCustomError(http.StatusConflict, message)
+}
+
+func NewUnauthorizedError(message string) *CustomError {
+	return NewCustomError(http.StatusUnauthorized, message)
+}
+
+func NewForbiddenError(message string) *CustomError {
+	return NewCustomError(http.StatusForbidden, message)
+}
+
+func NewNotFoundError(message string) *CustomError {
+	return NewCustomError(http.StatusNotFound, message)
+}
+
+func NewUnprocessableEntityError(message string) *CustomError {
+	return NewCustomError(http.StatusUnprocessableEntity, message)
+}
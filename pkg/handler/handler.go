@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"errors"
+	errorresponse "kc-bank/pkg/error_response"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -19,7 +20,24 @@ type HandlerInterface[R Request, Res Response] interface {
 	Handle(ctx context.Context, req *R) (*Res, error)
 }
 
-func Handle[R Request, Res Response](handler HandlerInterface[R, Res]) fiber.Handler {
+// StatusCoder is implemented by responses that should not be sent with the
+// default 200 status.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+type Option func(*options)
+
+type options struct {
+	idempotencyStore IdempotencyStore
+}
+
+func Handle[R Request, Res Response](handler HandlerInterface[R, Res], opts ...Option) fiber.Handler {
+	var cfg options
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	return func(c *fiber.Ctx) error {
 		var req R
 
@@ -47,12 +65,38 @@ func Handle[R Request, Res Response](handler HandlerInterface[R, Res]) fiber.Han
 		ctx, cancel := context.WithTimeout(c.UserContext(), 3*time.Second)
 		defer cancel()
 
-		res, err := handler.Handle(ctx, &req)
-		if err != nil {
-			zap.L().Error("Failed to handle request", zap.Error(err))
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		if cfg.idempotencyStore != nil {
+			if key := idempotencyKeyOf(&req); key != "" {
+				return handleIdempotent(ctx, c, cfg.idempotencyStore, key, func() error {
+					return run(ctx, c, handler, &req)
+				})
+			}
 		}
 
-		return c.JSON(res)
+		return run(ctx, c, handler, &req)
 	}
 }
+
+func run[R Request, Res Response](ctx context.Context, c *fiber.Ctx, handler HandlerInterface[R, Res], req *R) error {
+	res, err := handler.Handle(ctx, req)
+	if err != nil {
+		var customError *errorresponse.CustomError
+		if errors.As(err, &customError) {
+			return c.Status(customError.StatusCode).JSON(fiber.Map{"error": customError.Message})
+		}
+
+		var errorResponse *errorresponse.ErrorResponse
+		if errors.As(err, &errorResponse) {
+			return c.Status(int(errorResponse.Status)).JSON(errorResponse)
+		}
+
+		zap.L().Error("Failed to handle request", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if statusCoder, ok := any(res).(StatusCoder); ok {
+		c.Status(statusCoder.StatusCode())
+	}
+
+	return c.JSON(res)
+}
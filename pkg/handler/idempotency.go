@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"kc-bank/domain"
+	"kc-bank/pkg/auth"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// IdempotencyStore persists the outcome of requests sent with an
+// Idempotency-Key header.
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, key, requestHash string) (*domain.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, record *domain.IdempotencyRecord) error
+	MarkAmbiguous(ctx context.Context, record *domain.IdempotencyRecord) error
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyHeader is embedded in request types that honour the
+// Idempotency-Key header. It is filled by ReqHeaderParser like any other
+// header field.
+type IdempotencyHeader struct {
+	IdempotencyKey string `json:"-" reqHeader:"Idempotency-Key" validate:"omitempty,max=255"`
+}
+
+func (h *IdempotencyHeader) idempotencyKey() string {
+	return h.IdempotencyKey
+}
+
+// WithIdempotency makes retries of a request with the same Idempotency-Key
+// return the original response instead of running the handler again.
+func WithIdempotency(store IdempotencyStore) Option {
+	return func(o *options) {
+		o.idempotencyStore = store
+	}
+}
+
+func idempotencyKeyOf(req any) string {
+	if r, ok := req.(interface{ idempotencyKey() string }); ok {
+		return r.idempotencyKey()
+	}
+
+	return ""
+}
+
+func handleIdempotent(ctx context.Context, c *fiber.Ctx, store IdempotencyStore, key string, next func() error) error {
+	// Keys are scoped to the caller and the route, so the same key sent by two
+	// users or to two endpoints does not collide, and hashed to stay within the
+	// document key length limit.
+	caller := ""
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		caller = principal.UserId
+	}
+
+	scopedKey := sha256.Sum256([]byte(caller + " " + c.Method() + " " + c.Route().Path + " " + key))
+	storeKey := hex.EncodeToString(scopedKey[:])
+	requestHash := hashRequest(c)
+
+	record, reserved, err := store.Reserve(ctx, storeKey, requestHash)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if !reserved {
+		if record.RequestHash != requestHash {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Idempotency-Key was already used with a different request"})
+		}
+
+		if record.Ambiguous {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "the request with this Idempotency-Key failed and may have taken effect; check its outcome before sending it again with a new Idempotency-Key"})
+		}
+
+		if !record.Completed {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "a request with this Idempotency-Key is still being processed"})
+		}
+
+		c.Set("Idempotent-Replayed", "true")
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Status(record.StatusCode).Send(record.Body)
+	}
+
+	// Server errors, timeouts included, do not tell whether the request took
+	// effect: a transfer may have committed just before its context expired.
+	// The key stays reserved so a retry cannot apply it a second time.
+	if err := next(); err != nil {
+		markIdempotencyKeyAmbiguous(ctx, store, record)
+		return err
+	}
+
+	statusCode := c.Response().StatusCode()
+	if statusCode >= fiber.StatusInternalServerError {
+		markIdempotencyKeyAmbiguous(ctx, store, record)
+		return nil
+	}
+
+	// Conflicts are raised before anything is written and are worth
+	// retrying, so they are not remembered.
+	if statusCode == fiber.StatusConflict {
+		releaseIdempotencyKey(ctx, store, storeKey)
+		return nil
+	}
+
+	record.StatusCode = statusCode
+	record.Body = append([]byte(nil), c.Response().Body()...)
+
+	if !completeIdempotencyKey(ctx, store, record) {
+		// The request took effect, so the key must not be left to expire
+		// with its reservation and the request run again.
+		record.Completed = false
+		markIdempotencyKeyAmbiguous(ctx, store, record)
+	}
+
+	return nil
+}
+
+// completeIdempotencyKey stores the response for replay, trying twice before
+// giving up.
+func completeIdempotencyKey(ctx context.Context, store IdempotencyStore, record *domain.IdempotencyRecord) bool {
+	for attempt := 1; attempt <= 2; attempt++ {
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+		err := store.Complete(saveCtx, record)
+		cancel()
+
+		if err == nil {
+			return true
+		}
+
+		zap.L().Error("Failed to store idempotent response", zap.String("key", record.Key), zap.Int("attempt", attempt), zap.Error(err))
+	}
+
+	return false
+}
+
+func markIdempotencyKeyAmbiguous(ctx context.Context, store IdempotencyStore, record *domain.IdempotencyRecord) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+
+	if err := store.MarkAmbiguous(ctx, record); err != nil {
+		zap.L().Error("Failed to keep idempotency key reserved", zap.String("key", record.Key), zap.Error(err))
+	}
+}
+
+func releaseIdempotencyKey(ctx context.Context, store IdempotencyStore, key string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+
+	if err := store.Release(ctx, key); err != nil {
+		zap.L().Error("Failed to release idempotency key", zap.String("key", key), zap.Error(err))
+	}
+}
+
+func hashRequest(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.OriginalURL()))
+	hash.Write([]byte{0})
+	hash.Write(c.Body())
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
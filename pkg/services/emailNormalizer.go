@@ -0,0 +1,36 @@
+package services
+
+import "strings"
+
+// EmailNormalizer turns the email a user typed into the forms we store and
+// compare. Email domains and, in practice, local parts are case-insensitive.
+type EmailNormalizer struct {
+	// StripPlusAddressing treats "name+tag@host" as "name@host" when checking
+	// uniqueness, so one mailbox can not register many times.
+	StripPlusAddressing bool
+}
+
+// Normalize trims and lower-cases email. This is the address that is stored
+// and mailed to.
+func (n EmailNormalizer) Normalize(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Canonical is the form used to decide whether two addresses belong to the
+// same person.
+func (n EmailNormalizer) Canonical(email string) string {
+	email = n.Normalize(email)
+
+	if !n.StripPlusAddressing {
+		return email
+	}
+
+	local, domain, found := strings.Cut(email, "@")
+	if !found {
+		return email
+	}
+
+	local, _, _ = strings.Cut(local, "+")
+
+	return local + "@" + domain
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kc-bank/domain"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrExchangeRateUnavailable = errors.New("no exchange rate available")
+
+// inverseRateDecimals is the precision of rates derived from the rate of the
+// opposite direction.
+const inverseRateDecimals = 10
+
+// IExchangeRateProvider returns the mid-market rate to convert from one
+// currency into another. It returns ErrExchangeRateUnavailable when it has no
+// rate for the pair.
+type IExchangeRateProvider interface {
+	GetRate(ctx context.Context, from, to string) (*domain.ExchangeRate, error)
+}
+
+type noExchangeRateProvider struct{}
+
+// NewNoExchangeRateProvider has no rates, so every cross-currency transfer is
+// refused. It is used until a rate source is configured.
+func NewNoExchangeRateProvider() IExchangeRateProvider {
+	return &noExchangeRateProvider{}
+}
+
+func (p *noExchangeRateProvider) GetRate(ctx context.Context, from, to string) (*domain.ExchangeRate, error) {
+	return nil, fmt.Errorf("%w for %s to %s", ErrExchangeRateUnavailable, from, to)
+}
+
+// rateFile is the format of the rate file: pairs such as "USD/TRY" mapped to
+// the decimal rate of one USD in TRY.
+type rateFile struct {
+	Rates map[string]string `json:"rates"`
+}
+
+type fileExchangeRateProvider struct {
+	path string
+
+	mu       sync.Mutex
+	rates    map[string]string
+	modified time.Time
+}
+
+// NewFileExchangeRateProvider serves the rates in the JSON file at path and
+// reloads them when the file changes. A pair missing from the file is served
+// as the inverse of the opposite pair.
+func NewFileExchangeRateProvider(path string) (IExchangeRateProvider, error) {
+	p := &fileExchangeRateProvider{path: path}
+
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *fileExchangeRateProvider) GetRate(ctx context.Context, from, to string) (*domain.ExchangeRate, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A broken edit keeps the last good rates in service.
+	_ = p.reload()
+
+	if rate, ok := p.rates[from+"/"+to]; ok {
+		return domain.NewExchangeRate(from, to, rate)
+	}
+
+	if rate, ok := p.rates[to+"/"+from]; ok {
+		opposite, err := domain.NewExchangeRate(to, from, rate)
+		if err != nil {
+			return nil, err
+		}
+
+		return domain.InverseExchangeRate(opposite, inverseRateDecimals)
+	}
+
+	return nil, fmt.Errorf("%w for %s to %s", ErrExchangeRateUnavailable, from, to)
+}
+
+// reload reads the file again if it changed since it was last read.
+func (p *fileExchangeRateProvider) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("read exchange rates: %w", err)
+	}
+
+	if p.rates != nil && !info.ModTime().After(p.modified) {
+		return nil
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("read exchange rates: %w", err)
+	}
+
+	var file rateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parse exchange rates: %w", err)
+	}
+
+	for pair, rate := range file.Rates {
+		if _, err := domain.NewExchangeRate("", "", rate); err != nil {
+			return fmt.Errorf("parse exchange rates: %s: %w", pair, err)
+		}
+	}
+
+	p.rates = file.Rates
+	p.modified = info.ModTime()
+
+	return nil
+}
+
+// NewExchangeRateProvider returns the provider configured by name; the file
+// provider reads rates from path.
+func NewExchangeRateProvider(name, path string) (IExchangeRateProvider, error) {
+	switch name {
+	case "none":
+		return NewNoExchangeRateProvider(), nil
+	case "file":
+		return NewFileExchangeRateProvider(path)
+	default:
+		return nil, errors.New("unknown exchange rate provider " + name)
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidCiphertext = errors.New("ciphertext is malformed or was not produced with this key")
+
+// IFieldCipher protects sensitive fields stored in Couchbase. Encrypt is
+// randomised, so equal values encrypt differently; BlindIndex is
+// deterministic and lets equal values be found without storing them.
+type IFieldCipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+	BlindIndex(plaintext string) string
+}
+
+type fieldCipher struct {
+	aead     cipher.AEAD
+	indexKey []byte
+}
+
+// NewFieldCipher takes base64 keys: a 32 byte AES-256-GCM encryption key and
+// a separate HMAC-SHA256 key for blind indexes.
+func NewFieldCipher(encryptionKey, indexKey string) (IFieldCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encryptionKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("field encryption key must be 32 bytes, base64 encoded")
+	}
+
+	hmacKey, err := base64.StdEncoding.DecodeString(indexKey)
+	if err != nil || len(hmacKey) < 32 {
+		return nil, errors.New("field index key must be at least 32 bytes, base64 encoded")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fieldCipher{
+		aead:     aead,
+		indexKey: hmacKey,
+	}, nil
+}
+
+func (c *fieldCipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *fieldCipher) Decrypt(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidCiphertext, err.Error())
+	}
+
+	return string(plaintext), nil
+}
+
+func (c *fieldCipher) BlindIndex(plaintext string) string {
+	mac := hmac.New(sha256.New, c.indexKey)
+	mac.Write([]byte(plaintext))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"kc-bank/domain"
+	"time"
+)
+
+// IFxPricer prices currency conversions for customers: the provider's mid
+// rate less the configured spread.
+type IFxPricer interface {
+	Quote(ctx context.Context, userId, from, to string) (*domain.FxQuote, error)
+	SpotQuote(ctx context.Context, userId, from, to string) (*domain.FxQuote, error)
+}
+
+type fxPricer struct {
+	provider  IExchangeRateProvider
+	spreadBps int
+	quoteTTL  time.Duration
+}
+
+func NewFxPricer(provider IExchangeRateProvider, spreadBps int, quoteTTL time.Duration) IFxPricer {
+	return &fxPricer{
+		provider:  provider,
+		spreadBps: spreadBps,
+		quoteTTL:  quoteTTL,
+	}
+}
+
+// Quote returns a quote that holds its rate for the configured quote TTL.
+func (p *fxPricer) Quote(ctx context.Context, userId, from, to string) (*domain.FxQuote, error) {
+	midRate, err := p.provider.GetRate(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewFxQuote(userId, midRate, p.spreadBps, p.quoteTTL)
+}
+
+// SpotQuote returns the current price, for a conversion made right away.
+func (p *fxPricer) SpotQuote(ctx context.Context, userId, from, to string) (*domain.FxQuote, error) {
+	midRate, err := p.provider.GetRate(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewSpotFxQuote(userId, midRate, p.spreadBps)
+}
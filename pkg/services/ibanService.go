@@ -1,39 +1,200 @@
 package services
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"math/rand"
+	"strings"
+	"sync"
 	"time"
 )
 
+var (
+	ErrIbanUnsupportedCountry = errors.New("iban country is not supported")
+	ErrIbanInvalidCharacters  = errors.New("iban may only contain letters and digits")
+	ErrIbanInvalidLength      = errors.New("iban has the wrong length for its country")
+	ErrIbanInvalidFormat      = errors.New("iban does not match its country's format")
+	ErrIbanInvalidChecksum    = errors.New("iban check digits are invalid")
+)
+
+const (
+	digits       = "0123456789"
+	letters      = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	alphanumeric = digits + letters
+)
+
+// bbanSegment is one part of a country's BBAN as listed in the ISO 13616
+// registry: length characters from charset, or exactly fixed when set.
+type bbanSegment struct {
+	length  int
+	charset string
+	fixed   string
+}
+
+type ibanSpec struct {
+	length   int
+	segments []bbanSegment
+}
+
+// ibanSpecs holds the BBAN structure of every country we issue or accept
+// IBANs for.
+var ibanSpecs = map[string]ibanSpec{
+	// TR: 5n bank code, 1n reserved (always 0), 16c account number.
+	"TR": {length: 26, segments: []bbanSegment{{length: 5, charset: digits}, {length: 1, fixed: "0"}, {length: 16, charset: alphanumeric}}},
+	"DE": {length: 22, segments: []bbanSegment{{length: 8, charset: digits}, {length: 10, charset: digits}}},
+	"GB": {length: 22, segments: []bbanSegment{{length: 4, charset: letters}, {length: 6, charset: digits}, {length: 8, charset: digits}}},
+	"NL": {length: 18, segments: []bbanSegment{{length: 4, charset: letters}, {length: 10, charset: digits}}},
+	"FR": {length: 27, segments: []bbanSegment{{length: 5, charset: digits}, {length: 5, charset: digits}, {length: 11, charset: alphanumeric}, {length: 2, charset: digits}}},
+}
+
 type IIbanService interface {
-	GenerateIBAN(countryCode string, bankCodeLen, accountLen int) string
+	GenerateIBAN(countryCode string) (string, error)
+	ValidateIBAN(iban string) error
 }
 
 type IbanService struct {
-	rng *rand.Rand
+	rng   *rand.Rand
+	rngMu sync.Mutex
 }
 
 func NewIbanService() *IbanService {
-	src := rand.NewSource(time.Now().UnixNano())
+	src := rand.NewSource(newSeed())
 
 	return &IbanService{
 		rng: rand.New(src),
 	}
 }
 
-func (s *IbanService) GenerateIBAN(countryCode string, bankCodeLen, accountLen int) string {
+// NormalizeIBAN removes the spaces of the printed form and upper-cases iban,
+// giving the electronic form that is stored and validated.
+func NormalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(iban), " ", ""))
+}
+
+// GenerateIBAN returns a random IBAN for countryCode with a BBAN that follows
+// the country's structure and valid ISO 13616 check digits.
+func (s *IbanService) GenerateIBAN(countryCode string) (string, error) {
+	spec, ok := ibanSpecs[countryCode]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrIbanUnsupportedCountry, countryCode)
+	}
+
+	var bban strings.Builder
+	for _, segment := range spec.segments {
+		if segment.fixed != "" {
+			bban.WriteString(segment.fixed)
+			continue
+		}
+
+		// Account numbers are issued as digits only, which every
+		// alphanumeric segment also accepts.
+		charset := segment.charset
+		if charset == alphanumeric {
+			charset = digits
+		}
 
-	checkDigits := s.randomString(2, "0123456789")
-	bankCode := s.randomString(bankCodeLen, "0123456789")
-	accountNumber := s.randomString(accountLen, "0123456789")
+		bban.WriteString(s.randomString(segment.length, charset))
+	}
 
-	return countryCode + checkDigits + bankCode + accountNumber
+	return countryCode + checkDigits(countryCode, bban.String()) + bban.String(), nil
+}
+
+// ValidateIBAN checks iban, in electronic form, against its country's length
+// and BBAN structure and verifies the mod-97 check digits.
+func (s *IbanService) ValidateIBAN(iban string) error {
+	for _, r := range iban {
+		if !strings.ContainsRune(alphanumeric, r) {
+			return ErrIbanInvalidCharacters
+		}
+	}
+
+	if len(iban) < 4 {
+		return ErrIbanInvalidLength
+	}
+
+	countryCode := iban[:2]
+
+	spec, ok := ibanSpecs[countryCode]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrIbanUnsupportedCountry, countryCode)
+	}
+
+	if len(iban) != spec.length {
+		return fmt.Errorf("%w: %s IBANs have %d characters", ErrIbanInvalidLength, countryCode, spec.length)
+	}
+
+	if !strings.ContainsRune(digits, rune(iban[2])) || !strings.ContainsRune(digits, rune(iban[3])) {
+		return ErrIbanInvalidFormat
+	}
+
+	bban := iban[4:]
+	for _, segment := range spec.segments {
+		part := bban[:segment.length]
+		bban = bban[segment.length:]
+
+		if segment.fixed != "" {
+			if part != segment.fixed {
+				return ErrIbanInvalidFormat
+			}
+			continue
+		}
+
+		for _, r := range part {
+			if !strings.ContainsRune(segment.charset, r) {
+				return ErrIbanInvalidFormat
+			}
+		}
+	}
+
+	if mod97(iban[4:]+iban[:4]) != 1 {
+		return ErrIbanInvalidChecksum
+	}
+
+	return nil
 }
 
 func (s *IbanService) randomString(length int, charset string) string {
+	// rand.Rand is not safe for concurrent use.
+	s.rngMu.Lock()
+	defer s.rngMu.Unlock()
+
 	result := make([]byte, length)
 	for i := range result {
 		result[i] = charset[s.rng.Intn(len(charset))]
 	}
 	return string(result)
 }
+
+// newSeed draws the generator seed from crypto/rand, so instances started at
+// the same moment do not generate the same IBAN sequence.
+func newSeed() int64 {
+	var seed [8]byte
+	if _, err := cryptorand.Read(seed[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+
+	return int64(binary.BigEndian.Uint64(seed[:]))
+}
+
+// checkDigits computes the two ISO 13616 check digits for bban.
+func checkDigits(countryCode, bban string) string {
+	return fmt.Sprintf("%02d", 98-mod97(bban+countryCode+"00"))
+}
+
+// mod97 returns value mod 97, reading letters as 10 (A) to 35 (Z). The number
+// is reduced as it is read since it is far too long for an integer.
+func mod97(value string) int {
+	remainder := 0
+
+	for _, r := range value {
+		if r >= 'A' && r <= 'Z' {
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(r-'0')) % 97
+		}
+	}
+
+	return remainder
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateIBAN(t *testing.T) {
+	tests := []struct {
+		name    string
+		iban    string
+		wantErr error
+	}{
+		{name: "TR valid", iban: "TR330006100519786457841326"},
+		{name: "DE valid", iban: "DE89370400440532013000"},
+		{name: "GB valid", iban: "GB29NWBK60161331926819"},
+		{name: "NL valid", iban: "NL91ABNA0417164300"},
+		{name: "FR valid", iban: "FR1420041010050500013M02606"},
+		{name: "TR bad check digits", iban: "TR340006100519786457841326", wantErr: ErrIbanInvalidChecksum},
+		{name: "DE transposed digits", iban: "DE89370400440532031000", wantErr: ErrIbanInvalidChecksum},
+		{name: "GB bad check digits", iban: "GB28NWBK60161331926819", wantErr: ErrIbanInvalidChecksum},
+		{name: "NL bad check digits", iban: "NL92ABNA0417164300", wantErr: ErrIbanInvalidChecksum},
+		{name: "FR bad check digits", iban: "FR1520041010050500013M02606", wantErr: ErrIbanInvalidChecksum},
+		{name: "TR reserved digit not zero", iban: "TR330006110519786457841326", wantErr: ErrIbanInvalidFormat},
+		{name: "TR too short", iban: "TR33000610051978645784132", wantErr: ErrIbanInvalidLength},
+		{name: "DE too long", iban: "DE893704004405320130000", wantErr: ErrIbanInvalidLength},
+		{name: "GB bank code with digits", iban: "GB29NW1K60161331926819", wantErr: ErrIbanInvalidFormat},
+		{name: "NL account number with letters", iban: "NL91ABNA04171643A0", wantErr: ErrIbanInvalidFormat},
+		{name: "check digits with letters", iban: "DEA9370400440532013000", wantErr: ErrIbanInvalidFormat},
+		{name: "unsupported country", iban: "ES9121000418450200051332", wantErr: ErrIbanUnsupportedCountry},
+		{name: "lower case", iban: "de89370400440532013000", wantErr: ErrIbanInvalidCharacters},
+		{name: "spaces", iban: "DE89 3704 0044 0532 0130 00", wantErr: ErrIbanInvalidCharacters},
+		{name: "too short for a country", iban: "DE8", wantErr: ErrIbanInvalidLength},
+		{name: "empty", iban: "", wantErr: ErrIbanInvalidLength},
+	}
+
+	s := NewIbanService()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.ValidateIBAN(tt.iban); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateIBAN(%q) error = %v, want %v", tt.iban, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeIBAN(t *testing.T) {
+	tests := []struct {
+		iban string
+		want string
+	}{
+		{iban: "DE89370400440532013000", want: "DE89370400440532013000"},
+		{iban: "DE89 3704 0044 0532 0130 00", want: "DE89370400440532013000"},
+		{iban: "  gb29 nwbk 6016 1331 9268 19 ", want: "GB29NWBK60161331926819"},
+		{iban: "fr1420041010050500013m02606", want: "FR1420041010050500013M02606"},
+	}
+
+	s := NewIbanService()
+
+	for _, tt := range tests {
+		got := NormalizeIBAN(tt.iban)
+		if got != tt.want {
+			t.Errorf("NormalizeIBAN(%q) = %q, want %q", tt.iban, got, tt.want)
+		}
+
+		if err := s.ValidateIBAN(got); err != nil {
+			t.Errorf("ValidateIBAN(NormalizeIBAN(%q)) error = %v", tt.iban, err)
+		}
+	}
+}
+
+func TestGenerateIBAN(t *testing.T) {
+	s := NewIbanService()
+
+	for countryCode, spec := range ibanSpecs {
+		t.Run(countryCode, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				iban, err := s.GenerateIBAN(countryCode)
+				if err != nil {
+					t.Fatalf("GenerateIBAN(%s) error = %v", countryCode, err)
+				}
+
+				if len(iban) != spec.length || iban[:2] != countryCode {
+					t.Fatalf("GenerateIBAN(%s) = %q, want a %d character %s IBAN", countryCode, iban, spec.length, countryCode)
+				}
+
+				if err := s.ValidateIBAN(iban); err != nil {
+					t.Fatalf("GenerateIBAN(%s) = %q, which does not validate: %v", countryCode, iban, err)
+				}
+			}
+		})
+	}
+
+	if _, err := s.GenerateIBAN("ES"); !errors.Is(err, ErrIbanUnsupportedCountry) {
+		t.Errorf("GenerateIBAN(ES) error = %v, want %v", err, ErrIbanUnsupportedCountry)
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrIdentityNotVerified = errors.New("identity could not be verified with the national registry")
+
+// IdentityClaim is what a user states about themselves at registration.
+type IdentityClaim struct {
+	NationalId string
+	FirstName  string
+	LastName   string
+	BirthYear  int32
+}
+
+// IIdentityVerifier confirms an identity claim against the national identity
+// registry. It returns ErrIdentityNotVerified when the registry disagrees.
+type IIdentityVerifier interface {
+	Verify(ctx context.Context, claim IdentityClaim) error
+}
+
+type stubIdentityVerifier struct{}
+
+// NewStubIdentityVerifier accepts every claim with a name and a birth year.
+// It stands in for the registry in development and tests.
+func NewStubIdentityVerifier() IIdentityVerifier {
+	return &stubIdentityVerifier{}
+}
+
+func (v *stubIdentityVerifier) Verify(ctx context.Context, claim IdentityClaim) error {
+	if strings.TrimSpace(claim.FirstName) == "" || strings.TrimSpace(claim.LastName) == "" || claim.BirthYear <= 0 {
+		return ErrIdentityNotVerified
+	}
+
+	return nil
+}
+
+// NewIdentityVerifier returns the verifier configured by name.
+func NewIdentityVerifier(name string) (IIdentityVerifier, error) {
+	switch name {
+	case "stub":
+		return NewStubIdentityVerifier(), nil
+	default:
+		return nil, errors.New("unknown identity verifier " + name)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"kc-bank/domain"
+	"kc-bank/pkg/auth"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+// TokenConfig selects how access tokens are signed. HS256 uses Secret; RS256
+// signs with the PEM private key at PrivateKeyPath and verifies with the key
+// at PublicKeyPath, or with the private key's public half when it is empty.
+type TokenConfig struct {
+	Algorithm      string
+	Secret         string
+	PrivateKeyPath string
+	PublicKeyPath  string
+	Issuer         string
+	AccessTokenTTL time.Duration
+}
+
+type ITokenService interface {
+	IssueAccessToken(principal *auth.Principal) (string, time.Time, error)
+	ParseAccessToken(token string) (*auth.Principal, error)
+	NewRefreshToken() (string, error)
+	HashRefreshToken(token string) string
+}
+
+type accessClaims struct {
+	jwt.RegisteredClaims
+	Role domain.Role `json:"role"`
+}
+
+type tokenService struct {
+	method     jwt.SigningMethod
+	signingKey any
+	verifyKey  any
+	issuer     string
+	ttl        time.Duration
+}
+
+func NewTokenService(config TokenConfig) (ITokenService, error) {
+	s := &tokenService{
+		issuer: config.Issuer,
+		ttl:    config.AccessTokenTTL,
+	}
+
+	switch config.Algorithm {
+	case "HS256":
+		if config.Secret == "" {
+			return nil, errors.New("jwt secret is required for HS256")
+		}
+
+		s.method = jwt.SigningMethodHS256
+		s.signingKey = []byte(config.Secret)
+		s.verifyKey = []byte(config.Secret)
+
+	case "RS256":
+		privateKey, err := readRSAPrivateKey(config.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKey := &privateKey.PublicKey
+		if config.PublicKeyPath != "" {
+			if publicKey, err = readRSAPublicKey(config.PublicKeyPath); err != nil {
+				return nil, err
+			}
+		}
+
+		s.method = jwt.SigningMethodRS256
+		s.signingKey = privateKey
+		s.verifyKey = publicKey
+
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", config.Algorithm)
+	}
+
+	return s, nil
+}
+
+func (s *tokenService) IssueAccessToken(principal *auth.Principal) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.ttl)
+
+	claims := accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Subject:   principal.UserId,
+			Issuer:    s.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Role: principal.Role,
+	}
+
+	token, err := jwt.NewWithClaims(s.method, claims).SignedString(s.signingKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+func (s *tokenService) ParseAccessToken(token string) (*auth.Principal, error) {
+	var claims accessClaims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return s.verifyKey, nil
+	},
+		jwt.WithValidMethods([]string{s.method.Alg()}),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAccessToken, err.Error())
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidAccessToken)
+	}
+
+	// Tokens issued before roles existed carry none.
+	role := claims.Role
+	if role == "" {
+		role = domain.RoleCustomer
+	}
+
+	if !role.IsValid() {
+		return nil, fmt.Errorf("%w: unknown role", ErrInvalidAccessToken)
+	}
+
+	return &auth.Principal{UserId: claims.Subject, Role: role}, nil
+}
+
+// NewRefreshToken returns an opaque random token. Refresh tokens are not JWTs:
+// they are only meaningful to us and must be looked up to be trusted anyway.
+func (s *tokenService) NewRefreshToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// HashRefreshToken is the key a refresh token is stored under, so a leaked
+// database does not leak usable tokens.
+func (s *tokenService) HashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func readRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt private key: %w", err)
+	}
+
+	return jwt.ParseRSAPrivateKeyFromPEM(pem)
+}
+
+func readRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt public key: %w", err)
+	}
+
+	return jwt.ParseRSAPublicKeyFromPEM(pem)
+}